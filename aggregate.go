@@ -0,0 +1,200 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// aggregatePageSize is the number of children requested per page by
+// Aggregate.
+const aggregatePageSize = 1000
+
+// Reducer folds the children of a location into an aggregate value.
+type Reducer interface {
+	// Reduce is called once for every child, in the order of the query.
+	Reduce(key string, value interface{}) error
+}
+
+// ReducerFunc is an adapter to allow the use of ordinary functions as
+// a Reducer.
+type ReducerFunc func(key string, value interface{}) error
+
+// Reduce calls f(key, value).
+func (f ReducerFunc) Reduce(key string, value interface{}) error {
+	return f(key, value)
+}
+
+// Aggregate streams the children of the Firebase reference, page by page
+// in the order of its query, into r so that aggregates over large
+// locations never hold more than a single page in memory. Only the
+// children matching the filters and limit of the query are reduced, by key
+// if it is not ordered; a query limited with LimitToLast or made Shallow
+// fails with an error wrapping ErrInvalidQuery, see Pages. Aggregate stops
+// early if ctx is done or r returns an error.
+func Aggregate(ctx context.Context, fb *Firebase, r Reducer) error {
+	return fb.forEachChild(ctx, aggregatePageSize, r.Reduce)
+}
+
+// forEachChild calls fn for every child of the query of the reference, in
+// its order, fetching pageSize children at a time.
+func (fb *Firebase) forEachChild(ctx context.Context, pageSize int, fn func(key string, value interface{}) error) error {
+	p, err := fb.pager(pageSize, false)
+	if err != nil {
+		return err
+	}
+	for {
+		kvs, err := p.next(ctx)
+		if err != nil || len(kvs) == 0 {
+			return err
+		}
+		for _, kv := range kvs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var v interface{}
+			if err := json.Unmarshal(kv.Value, &v); err != nil {
+				return err
+			}
+			if err := fn(kv.Key, v); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in the order Firebase sorts them.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Sort(byKey(keys))
+	return keys
+}
+
+// byKey sorts keys the way Firebase does: keys that parse as 32-bit
+// integers come first in numeric order, followed by the remaining keys
+// in lexicographical order.
+//
+// Reference https://www.firebase.com/docs/rest/guide/retrieving-data.html#section-rest-ordered-data
+type byKey []string
+
+func (k byKey) Len() int      { return len(k) }
+func (k byKey) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byKey) Less(i, j int) bool {
+	a, aErr := strconv.ParseInt(k[i], 10, 32)
+	b, bErr := strconv.ParseInt(k[j], 10, 32)
+	switch {
+	case aErr == nil && bErr == nil:
+		return a < b
+	case aErr == nil:
+		return true
+	case bErr == nil:
+		return false
+	}
+	return k[i] < k[j]
+}
+
+// fieldNumber returns the numeric value of field of v, or of v itself if
+// field is empty.
+func fieldNumber(v interface{}, field string) (float64, bool) {
+	if field != "" {
+		v = lookup(v, field)
+	}
+	f, ok := v.(float64)
+	return f, ok
+}
+
+// Count is a Reducer that counts children.
+type Count struct {
+	N int64
+}
+
+// Reduce counts the child.
+func (c *Count) Reduce(key string, value interface{}) error {
+	c.N++
+	return nil
+}
+
+// Sum is a Reducer that totals a numeric field of every child. Children
+// without a numeric value for the field are skipped.
+type Sum struct {
+	// Field to total, nested fields are separated by a "/". If empty,
+	// the value of the child itself is used.
+	Field string
+	Total float64
+}
+
+// Reduce adds the field of the child to the total.
+func (s *Sum) Reduce(key string, value interface{}) error {
+	if f, ok := fieldNumber(value, s.Field); ok {
+		s.Total += f
+	}
+	return nil
+}
+
+// Avg is a Reducer that averages a numeric field of every child.
+// Children without a numeric value for the field are skipped.
+type Avg struct {
+	// Field to average, nested fields are separated by a "/". If empty,
+	// the value of the child itself is used.
+	Field string
+	Sum   float64
+	N     int64
+}
+
+// Reduce adds the field of the child to the average.
+func (a *Avg) Reduce(key string, value interface{}) error {
+	if f, ok := fieldNumber(value, a.Field); ok {
+		a.Sum += f
+		a.N++
+	}
+	return nil
+}
+
+// Value returns the average, or NaN if no children had the field.
+func (a *Avg) Value() float64 {
+	if a.N == 0 {
+		return math.NaN()
+	}
+	return a.Sum / float64(a.N)
+}
+
+// Min is a Reducer that finds the child with the smallest numeric field.
+type Min struct {
+	// Field to compare, nested fields are separated by a "/". If empty,
+	// the value of the child itself is used.
+	Field string
+	// Key of the child with the smallest value, empty if none was found.
+	Key   string
+	Value float64
+}
+
+// Reduce compares the field of the child with the current minimum.
+func (m *Min) Reduce(key string, value interface{}) error {
+	if f, ok := fieldNumber(value, m.Field); ok && (m.Key == "" || f < m.Value) {
+		m.Key, m.Value = key, f
+	}
+	return nil
+}
+
+// Max is a Reducer that finds the child with the largest numeric field.
+type Max struct {
+	// Field to compare, nested fields are separated by a "/". If empty,
+	// the value of the child itself is used.
+	Field string
+	// Key of the child with the largest value, empty if none was found.
+	Key   string
+	Value float64
+}
+
+// Reduce compares the field of the child with the current maximum.
+func (m *Max) Reduce(key string, value interface{}) error {
+	if f, ok := fieldNumber(value, m.Field); ok && (m.Key == "" || f > m.Value) {
+		m.Key, m.Value = key, f
+	}
+	return nil
+}
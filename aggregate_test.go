@@ -0,0 +1,190 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPagingServer serves children from data honoring the orderBy, startAt,
+// endAt and limitToFirst query parameters.
+func newPagingServer(t *testing.T, data map[string]interface{}) (*httptest.Server, *int) {
+	b, err := json.Marshal(data)
+	require.NoError(t, err)
+	var stored map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &stored))
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		q := req.URL.Query()
+		if q.Get(shallowParam) == "true" {
+			keys := map[string]bool{}
+			for k := range stored {
+				keys[k] = true
+			}
+			json.NewEncoder(w).Encode(keys)
+			return
+		}
+		orderBy, err := unquote(q.Get(orderByParam))
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(q.Get(limitToFirstParam))
+		require.NoError(t, err)
+		bound := func(param string) (interface{}, bool) {
+			var v interface{}
+			s := q.Get(param)
+			if s != "" {
+				require.NoError(t, json.Unmarshal([]byte(s), &v))
+			}
+			return v, s != ""
+		}
+		start, hasStart := bound(startAtParam)
+		end, hasEnd := bound(endAtParam)
+
+		keys := make([]string, 0, len(stored))
+		for k := range stored {
+			keys = append(keys, k)
+		}
+		sort.Sort(byOrder{keys: keys, orderBy: orderBy, children: stored})
+		page := map[string]interface{}{}
+		for _, k := range keys {
+			v := orderValue(orderBy, k, stored[k])
+			if orderBy == "$key" {
+				if hasStart && (byKey{k, start.(string)}).Less(0, 1) || hasEnd && (byKey{end.(string), k}).Less(0, 1) {
+					continue
+				}
+			} else if hasStart && compareOrder(v, start) < 0 || hasEnd && compareOrder(v, end) > 0 {
+				continue
+			}
+			if len(page) == limit {
+				break
+			}
+			page[k] = stored[k]
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	return server, &requests
+}
+
+func TestAggregate(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	for i := 0; i < 2500; i++ {
+		data["item"+strconv.Itoa(10000+i)] = map[string]interface{}{"price": float64(i % 10)}
+	}
+	server, requests := newPagingServer(t, data)
+	defer server.Close()
+
+	var (
+		fb    = New(server.URL)
+		count Count
+		sum   = Sum{Field: "price"}
+		avg   = Avg{Field: "price"}
+		max   = Max{Field: "price"}
+		keys  []string
+	)
+	err := Aggregate(context.Background(), fb, ReducerFunc(func(key string, value interface{}) error {
+		keys = append(keys, key)
+		for _, r := range []Reducer{&count, &sum, &avg, &max} {
+			r.Reduce(key, value)
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, *requests)
+	assert.Equal(t, int64(2500), count.N)
+	assert.Equal(t, float64(11250), sum.Total)
+	assert.Equal(t, 4.5, avg.Value())
+	assert.Equal(t, float64(9), max.Value)
+	assert.Equal(t, "item10009", max.Key)
+	assert.True(t, sort.IsSorted(byKey(keys)))
+	assert.Len(t, keys, 2500)
+}
+
+func TestAggregateStops(t *testing.T) {
+	t.Parallel()
+	server, requests := newPagingServer(t, map[string]interface{}{"a": 1, "b": 2})
+	defer server.Close()
+	fb := New(server.URL)
+
+	errStop := errors.New("stop")
+	err := Aggregate(context.Background(), fb, ReducerFunc(func(string, interface{}) error {
+		return errStop
+	}))
+	assert.Equal(t, errStop, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Aggregate(ctx, fb, &Count{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, *requests)
+}
+
+func TestAggregateQuery(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	for i := 0; i < 2500; i++ {
+		data["item"+strconv.Itoa(10000+i)] = map[string]interface{}{"price": float64(i % 10)}
+	}
+	server, _ := newPagingServer(t, data)
+	defer server.Close()
+	fb := New(server.URL).OrderByChild("price")
+
+	for _, tt := range []struct {
+		ref   *Firebase
+		count int64
+	}{
+		{fb, 2500},
+		{fb.EqualToValue(3), 250},
+		{fb.StartAtValue(5).LimitToFirst(1200), 1200},
+		{fb.StartAfter(8, "item12008"), 49 + 250},
+	} {
+		var (
+			count Count
+			last  interface{}
+		)
+		err := Aggregate(context.Background(), tt.ref, ReducerFunc(func(key string, value interface{}) error {
+			price := value.(map[string]interface{})["price"]
+			if last != nil {
+				assert.True(t, compareOrder(last, price) <= 0, "children are reduced in order")
+			}
+			last = price
+			return count.Reduce(key, value)
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, tt.count, count.N)
+	}
+
+	err := Aggregate(context.Background(), fb.LimitToLast(1), &Count{})
+	require.IsType(t, queryError(""), err)
+	assert.Equal(t, ErrInvalidQuery, err.(queryError).Unwrap())
+}
+
+func TestMinAvgWithoutField(t *testing.T) {
+	t.Parallel()
+	min := Min{}
+	avg := Avg{}
+	for k, v := range map[string]interface{}{"a": 3.0, "b": 1.0, "c": "nope"} {
+		min.Reduce(k, v)
+		avg.Reduce(k, v)
+	}
+	assert.Equal(t, "b", min.Key)
+	assert.Equal(t, 1.0, min.Value)
+	assert.Equal(t, 2.0, avg.Value())
+}
+
+func TestByKey(t *testing.T) {
+	t.Parallel()
+	keys := []string{"b", "10", "a", "2", "-1", "99999999999"}
+	sort.Sort(byKey(keys))
+	assert.Equal(t, []string{"-1", "2", "10", "99999999999", "a", "b"}, keys)
+}
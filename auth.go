@@ -1,11 +1,49 @@
 package firego
 
+// TokenSource creates the auth tokens of a reference, see WithTokenSource.
+type TokenSource interface {
+	// Token returns a valid auth token.
+	Token() (string, error)
+}
+
+// TokenFunc adapts a function to a TokenSource.
+type TokenFunc func() (string, error)
+
+// Token calls f.
+func (f TokenFunc) Token() (string, error) {
+	return f()
+}
+
 // Auth sets the custom Firebase token used to authenticate to Firebase.
 func (fb *Firebase) Auth(token string) {
-	fb.params.Set(authParam, token)
+	fb.setParam(authParam, token)
 }
 
 // Unauth removes the current token being used to authenticate to Firebase.
 func (fb *Firebase) Unauth() {
-	fb.params.Del(authParam)
+	fb.setParam(authParam, "")
+}
+
+// WithAuth creates a new Firebase reference that authenticates with token,
+// the reference it is called on keeps its own credentials. An empty token
+// creates an unauthenticated reference.
+func (fb *Firebase) WithAuth(token string) *Firebase {
+	c := fb.copy()
+	if token == "" {
+		c.Unauth()
+	} else {
+		c.Auth(token)
+	}
+	return c
+}
+
+// refreshAuth authenticates the reference with a new token from its
+// TokenSource.
+func (fb *Firebase) refreshAuth() error {
+	token, err := fb.tokens.Token()
+	if err != nil {
+		return err
+	}
+	fb.Auth(token)
+	return nil
 }
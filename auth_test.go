@@ -16,7 +16,7 @@ func TestAuth(t *testing.T) {
 	defer server.Close()
 
 	server.RequireAuth(true)
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 
 	fb.Auth(server.Secret)
 	var v interface{}
@@ -31,10 +31,27 @@ func TestUnauth(t *testing.T) {
 	defer server.Close()
 
 	server.RequireAuth(true)
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 
 	fb.params.Add("auth", server.Secret)
 	fb.Unauth()
 	err := fb.Value("")
 	assert.Error(t, err)
 }
+
+func TestWithAuth(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.RequireAuth(true)
+	fb := New(server.URL)
+
+	authed := fb.WithAuth(server.Secret)
+	var v interface{}
+	assert.NoError(t, authed.Child("a").Value(&v))
+	assert.Error(t, fb.Value(&v), "the original reference is not authenticated")
+	assert.Error(t, authed.WithAuth("").Value(&v))
+	assert.NoError(t, authed.Value(&v))
+}
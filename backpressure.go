@@ -0,0 +1,102 @@
+package firego
+
+import "context"
+
+// Backpressure is what a watch does when its notifications channel is not
+// received from as fast as Firebase sends events, see WithBackpressure.
+type Backpressure int
+
+const (
+	// BackpressureBlock stops reading from the connection until the event
+	// is received, the default. A consumer that falls too far behind may
+	// get the connection closed by Firebase.
+	BackpressureBlock Backpressure = iota
+	// BackpressureBuffer keeps reading from the connection while up to n
+	// events wait to be received, and blocks once there are n of them.
+	BackpressureBuffer
+	// BackpressureDropOldest keeps up to n events waiting to be received
+	// and drops the oldest one to make room for a new one.
+	BackpressureDropOldest
+	// BackpressureDropNewest keeps up to n events waiting to be received
+	// and drops the new events while there are n of them.
+	BackpressureDropNewest
+)
+
+// WithBackpressure sets what a watch does when its notifications channel
+// is full, n is the number of events that may be waiting to be received,
+// at least 1. Policies that drop events only drop put, patch and
+// keep-alive events, the events that end the watch are always delivered.
+// Since dropped put and patch events are lost, consumers that maintain a
+// copy of the data should re-read it when they fall behind.
+func WithBackpressure(policy Backpressure, n int) RequestOption {
+	return func(r *request) {
+		if n < 1 {
+			n = 1
+		}
+		r.backpressure, r.pending = policy, n
+	}
+}
+
+// relay forwards the events of in to out following policy, with up to n
+// events waiting, until in is closed and the events waiting were received
+// or ctx is done.
+func relay(ctx context.Context, in <-chan Event, out chan<- Event, policy Backpressure, n int) {
+	var queue []Event
+	for in != nil || len(queue) > 0 {
+		var (
+			send chan<- Event
+			next Event
+		)
+		if len(queue) > 0 {
+			send, next = out, queue[0]
+		}
+		receive := in
+		if policy == BackpressureBuffer && len(queue) >= n {
+			receive = nil
+		}
+
+		select {
+		case event, ok := <-receive:
+			if !ok {
+				in = nil
+				continue
+			}
+			queue = enqueue(queue, event, policy, n)
+		case send <- next:
+			queue = queue[1:]
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// enqueue adds event to the events waiting to be received, dropping one
+// of them if the queue is full and policy says so.
+func enqueue(queue []Event, event Event, policy Backpressure, n int) []Event {
+	if len(queue) < n {
+		return append(queue, event)
+	}
+	switch policy {
+	case BackpressureDropNewest:
+		if droppable(event) {
+			return queue
+		}
+	case BackpressureDropOldest:
+		for i, e := range queue {
+			if droppable(e) {
+				queue = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+	}
+	return append(queue, event)
+}
+
+// droppable reports whether a backpressure policy may drop event.
+func droppable(event Event) bool {
+	switch event.Type {
+	case EventTypePut, EventTypePatch, EventTypeKeepAlive:
+		return true
+	}
+	return false
+}
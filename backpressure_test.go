@@ -0,0 +1,103 @@
+package firego
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueue(t *testing.T) {
+	t.Parallel()
+	put := func(v float64) Event { return Event{Type: EventTypePut, Data: v} }
+	full := []Event{{Type: EventTypeCancel}, put(1), put(2)}
+
+	tests := []struct {
+		policy Backpressure
+		want   []Event
+	}{
+		{BackpressureBuffer, append(full, put(3))},
+		{BackpressureDropNewest, full},
+		{BackpressureDropOldest, []Event{{Type: EventTypeCancel}, put(2), put(3)}},
+	}
+	for _, test := range tests {
+		queue := append([]Event(nil), full...)
+		assert.Equal(t, test.want, enqueue(queue, put(3), test.policy, 3), "policy %d", test.policy)
+	}
+
+	// events that end the watch are never dropped
+	queue := []Event{{Type: EventTypeCancel}}
+	assert.Len(t, enqueue(queue, Event{Type: EventTypeError}, BackpressureDropOldest, 1), 2)
+	assert.Len(t, enqueue(queue, Event{Type: EventTypeError}, BackpressureDropNewest, 1), 2)
+}
+
+func TestRelay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy Backpressure
+		n      int
+		want   []interface{}
+	}{
+		{BackpressureBuffer, 20, []interface{}{0.0, 1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0}},
+		{BackpressureDropOldest, 3, []interface{}{7.0, 8.0, 9.0}},
+		{BackpressureDropNewest, 3, []interface{}{0.0, 1.0, 2.0}},
+	}
+	for _, test := range tests {
+		in, out := make(chan Event), make(chan Event)
+		done := make(chan struct{})
+		go func() {
+			relay(context.Background(), in, out, test.policy, test.n)
+			close(done)
+		}()
+		// nothing is received until every event was sent
+		for i := 0; i < 10; i++ {
+			in <- Event{Type: EventTypePut, Data: float64(i)}
+		}
+		close(in)
+
+		var values []interface{}
+		for len(values) < len(test.want) {
+			values = append(values, (<-out).Data)
+		}
+		<-done
+		assert.Equal(t, test.want, values, "policy %d", test.policy)
+	}
+}
+
+func TestWatchBackpressure(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, "event: put\ndata: {\"path\":\"/\",\"data\":%d}\n\n", i)
+		}
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications, WithBackpressure(BackpressureBuffer, 20)))
+	var values []interface{}
+	for event := range notifications {
+		if event.Type == EventTypePut {
+			values = append(values, event.Data)
+		}
+	}
+	assert.Len(t, values, 10)
+	assert.Equal(t, io.EOF, fb.WatchErr())
+	assert.False(t, fb.isWatching())
+
+	// the watch can be stopped while events wait to be received
+	notifications = make(chan Event)
+	require.NoError(t, fb.Watch(notifications, WithBackpressure(BackpressureDropOldest, 3)))
+	<-notifications
+	fb.StopWatching()
+	for range notifications {
+	}
+	assert.False(t, fb.isWatching())
+}
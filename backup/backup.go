@@ -0,0 +1,188 @@
+/*
+Package backup exports Firebase locations to a Destination on a schedule
+and retains the most recent backups.
+
+Every backup is a single JSON object mapping each configured path to its
+value, named after the time it was taken so that
+backups sort chronologically.
+*/
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zabawaba99/firego"
+)
+
+// DefaultPrefix is the default prefix of backup names.
+const DefaultPrefix = "backup"
+
+const (
+	timeFormat = "20060102T150405Z"
+	ext        = ".json"
+)
+
+// Runner takes backups of the configured paths.
+type Runner struct {
+	// Root is the reference that Paths are relative to.
+	Root *firego.Firebase
+	// Paths to back up, the whole database is backed up if empty.
+	Paths []string
+	// Schedule decides when Run takes backups.
+	Schedule Schedule
+	// Destination stores the backups.
+	Destination Destination
+	// Prefix of the backup names, it defaults to DefaultPrefix.
+	Prefix string
+	// Keep is the number of backups retained, older backups are removed
+	// after every successful backup. Zero keeps every backup.
+	Keep int
+	// OnError, if set, is called whenever a backup fails.
+	OnError func(error)
+	// OnBackup, if set, is called with the name of every backup taken.
+	OnBackup func(name string)
+
+	now func() time.Time
+}
+
+// Run takes backups on the Schedule until ctx is done, it always returns
+// the context's error.
+func (r *Runner) Run(ctx context.Context) error {
+	if r.Schedule == nil {
+		return errors.New("backup runner has no schedule")
+	}
+	for {
+		now := r.clock()
+		next := r.Schedule.Next(now)
+		if next.IsZero() {
+			return errors.New("backup schedule never runs")
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		name, err := r.Backup(ctx)
+		if err != nil {
+			if r.OnError != nil {
+				r.OnError(err)
+			}
+			continue
+		}
+		if r.OnBackup != nil {
+			r.OnBackup(name)
+		}
+	}
+}
+
+// Backup takes a backup now and applies the retention, it returns the name
+// of the backup. The value of every path is streamed to the Destination as
+// it is received, a backup that fails is removed from it.
+func (r *Runner) Backup(ctx context.Context) (string, error) {
+	name := r.prefix() + "-" + r.clock().UTC().Format(timeFormat) + ext
+
+	w, err := r.Destination.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if err := r.export(ctx, w); err != nil {
+		w.Close()
+		r.Destination.Remove(name)
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		r.Destination.Remove(name)
+		return "", err
+	}
+
+	return name, r.prune()
+}
+
+// List returns the names of the retained backups, oldest first.
+func (r *Runner) List() ([]string, error) {
+	names, err := r.Destination.List()
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, n := range names {
+		if strings.HasPrefix(n, r.prefix()+"-") && strings.HasSuffix(n, ext) {
+			backups = append(backups, n)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// export writes the backup to w, one path at a time.
+func (r *Runner) export(ctx context.Context, w io.Writer) error {
+	paths := r.Paths
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	sep := "{"
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ref := r.Root
+		if p = strings.Trim(p, "/"); p != "" {
+			ref = ref.Child(p)
+		}
+
+		key, _ := json.Marshal("/" + p)
+		if _, err := io.WriteString(w, sep+string(key)+":"); err != nil {
+			return err
+		}
+		if err := ref.ValueToContext(ctx, w); err != nil {
+			return err
+		}
+		sep = ","
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+// prune removes the backups exceeding Keep.
+func (r *Runner) prune() error {
+	if r.Keep <= 0 {
+		return nil
+	}
+	backups, err := r.List()
+	if err != nil {
+		return err
+	}
+	for len(backups) > r.Keep {
+		if err := r.Destination.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func (r *Runner) prefix() string {
+	if r.Prefix == "" {
+		return DefaultPrefix
+	}
+	return r.Prefix
+}
+
+func (r *Runner) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,219 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firetest"
+)
+
+// memory is a Destination that keeps backups in memory.
+type memory struct {
+	mtx     sync.Mutex
+	backups map[string]*bytes.Buffer
+	fail    error
+}
+
+type closer struct {
+	*bytes.Buffer
+	err error
+}
+
+func (c closer) Close() error { return c.err }
+
+func (m *memory) Create(name string) (io.WriteCloser, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.backups == nil {
+		m.backups = map[string]*bytes.Buffer{}
+	}
+	b := &bytes.Buffer{}
+	m.backups[name] = b
+	return closer{b, m.fail}, nil
+}
+
+func (m *memory) List() ([]string, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	var names []string
+	for n := range m.backups {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *memory) Remove(name string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.backups, name)
+	return nil
+}
+
+func (m *memory) get(name string) string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.backups[name].String()
+}
+
+func TestBackup(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", map[string]interface{}{"name": "A"})
+	server.Set("posts/p", "hello")
+
+	dest := &memory{}
+	now := time.Date(2016, 3, 1, 10, 0, 0, 0, time.UTC)
+	r := &Runner{
+		Root:        firego.New(server.URL),
+		Paths:       []string{"users", "/posts/", "missing"},
+		Destination: dest,
+		Keep:        2,
+		now:         func() time.Time { return now },
+	}
+
+	for _, expected := range []string{
+		"backup-20160301T100000Z.json",
+		"backup-20160301T101000Z.json",
+		"backup-20160301T102000Z.json",
+	} {
+		name, err := r.Backup(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, expected, name)
+		now = now.Add(10 * time.Minute)
+	}
+
+	names, err := r.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup-20160301T101000Z.json", "backup-20160301T102000Z.json"}, names)
+	assert.JSONEq(t, `{"/users":{"a":{"name":"A"}},"/posts":{"p":"hello"},"/missing":null}`, dest.get(names[0]))
+}
+
+func TestBackupWholeDatabase(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("a", 1)
+
+	dest := &memory{}
+	r := &Runner{Root: firego.New(server.URL), Destination: dest, Prefix: "db"}
+	name, err := r.Backup(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, name, "db-")
+	assert.JSONEq(t, `{"/":{"a":1}}`, dest.get(name))
+}
+
+func TestBackupFailedWrite(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	dest := &memory{fail: errors.New("disk full")}
+	r := &Runner{Root: firego.New(server.URL), Destination: dest}
+	_, err := r.Backup(context.Background())
+	assert.EqualError(t, err, "disk full")
+
+	names, err := r.List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestBackupCanceled(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("a", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dest := &memory{}
+	r := &Runner{Root: firego.New(server.URL), Paths: []string{"a"}, Destination: dest}
+	_, err := r.Backup(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	names, err := r.List()
+	require.NoError(t, err)
+	assert.Empty(t, names, "the partial backup is removed")
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backups := make(chan string, 2)
+	errs := make(chan error, 2)
+	r := &Runner{
+		Root:        firego.New(server.URL),
+		Schedule:    Every(10 * time.Millisecond),
+		Destination: &memory{},
+		OnBackup:    func(name string) { backups <- name },
+		OnError:     func(err error) { errs <- err },
+	}
+
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	select {
+	case <-backups:
+	case err := <-errs:
+		require.FailNow(t, err.Error())
+	case <-time.After(time.Second):
+		require.FailNow(t, "no backup was taken")
+	}
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestRunFailure(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	r := &Runner{
+		Root:        firego.New(server.URL),
+		Schedule:    Every(10 * time.Millisecond),
+		Destination: &memory{},
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+	go r.Run(ctx)
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "failure was not reported")
+	}
+}
@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Destination stores backups, e.g. on the local disk or in an object store
+// like S3 or GCS.
+type Destination interface {
+	// Create opens a new backup called name for writing, the backup is
+	// complete once the writer is closed.
+	Create(name string) (io.WriteCloser, error)
+	// List returns the names of the stored backups.
+	List() ([]string, error)
+	// Remove deletes the backup called name.
+	Remove(name string) error
+}
+
+// Dir is a Destination that stores backups as files in a directory.
+type Dir string
+
+// Create creates the file name in the directory, the directory is created
+// if needed.
+func (d Dir) Create(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(string(d), name))
+}
+
+// List returns the names of the files in the directory.
+func (d Dir) List() ([]string, error) {
+	infos, err := ioutil.ReadDir(string(d))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if info.Mode().IsRegular() {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
+
+// Remove deletes the file name from the directory.
+func (d Dir) Remove(name string) error {
+	return os.Remove(filepath.Join(string(d), name))
+}
+
+// WriterFunc is a Destination that writes every backup to the writer
+// returned by the function. It does not keep track of the backups it
+// wrote, so retention does not apply to it.
+type WriterFunc func(name string) (io.WriteCloser, error)
+
+// Create calls f(name).
+func (f WriterFunc) Create(name string) (io.WriteCloser, error) {
+	return f(name)
+}
+
+// List returns no backups.
+func (f WriterFunc) List() ([]string, error) {
+	return nil, nil
+}
+
+// Remove does nothing.
+func (f WriterFunc) Remove(name string) error {
+	return nil
+}
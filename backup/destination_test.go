@@ -0,0 +1,38 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+	tmp, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	d := Dir(filepath.Join(tmp, "backups"))
+	names, err := d.List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	w, err := d.Create("a.json")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`{}`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	names, err = d.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.json"}, names)
+
+	require.NoError(t, d.Remove("a.json"))
+	names, err = d.List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2016, 3, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(time.Hour), Every(time.Hour).Next(now))
+}
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+	// a Tuesday
+	now := time.Date(2016, 3, 1, 10, 7, 30, 0, time.UTC)
+	for expr, expected := range map[string]time.Time{
+		"* * * * *":     time.Date(2016, 3, 1, 10, 8, 0, 0, time.UTC),
+		"*/15 * * * *":  time.Date(2016, 3, 1, 10, 15, 0, 0, time.UTC),
+		"0 3 * * *":     time.Date(2016, 3, 2, 3, 0, 0, 0, time.UTC),
+		"30 9-17 * * *": time.Date(2016, 3, 1, 10, 30, 0, 0, time.UTC),
+		"0 0 1 * *":     time.Date(2016, 4, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 0":     time.Date(2016, 3, 6, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":     time.Date(2016, 3, 6, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 1,5":   time.Date(2016, 3, 4, 0, 0, 0, 0, time.UTC),
+		"0 0 15 * 5":    time.Date(2016, 3, 4, 0, 0, 0, 0, time.UTC),
+		"0 12 29 2 *":   time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC),
+		"5/20 10 1 3 *": time.Date(2016, 3, 1, 10, 25, 0, 0, time.UTC),
+	} {
+		s, err := ParseCron(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, expected, s.Next(now), expr)
+	}
+}
+
+func TestParseCronNeverMatches(t *testing.T) {
+	t.Parallel()
+	s, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	t.Parallel()
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
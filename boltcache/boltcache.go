@@ -0,0 +1,106 @@
+//go:build go1.17
+// +build go1.17
+
+/*
+Package boltcache implements a firego.CacheStore backed by a bbolt
+(https://github.com/etcd-io/bbolt) database file.
+
+	store, err := boltcache.Open("firego.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	fb := firego.New("https://my-firebase-app.firebaseIO.com").WithCache(store)
+
+The package needs Go 1.17 or later, like bbolt does.
+*/
+package boltcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("firego")
+
+// errCorrupt is returned when a cached entry cannot be decoded.
+var errCorrupt = errors.New("corrupt cache entry")
+
+// Store is a firego.CacheStore that persists values in a bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens, creating it if needed, the bbolt database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
+// New creates a Store that keeps its entries in an already open database.
+func New(db *bolt.DB) *Store {
+	return &Store{db: db}
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the value and ETag cached under key.
+func (s *Store) Get(key string) (value []byte, etag string, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+		entry := b.Get([]byte(key))
+		if entry == nil {
+			return nil
+		}
+
+		// entries are only valid for the life of the transaction
+		v, tag, err := decode(entry)
+		if err != nil {
+			return err
+		}
+		value, etag, ok = append([]byte(nil), v...), tag, true
+		return nil
+	})
+	return value, etag, ok, err
+}
+
+// Put caches value and its ETag under key.
+func (s *Store) Put(key string, value []byte, etag string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), encode(value, etag))
+	})
+}
+
+// encode stores the length of the ETag, the ETag and the value.
+func encode(value []byte, etag string) []byte {
+	entry := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(etag)+len(value))
+	n := binary.PutUvarint(entry, uint64(len(etag)))
+	entry = append(entry[:n], etag...)
+	return append(entry, value...)
+}
+
+func decode(entry []byte) ([]byte, string, error) {
+	l, n := binary.Uvarint(entry)
+	if n <= 0 || uint64(len(entry)-n) < l {
+		return nil, "", errCorrupt
+	}
+	etag := string(entry[n : n+int(l)])
+	return entry[n+int(l):], etag, nil
+}
@@ -0,0 +1,50 @@
+//go:build go1.17
+// +build go1.17
+
+package boltcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+)
+
+var _ firego.CacheStore = (*Store)(nil)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltcache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(filepath.Join(dir, "cache.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, _, ok, err := s.Get("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Put("https://app.firebaseio.com/a", []byte(`{"a":1}`), "etag1"))
+	value, etag, ok, err := s.Get("https://app.firebaseio.com/a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"a":1}`, string(value))
+	assert.Equal(t, "etag1", etag)
+
+	require.NoError(t, s.Put("https://app.firebaseio.com/a", []byte(`2`), ""))
+	value, etag, ok, err = s.Get("https://app.firebaseio.com/a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `2`, string(value))
+	assert.Empty(t, etag)
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	_, _, err := decode([]byte{0x05, 'a'})
+	assert.Equal(t, errCorrupt, err)
+}
@@ -0,0 +1,115 @@
+package firego
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// CacheStore persists the last known value of locations so that they can
+// be served without waiting for Firebase, e.g. on a cold start with
+// intermittent connectivity.
+type CacheStore interface {
+	// Get returns the cached JSON value and ETag stored under key. ok
+	// is false if nothing is cached.
+	Get(key string) (value []byte, etag string, ok bool, err error)
+	// Put caches the JSON value and ETag under key.
+	Put(key string, value []byte, etag string) error
+}
+
+// cache is the store of a reference along with the keys being revalidated
+// in the background, it is shared by the references derived from the one
+// WithCache returned.
+type cache struct {
+	CacheStore
+
+	mtx          sync.Mutex
+	revalidating map[string]struct{}
+}
+
+// startRevalidating reports whether key is not being revalidated already,
+// and marks it as being revalidated if so.
+func (c *cache) startRevalidating(key string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.revalidating[key]; ok {
+		return false
+	}
+	c.revalidating[key] = struct{}{}
+	return true
+}
+
+func (c *cache) doneRevalidating(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.revalidating, key)
+}
+
+// WithCache creates a new Firebase reference whose Value calls are served
+// from store when it holds the location. A cached value is returned
+// immediately and revalidated against Firebase in the background, the
+// store is only written to when the ETag of the value changed. Locations
+// that are not cached are fetched and stored before they are returned.
+// A location is only revalidated once at a time, the reads served while it
+// is being revalidated do not start another request.
+//
+// Cached values are stored before codecs and transforms are applied.
+func (fb *Firebase) WithCache(store CacheStore) *Firebase {
+	c := fb.copy()
+	c.cache = &cache{CacheStore: store, revalidating: map[string]struct{}{}}
+	return c
+}
+
+// cacheKey identifies the location and query of the reference, without
+// its credentials.
+func (fb *Firebase) cacheKey() string {
+	params := fb.queryParams()
+	params.Del(authParam)
+	key := fb.url
+	if len(params) > 0 {
+		key += "?" + params.Encode()
+	}
+	return key
+}
+
+// cachedBody returns the body of the reference from its cache.
+func (fb *Firebase) cachedBody(ctx context.Context) ([]byte, error) {
+	key := fb.cacheKey()
+	body, etag, ok, err := fb.cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if fb.cache.startRevalidating(key) {
+			go fb.revalidate(key, etag)
+		}
+		return body, nil
+	}
+	return fb.refreshCache(ctx, key, "")
+}
+
+// revalidate refreshes a cached value in the background, errors are
+// ignored since the cached value has already been served.
+func (fb *Firebase) revalidate(key, etag string) {
+	defer fb.cache.doneRevalidating(key)
+	fb.refreshCache(context.Background(), key, etag)
+}
+
+// refreshCache fetches the value of the reference and stores it under key
+// unless its ETag matches etag.
+func (fb *Firebase) refreshCache(ctx context.Context, key, etag string) ([]byte, error) {
+	resp, err := fb.do(ctx, "GET", nil, http.Header{etagHeader: {"true"}})
+	if err != nil {
+		return nil, err
+	}
+	if resp.status/200 != 1 {
+		return nil, fb.newError("GET", resp.status, resp.body)
+	}
+
+	if newTag := resp.header.Get("ETag"); newTag == "" || newTag != etag {
+		if err := fb.cache.Put(key, resp.body, newTag); err != nil {
+			return nil, err
+		}
+	}
+	return resp.body, nil
+}
@@ -0,0 +1,152 @@
+package firego
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCache struct {
+	mtx     sync.Mutex
+	entries map[string][2]string
+	puts    int
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[string][2]string{}}
+}
+
+func (m *memoryCache) Get(key string) ([]byte, string, bool, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.entries[key]
+	return []byte(e[0]), e[1], ok, nil
+}
+
+func (m *memoryCache) Put(key string, value []byte, etag string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.puts++
+	m.entries[key] = [2]string{string(value), etag}
+	return nil
+}
+
+func (m *memoryCache) entry(key string) [2]string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.entries[key]
+}
+
+func TestWithCache(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx   sync.Mutex
+		value = `"v1"`
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Equal(t, "true", req.Header.Get(etagHeader))
+		w.Header().Set("ETag", "etag-"+value)
+		fmt.Fprint(w, value)
+	}))
+	defer server.Close()
+
+	store := newMemoryCache()
+	fb := New(server.URL).WithCache(store)
+	fb.Auth("secret")
+	key := fb.cacheKey()
+	assert.NotContains(t, key, "secret")
+
+	// a miss is fetched and stored
+	var v string
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, [2]string{`"v1"`, `etag-"v1"`}, store.entry(key))
+
+	// a hit is served from the cache and revalidated in the background
+	mtx.Lock()
+	value = `"v2"`
+	mtx.Unlock()
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, "v1", v)
+
+	deadline := time.Now().Add(time.Second)
+	for store.entry(key)[0] != `"v2"` && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, [2]string{`"v2"`, `etag-"v2"`}, store.entry(key))
+
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, "v2", v)
+}
+
+func TestWithCacheUnchanged(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", "same")
+		fmt.Fprint(w, `1`)
+	}))
+	defer server.Close()
+
+	store := newMemoryCache()
+	fb := New(server.URL).WithCache(store)
+	store.Put(fb.cacheKey(), []byte(`1`), "same")
+
+	var v int
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, 1, v)
+	time.Sleep(50 * time.Millisecond)
+	store.mtx.Lock()
+	assert.Equal(t, 1, store.puts)
+	store.mtx.Unlock()
+}
+
+func TestWithCacheCoalescesRevalidation(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx      sync.Mutex
+		requests int
+	)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		requests++
+		mtx.Unlock()
+		<-release
+		w.Header().Set("ETag", "same")
+		fmt.Fprint(w, `1`)
+	}))
+	defer server.Close()
+
+	store := newMemoryCache()
+	fb := New(server.URL).WithCache(store)
+	store.Put(fb.cacheKey(), []byte(`1`), "same")
+
+	for i := 0; i < 10; i++ {
+		var v int
+		require.NoError(t, fb.Value(&v))
+		assert.Equal(t, 1, v)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		mtx.Lock()
+		n := requests
+		mtx.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	mtx.Lock()
+	assert.Equal(t, 1, requests, "a single revalidation is in flight")
+	mtx.Unlock()
+	close(release)
+}
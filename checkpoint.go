@@ -0,0 +1,144 @@
+package firego
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CheckpointStore persists the last completed key of bulk operations such
+// as Export, Import and DeleteLarge, so that an interrupted operation
+// resumes where it left off instead of starting over. Operations are
+// identified by their name and the URL of their reference.
+type CheckpointStore interface {
+	// Checkpoint returns the key saved for the operation id, ok is false
+	// if there is none.
+	Checkpoint(id string) (key string, ok bool, err error)
+	// SaveCheckpoint saves key as the last completed key of the
+	// operation id.
+	SaveCheckpoint(id, key string) error
+	// ClearCheckpoint removes the checkpoint of the operation id, it is
+	// called once the operation completed.
+	ClearCheckpoint(id string) error
+}
+
+// FileCheckpoints is a CheckpointStore that keeps checkpoints in a JSON
+// file.
+type FileCheckpoints struct {
+	path string
+	mtx  sync.Mutex
+}
+
+// NewFileCheckpoints creates a FileCheckpoints that keeps its checkpoints
+// in the file at path, which is created when needed.
+func NewFileCheckpoints(path string) *FileCheckpoints {
+	return &FileCheckpoints{path: path}
+}
+
+// Checkpoint returns the key saved for the operation id.
+func (f *FileCheckpoints) Checkpoint(id string) (string, bool, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	m, err := f.read()
+	if err != nil {
+		return "", false, err
+	}
+	key, ok := m[id]
+	return key, ok, nil
+}
+
+// SaveCheckpoint saves key for the operation id.
+func (f *FileCheckpoints) SaveCheckpoint(id, key string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	m, err := f.read()
+	if err != nil {
+		return err
+	}
+	m[id] = key
+	return f.write(m)
+}
+
+// ClearCheckpoint removes the checkpoint of the operation id.
+func (f *FileCheckpoints) ClearCheckpoint(id string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	m, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := m[id]; !ok {
+		return nil
+	}
+	delete(m, id)
+	return f.write(m)
+}
+
+func (f *FileCheckpoints) read() (map[string]string, error) {
+	m := map[string]string{}
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(b, &m)
+}
+
+// write replaces the file atomically, so that a crash never leaves a
+// partially written checkpoint behind.
+func (f *FileCheckpoints) write(m map[string]string) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), ".checkpoints-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// checkpoint tracks the checkpoint of a single operation, it does nothing
+// without a store.
+type checkpoint struct {
+	store CheckpointStore
+	id    string
+}
+
+func (fb *Firebase) checkpoint(op string, store CheckpointStore) checkpoint {
+	return checkpoint{store: store, id: op + ":" + fb.url}
+}
+
+func (c checkpoint) load() (string, bool, error) {
+	if c.store == nil {
+		return "", false, nil
+	}
+	return c.store.Checkpoint(c.id)
+}
+
+func (c checkpoint) save(key string) error {
+	if c.store == nil {
+		return nil
+	}
+	return c.store.SaveCheckpoint(c.id, key)
+}
+
+func (c checkpoint) clear() error {
+	if c.store == nil {
+		return nil
+	}
+	return c.store.ClearCheckpoint(c.id)
+}
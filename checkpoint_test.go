@@ -0,0 +1,156 @@
+package firego
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func tempCheckpoints(t *testing.T) (*FileCheckpoints, func()) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	require.NoError(t, err)
+	return NewFileCheckpoints(filepath.Join(dir, "checkpoints.json")), func() { os.RemoveAll(dir) }
+}
+
+func TestFileCheckpoints(t *testing.T) {
+	t.Parallel()
+	store, cleanup := tempCheckpoints(t)
+	defer cleanup()
+
+	_, ok, err := store.Checkpoint("export:a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.SaveCheckpoint("export:a", "k1"))
+	require.NoError(t, store.SaveCheckpoint("import:b", "k2"))
+
+	// a new store reads the same file
+	store = NewFileCheckpoints(store.path)
+	key, ok, err := store.Checkpoint("export:a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "k1", key)
+
+	require.NoError(t, store.ClearCheckpoint("export:a"))
+	require.NoError(t, store.ClearCheckpoint("missing"))
+	_, ok, err = store.Checkpoint("export:a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	key, _, err = store.Checkpoint("import:b")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", key)
+}
+
+func TestExportResume(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"a": 1.0, "b": 2.0, "c": 3.0, "d": 4.0, "e": 5.0}
+	server, _ := newPagingServer(t, data)
+	defer server.Close()
+
+	store, cleanup := tempCheckpoints(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	fb := New(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := fb.Export(ctx, &buf, ExportOptions{
+		PageSize:    2,
+		Checkpoints: store,
+		Progress: ProgressFunc(func(u ProgressUpdate) {
+			if u.LastKey == "c" {
+				cancel()
+			}
+		}),
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	key, ok, err := store.Checkpoint("export:" + server.URL)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	var done []int64
+	err = fb.Export(context.Background(), &buf, ExportOptions{
+		PageSize:    2,
+		Checkpoints: store,
+		Progress:    ProgressFunc(func(u ProgressUpdate) { done = append(done, u.Done) }),
+	})
+	require.NoError(t, err)
+
+	// "c" was written after the last checkpoint, so it is written again
+	assert.Equal(t, `{"a":1,"b":2,"c":3,"c":3,"d":4,"e":5}`, buf.String())
+	assert.Equal(t, []int64{3, 4, 5}, done)
+
+	_, ok, err = store.Checkpoint("export:" + server.URL)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestImportResume(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	store, cleanup := tempCheckpoints(t)
+	defer cleanup()
+
+	fb := New(server.URL + "/users")
+	require.NoError(t, store.SaveCheckpoint("import:"+server.URL+"/users", "b"))
+
+	err := fb.Import(context.Background(), strings.NewReader(`{"a":1,"b":2,"c":3}`), ImportOptions{Checkpoints: store})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"c": 3.0}, server.Get("users"))
+
+	_, ok, err := store.Checkpoint("import:" + server.URL + "/users")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.SaveCheckpoint("import:"+server.URL+"/users", "z"))
+	err = fb.Import(context.Background(), strings.NewReader(`{"a":1}`), ImportOptions{Checkpoints: store})
+	assert.EqualError(t, err, `checkpoint key "z" is not in the import data`)
+}
+
+func TestDeleteLargeCheckpoint(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	store, cleanup := tempCheckpoints(t)
+	defer cleanup()
+
+	server.Set("big", map[string]interface{}{"a": 1, "b": 2, "c": 3})
+	fb := New(server.URL + "/big")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := fb.DeleteLarge(ctx, DeleteOptions{
+		BatchSize:   1,
+		Checkpoints: store,
+		Progress:    ProgressFunc(func(ProgressUpdate) { cancel() }),
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	key, _, err := store.Checkpoint("delete:" + server.URL + "/big")
+	require.NoError(t, err)
+	assert.Equal(t, "a", key)
+
+	var removed []string
+	err = fb.DeleteLarge(context.Background(), DeleteOptions{
+		BatchSize:   1,
+		Checkpoints: store,
+		Progress:    ProgressFunc(func(u ProgressUpdate) { removed = append(removed, u.LastKey) }),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, removed)
+	assert.Nil(t, server.Get("big"))
+}
@@ -0,0 +1,133 @@
+package firego
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Child event types.
+const (
+	// ChildAdded is the type of a ChildEvent for a child that did not
+	// exist before, including the children that exist when the watch
+	// starts.
+	ChildAdded = "child_added"
+	// ChildChanged is the type of a ChildEvent for a child whose value
+	// changed.
+	ChildChanged = "child_changed"
+	// ChildRemoved is the type of a ChildEvent for a child that was
+	// removed.
+	ChildRemoved = "child_removed"
+	// ChildMoved is the type of a ChildEvent for a child whose change
+	// moved it to another place in the order of the query. It follows the
+	// ChildChanged event of the child.
+	ChildMoved = "child_moved"
+)
+
+// ChildEvent describes a change to a child of a watched location, like
+// the child events of the official SDKs.
+type ChildEvent struct {
+	// Type is one of ChildAdded, ChildChanged, ChildRemoved or
+	// ChildMoved.
+	Type string
+	// Key of the child.
+	Key string
+	// Value of the child, its last value for ChildRemoved.
+	Value interface{}
+	// PrevKey is the key of the child before it in the order of the
+	// query, empty if it is the first child or it was removed.
+	PrevKey string
+}
+
+// WatchChildren is like Watch but the put and patch events are turned into
+// events for the children of the reference they added, changed, removed or
+// moved. Children are ordered the way the query of the reference orders
+// them, by key without OrderBy. The watch is stopped with StopWatching and
+// WatchErr tells why it ended once events is closed.
+func (fb *Firebase) WatchChildren(events chan ChildEvent) error {
+	notifications := make(chan Event)
+	if err := fb.Watch(notifications); err != nil {
+		return err
+	}
+
+	orderBy := "$key"
+	if s, err := unquote(fb.queryParams().Get(orderByParam)); err == nil && s != "" {
+		orderBy = s
+	}
+	go func() {
+		defer close(events)
+		c := &children{orderBy: orderBy}
+		for event := range notifications {
+			for _, e := range c.apply(event) {
+				events <- e
+			}
+		}
+	}()
+	return nil
+}
+
+// children keeps the value of a watched location to turn its events into
+// child events.
+type children struct {
+	orderBy string
+	value   interface{}
+	order   []string
+}
+
+// apply records the changes of event and returns the child events they
+// amount to.
+func (c *children) apply(event Event) []ChildEvent {
+	before, _ := c.value.(map[string]interface{})
+	value, ok := withEvent(c.value, event)
+	if !ok {
+		return nil
+	}
+	c.value = value
+
+	after, _ := c.value.(map[string]interface{})
+	order := make([]string, 0, len(after))
+	for k := range after {
+		order = append(order, k)
+	}
+	sort.Sort(byOrder{keys: order, orderBy: c.orderBy, children: after})
+
+	var events []ChildEvent
+	for _, k := range c.order {
+		if _, ok := after[k]; !ok {
+			events = append(events, ChildEvent{Type: ChildRemoved, Key: k, Value: before[k]})
+		}
+	}
+	oldPrev := previousKeys(c.order, after)
+	newPrev := previousKeys(order, before)
+	for i, k := range order {
+		prev := ""
+		if i > 0 {
+			prev = order[i-1]
+		}
+		old, existed := before[k]
+		switch {
+		case !existed:
+			events = append(events, ChildEvent{Type: ChildAdded, Key: k, Value: after[k], PrevKey: prev})
+		case !reflect.DeepEqual(old, after[k]):
+			events = append(events, ChildEvent{Type: ChildChanged, Key: k, Value: after[k], PrevKey: prev})
+			if oldPrev[k] != newPrev[k] {
+				events = append(events, ChildEvent{Type: ChildMoved, Key: k, Value: after[k], PrevKey: prev})
+			}
+		}
+	}
+	c.order = order
+	return events
+}
+
+// previousKeys maps the keys in order that are also in others to the key
+// before them, among those keys.
+func previousKeys(order []string, others map[string]interface{}) map[string]string {
+	prev := map[string]string{}
+	last := ""
+	for _, k := range order {
+		if _, ok := others[k]; ok {
+			prev[k] = last
+			last = k
+		}
+	}
+	return prev
+}
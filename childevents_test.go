@@ -0,0 +1,93 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestChildrenApply(t *testing.T) {
+	t.Parallel()
+	c := &children{orderBy: "score"}
+
+	events := c.apply(Event{Type: EventTypePut, Path: "/", Data: map[string]interface{}{
+		"a": map[string]interface{}{"score": 2.0},
+		"b": map[string]interface{}{"score": 1.0},
+	}})
+	assert.Equal(t, []ChildEvent{
+		{Type: ChildAdded, Key: "b", Value: map[string]interface{}{"score": 1.0}},
+		{Type: ChildAdded, Key: "a", Value: map[string]interface{}{"score": 2.0}, PrevKey: "b"},
+	}, events)
+
+	// a change that keeps the order
+	events = c.apply(Event{Type: EventTypePut, Path: "/a/name", Data: "alice"})
+	assert.Equal(t, []ChildEvent{
+		{Type: ChildChanged, Key: "a", Value: map[string]interface{}{"score": 2.0, "name": "alice"}, PrevKey: "b"},
+	}, events)
+
+	// a change that moves the child first
+	events = c.apply(Event{Type: EventTypePatch, Path: "/a", Data: map[string]interface{}{"score": 0.0}})
+	require.Len(t, events, 2)
+	assert.Equal(t, ChildChanged, events[0].Type)
+	assert.Equal(t, ChildEvent{Type: ChildMoved, Key: "a", Value: events[0].Value}, events[1])
+
+	// an added child does not move the children after it
+	events = c.apply(Event{Type: EventTypePatch, Path: "/", Data: map[string]interface{}{
+		"c":       map[string]interface{}{"score": 0.5},
+		"b/score": 3.0,
+	}})
+	assert.Equal(t, []ChildEvent{
+		{Type: ChildAdded, Key: "c", Value: map[string]interface{}{"score": 0.5}, PrevKey: "a"},
+		{Type: ChildChanged, Key: "b", Value: map[string]interface{}{"score": 3.0}, PrevKey: "c"},
+	}, events)
+
+	events = c.apply(Event{Type: EventTypePut, Path: "/c", Data: nil})
+	assert.Equal(t, []ChildEvent{
+		{Type: ChildRemoved, Key: "c", Value: map[string]interface{}{"score": 0.5}},
+	}, events)
+
+	assert.Empty(t, c.apply(Event{Type: EventTypeKeepAlive}))
+	assert.Empty(t, c.apply(Event{Type: EventTypePut, Path: "/a/score", Data: 0.0}))
+
+	events = c.apply(Event{Type: EventTypePut, Path: "/", Data: nil})
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].Key)
+	assert.Equal(t, "b", events[1].Key)
+}
+
+func TestWatchChildren(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", 1)
+	fb := New(server.URL + "/users")
+	events := make(chan ChildEvent)
+	require.NoError(t, fb.WatchChildren(events))
+
+	next := func() ChildEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no child event")
+		}
+		return ChildEvent{}
+	}
+	assert.Equal(t, ChildEvent{Type: ChildAdded, Key: "a", Value: 1.0}, next())
+
+	server.Set("users/b", 2)
+	assert.Equal(t, ChildEvent{Type: ChildAdded, Key: "b", Value: 2.0, PrevKey: "a"}, next())
+
+	server.Set("users/a", 3)
+	assert.Equal(t, ChildEvent{Type: ChildChanged, Key: "a", Value: 3.0}, next())
+
+	fb.StopWatching()
+	for range events {
+	}
+	assert.NoError(t, fb.WatchErr())
+}
@@ -0,0 +1,154 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ChildIterator walks the children of a location one at a time, in key
+// order, see Children.
+type ChildIterator struct {
+	// Prefetch is the number of children fetched ahead of the one Next
+	// returns, it must be set before the first call to Next. By default
+	// every child is fetched when Next is called.
+	Prefetch int
+
+	ref     *Firebase
+	keys    []string
+	listed  bool
+	pending []chan childResult
+	cancel  context.CancelFunc
+	ctx     context.Context
+	key     string
+	value   json.RawMessage
+	err     error
+}
+
+type childResult struct {
+	value json.RawMessage
+	err   error
+}
+
+// Children returns an iterator over the children of the Firebase reference.
+// Only the keys are listed up front, every value is fetched with its own
+// request when the iteration reaches it, so that locations with too many
+// children for a single request can be processed. Children removed while
+// iterating are skipped. The iterator must be closed if the iteration is
+// not run to its end.
+//
+//	it := ref.Children()
+//	defer it.Close()
+//	for it.Next() {
+//		var v Item
+//		if err := it.Value(&v); err != nil {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (fb *Firebase) Children() *ChildIterator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ChildIterator{ref: fb.location(), ctx: ctx, cancel: cancel}
+}
+
+// Next moves to the next child, it returns false once all the children
+// were returned or a request failed, see Err.
+func (it *ChildIterator) Next() bool {
+	return it.NextContext(context.Background())
+}
+
+// NextContext is like Next but the requests are canceled when ctx is done.
+func (it *ChildIterator) NextContext(ctx context.Context) bool {
+	it.key, it.value = "", nil
+	if it.err != nil {
+		return false
+	}
+	if !it.listed {
+		keys, err := it.ref.KeysContext(ctx)
+		if err != nil {
+			return it.fail(err)
+		}
+		it.keys, it.listed = keys, true
+	}
+
+	for len(it.keys) > 0 {
+		it.prefetch()
+
+		var r childResult
+		key := it.keys[0]
+		it.keys = it.keys[1:]
+		if len(it.pending) == 0 {
+			r.value, r.err = it.ref.Child(key).ValueRawContext(ctx)
+		} else {
+			select {
+			case r = <-it.pending[0]:
+				it.pending = it.pending[1:]
+			case <-ctx.Done():
+				return it.fail(ctx.Err())
+			}
+		}
+		if r.err != nil {
+			return it.fail(r.err)
+		}
+		if string(r.value) == "null" {
+			// removed since the keys were listed
+			continue
+		}
+		it.key, it.value = key, r.value
+		return true
+	}
+	it.Close()
+	return false
+}
+
+// prefetch starts fetching the children after the next one, up to
+// Prefetch of them. The fetches are kept in pending, in key order, with
+// keys[0] the child pending[0] fetches.
+func (it *ChildIterator) prefetch() {
+	if it.Prefetch <= 0 {
+		return
+	}
+	for len(it.pending) <= it.Prefetch && len(it.pending) < len(it.keys) {
+		ch := make(chan childResult, 1)
+		ref := it.ref.Child(it.keys[len(it.pending)])
+		go func() {
+			v, err := ref.ValueRawContext(it.ctx)
+			ch <- childResult{value: v, err: err}
+		}()
+		it.pending = append(it.pending, ch)
+	}
+}
+
+func (it *ChildIterator) fail(err error) bool {
+	it.err = err
+	it.Close()
+	return false
+}
+
+// Key returns the key of the child Next moved to.
+func (it *ChildIterator) Key() string {
+	return it.key
+}
+
+// Raw returns the JSON encoded value of the child Next moved to.
+func (it *ChildIterator) Raw() json.RawMessage {
+	return it.value
+}
+
+// Value decodes the value of the child Next moved to into v.
+func (it *ChildIterator) Value(v interface{}) error {
+	return json.Unmarshal(it.value, v)
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *ChildIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration and cancels the requests of the children that
+// are being prefetched.
+func (it *ChildIterator) Close() {
+	it.cancel()
+	it.keys, it.pending, it.listed = nil, nil, true
+}
@@ -0,0 +1,132 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newChildrenServer serves the keys of the children of /items when asked
+// for a shallow read and the value of each child otherwise.
+func newChildrenServer(values map[string]string, delay time.Duration) (*httptest.Server, func() int) {
+	var (
+		mtx      sync.Mutex
+		inFlight int
+		max      int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get(shallowParam) == "true" {
+			w.Write([]byte(`{"a":true,"b":true,"c":true,"gone":true,"denied":true}`))
+			return
+		}
+		mtx.Lock()
+		inFlight++
+		if inFlight > max {
+			max = inFlight
+		}
+		mtx.Unlock()
+		defer func() {
+			mtx.Lock()
+			inFlight--
+			mtx.Unlock()
+		}()
+		time.Sleep(delay)
+
+		key := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/items/"), "/.json")
+		v, ok := values[key]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Permission denied"}`))
+			return
+		}
+		w.Write([]byte(v))
+	}))
+	return server, func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return max
+	}
+}
+
+func TestChildren(t *testing.T) {
+	t.Parallel()
+	server, _ := newChildrenServer(map[string]string{
+		"a":      `{"n":1}`,
+		"b":      `{"n":2}`,
+		"c":      `{"n":3}`,
+		"gone":   `null`,
+		"denied": `{"n":4}`,
+	}, 0)
+	defer server.Close()
+
+	it := New(server.URL, WithHTTPClient(&http.Client{})).Child("items").Children()
+	defer it.Close()
+	var keys []string
+	var sum int
+	for it.Next() {
+		keys = append(keys, it.Key())
+		var v struct{ N int }
+		require.NoError(t, it.Value(&v))
+		sum += v.N
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"a", "b", "c", "denied"}, keys)
+	assert.Equal(t, 10, sum)
+	assert.False(t, it.Next())
+}
+
+func TestChildrenPrefetch(t *testing.T) {
+	t.Parallel()
+	server, maxInFlight := newChildrenServer(map[string]string{
+		"a":      `1`,
+		"b":      `2`,
+		"c":      `3`,
+		"gone":   `4`,
+		"denied": `5`,
+	}, 10*time.Millisecond)
+	defer server.Close()
+
+	it := New(server.URL, WithHTTPClient(&http.Client{})).Child("items").Children()
+	it.Prefetch = 2
+	defer it.Close()
+	var values []string
+	for it.Next() {
+		values = append(values, string(it.Raw()))
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"1", "2", "3", "5", "4"}, values)
+	assert.True(t, maxInFlight() <= 3, "at most the next child and 2 more are fetched")
+}
+
+func TestChildrenError(t *testing.T) {
+	t.Parallel()
+	server, _ := newChildrenServer(map[string]string{"a": `1`}, 0)
+	defer server.Close()
+
+	for _, prefetch := range []int{0, 3} {
+		it := New(server.URL, WithHTTPClient(&http.Client{})).Child("items").Children()
+		it.Prefetch = prefetch
+		require.True(t, it.Next())
+		assert.Equal(t, "a", it.Key())
+		assert.False(t, it.Next())
+		assert.Error(t, it.Err())
+		assert.False(t, it.Next())
+	}
+}
+
+func TestChildrenClose(t *testing.T) {
+	t.Parallel()
+	server, _ := newChildrenServer(map[string]string{}, 0)
+	defer server.Close()
+
+	it := New(server.URL, WithHTTPClient(&http.Client{})).Child("items").Children()
+	it.Close()
+	assert.False(t, it.Next())
+	assert.NoError(t, it.Err())
+}
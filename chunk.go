@@ -0,0 +1,149 @@
+package firego
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	manifestChild = "manifest"
+	chunksChild   = "chunks"
+)
+
+// ErrChunkIntegrity is returned by ValueChunked when the reassembled
+// chunks do not match their manifest.
+var ErrChunkIntegrity = errors.New("chunked value does not match its manifest")
+
+// chunkManifest describes a value written by SetChunked.
+type chunkManifest struct {
+	// Size of the JSON encoded value in bytes.
+	Size int `json:"size"`
+	// Chunks is the number of chunks the value was split into.
+	Chunks int `json:"chunks"`
+	// SHA256 is the hex encoded hash of the JSON encoded value.
+	SHA256 string `json:"sha256"`
+	// Generation is the child of "chunks" holding the chunks, empty for
+	// values written before chunks had generations, whose chunks are the
+	// children of "chunks" themselves.
+	Generation string `json:"generation,omitempty"`
+}
+
+// chunk returns the path of chunk i, relative to the reference.
+func (m chunkManifest) chunk(i int) string {
+	if m.Generation == "" {
+		return chunksChild + "/" + strconv.Itoa(i)
+	}
+	return chunksChild + "/" + m.Generation + "/" + strconv.Itoa(i)
+}
+
+// SetChunked sets the value of the Firebase reference by splitting its
+// JSON encoding into strings of at most chunkSize bytes, stored as
+// numbered children of "chunks", along with a "manifest" holding their
+// count and an integrity hash. Every chunk is written with its own
+// request, so values larger than the Firebase write limits can be stored.
+// The value must be read back with ValueChunked.
+//
+// The chunks of every value are written under a new generation and the
+// manifest is switched to it last, before the chunks of the previous value
+// are removed, so readers never mix the chunks of two values: a read
+// racing a write returns the previous value, or ErrChunkIntegrity if its
+// chunks were removed in the meantime.
+func (fb *Firebase) SetChunked(v interface{}, chunkSize int) error {
+	if chunkSize < utf8.UTFMax {
+		return errors.New("chunk size must be at least " + strconv.Itoa(utf8.UTFMax) + " bytes")
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var old chunkManifest
+	if err := fb.Child(manifestChild).Value(&old); err != nil {
+		return err
+	}
+
+	chunks := splitChunks(b, chunkSize)
+	sum := sha256.Sum256(b)
+	manifest := chunkManifest{
+		Size:       len(b),
+		Chunks:     len(chunks),
+		SHA256:     hex.EncodeToString(sum[:]),
+		Generation: GeneratePushID(time.Now()),
+	}
+	for i, chunk := range chunks {
+		if err := fb.Child(manifest.chunk(i)).Set(chunk); err != nil {
+			return err
+		}
+	}
+	if err := fb.Child(manifestChild).Set(manifest); err != nil {
+		return err
+	}
+
+	// remove the chunks of the previous value
+	if old.Generation != "" {
+		return fb.Child(chunksChild + "/" + old.Generation).Remove()
+	}
+	if old.Chunks > 0 {
+		stale := make(map[string]interface{}, old.Chunks)
+		for i := 0; i < old.Chunks; i++ {
+			stale[strconv.Itoa(i)] = nil
+		}
+		return fb.Child(chunksChild).Update(stale)
+	}
+	return nil
+}
+
+// ValueChunked gets a value written by SetChunked, verifying the
+// reassembled chunks against the manifest.
+func (fb *Firebase) ValueChunked(v interface{}) error {
+	var manifest chunkManifest
+	if err := fb.Child(manifestChild).Value(&manifest); err != nil {
+		return err
+	}
+	if manifest.Chunks == 0 {
+		return json.Unmarshal([]byte("null"), v)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(manifest.Size)
+	for i := 0; i < manifest.Chunks; i++ {
+		var chunk string
+		if err := fb.Child(manifest.chunk(i)).Value(&chunk); err != nil {
+			return err
+		}
+		buf.WriteString(chunk)
+	}
+
+	b := buf.Bytes()
+	sum := sha256.Sum256(b)
+	if len(b) != manifest.Size || hex.EncodeToString(sum[:]) != manifest.SHA256 {
+		return ErrChunkIntegrity
+	}
+	return json.Unmarshal(b, v)
+}
+
+// splitChunks splits b into strings of at most size bytes without
+// breaking up multi-byte characters.
+func splitChunks(b []byte, size int) []string {
+	var chunks []string
+	for len(b) > 0 {
+		end := size
+		if end >= len(b) {
+			end = len(b)
+		} else {
+			for end > 0 && !utf8.RuneStart(b[end]) {
+				end--
+			}
+		}
+		chunks = append(chunks, string(b[:end]))
+		b = b[end:]
+	}
+	return chunks
+}
@@ -0,0 +1,103 @@
+package firego
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestSetChunked(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).Child("doc")
+	doc := map[string]interface{}{"body": strings.Repeat("héllo wörld ", 20)}
+	require.NoError(t, fb.SetChunked(doc, 32))
+
+	manifest := server.Get("doc/manifest").(map[string]interface{})
+	assert.True(t, manifest["chunks"].(float64) > 1)
+
+	var v map[string]interface{}
+	require.NoError(t, fb.ValueChunked(&v))
+	assert.Equal(t, doc, v)
+
+	// the chunks of the previous value are removed once the manifest
+	// points to the new ones
+	old := manifest["generation"].(string)
+	require.NoError(t, fb.SetChunked("small", 32))
+	var s string
+	require.NoError(t, fb.ValueChunked(&s))
+	assert.Equal(t, "small", s)
+	assert.Nil(t, server.Get("doc/chunks/"+old))
+	assert.Len(t, server.Get("doc/chunks"), 1)
+}
+
+func TestSetChunkedLegacy(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	// written before chunks had generations
+	server.Set("doc/chunks", map[string]interface{}{"0": `"hel`, "1": `lo"`})
+	server.Set("doc/manifest", map[string]interface{}{
+		"size":   7,
+		"chunks": 2,
+		"sha256": "5aa762ae383fbb727af3c7a36d4940a5b8c40a989452d2304fc958ff3f354e7a",
+	})
+
+	fb := New(server.URL).Child("doc")
+	var s string
+	require.NoError(t, fb.ValueChunked(&s))
+	assert.Equal(t, "hello", s)
+
+	require.NoError(t, fb.SetChunked("bye", 32))
+	require.NoError(t, fb.ValueChunked(&s))
+	assert.Equal(t, "bye", s)
+	assert.Nil(t, server.Get("doc/chunks/0"))
+	assert.Len(t, server.Get("doc/chunks"), 1)
+}
+
+func TestValueChunkedIntegrity(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).Child("doc")
+	require.NoError(t, fb.SetChunked(strings.Repeat("x", 100), 10))
+	generation := server.Get("doc/manifest/generation").(string)
+	server.Set("doc/chunks/"+generation+"/3", "tampered!!")
+
+	var v string
+	assert.Equal(t, ErrChunkIntegrity, fb.ValueChunked(&v))
+}
+
+func TestValueChunkedMissing(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	v := map[string]interface{}{"stale": true}
+	require.NoError(t, New(server.URL).ValueChunked(&v))
+	assert.Nil(t, v)
+}
+
+func TestSplitChunks(t *testing.T) {
+	t.Parallel()
+	chunks := splitChunks([]byte("aé€😀b"), 4)
+	assert.Equal(t, "aé€😀b", strings.Join(chunks, ""))
+	for _, c := range chunks {
+		assert.True(t, utf8.ValidString(c))
+		assert.True(t, len(c) <= 4)
+	}
+
+	assert.Error(t, New(URL).SetChunked("x", 2))
+}
@@ -0,0 +1,133 @@
+package firego
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned by the requests of a reference whose client was
+// closed.
+var ErrClosed = errors.New("firebase client is closed")
+
+// lifecycle is shared by all the references derived from the same New
+// call, it tracks what has to be torn down when they are closed: the
+// watches of every reference.
+type lifecycle struct {
+	mtx     sync.Mutex
+	closed  chan struct{}
+	watches map[*watch]struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		closed:  make(chan struct{}),
+		watches: map[*watch]struct{}{},
+	}
+}
+
+func (l *lifecycle) isClosed() bool {
+	if l == nil {
+		return false
+	}
+	select {
+	case <-l.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// context returns a context that is canceled when ctx is done or the
+// client is closed. cancel must be called to release it.
+func (l *lifecycle) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if l == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-l.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// watch records w, which Close then cancels, it returns false if the
+// client is closed.
+func (l *lifecycle) watch(w *watch) bool {
+	if l == nil {
+		return true
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.isClosed() {
+		return false
+	}
+	l.watches[w] = struct{}{}
+	return true
+}
+
+func (l *lifecycle) unwatch(w *watch) {
+	if l == nil {
+		return
+	}
+	l.mtx.Lock()
+	delete(l.watches, w)
+	l.mtx.Unlock()
+}
+
+// Close shuts down the client the reference belongs to, which is shared by
+// every reference derived from the same New call. Their watches are
+// stopped, their in-flight requests are canceled and the idle connections
+// of the client's transport are closed. Requests made afterwards return
+// ErrClosed.
+func (fb *Firebase) Close() error {
+	l := fb.life
+	if l == nil {
+		return nil
+	}
+	l.mtx.Lock()
+	if l.isClosed() {
+		l.mtx.Unlock()
+		return nil
+	}
+	close(l.closed)
+	watches := l.watches
+	l.watches = map[*watch]struct{}{}
+	l.mtx.Unlock()
+
+	for w := range watches {
+		w.cancel()
+	}
+	if t, ok := fb.client.Transport.(interface {
+		CloseIdleConnections()
+	}); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// closedErr returns ErrClosed in place of err if the request failed
+// because the client was closed rather than because ctx is done.
+func (fb *Firebase) closedErr(ctx context.Context, err error) error {
+	if fb.life.isClosed() && ctx.Err() == nil {
+		return ErrClosed
+	}
+	return err
+}
+
+// cancelBody releases the context of a request once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
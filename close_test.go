@@ -0,0 +1,105 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestCloseCancelsRequests(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	fb := New(server.URL)
+	other := New(server.URL)
+	done := make(chan error)
+	go func() {
+		var v interface{}
+		done <- fb.Child("a").Value(&v)
+	}()
+
+	<-started
+	require.NoError(t, fb.Close())
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrClosed, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the request was not canceled")
+	}
+
+	var v interface{}
+	assert.Equal(t, ErrClosed, fb.Child("b").Value(&v))
+	assert.Equal(t, ErrClosed, fb.Set(1))
+	assert.Equal(t, ErrClosed, fb.Watch(make(chan Event)))
+	assert.NoError(t, fb.Close())
+	assert.False(t, other.life.isClosed(), "other clients are not closed")
+}
+
+func TestCloseStopsWatches(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	notifications := make(chan Event)
+	require.NoError(t, fb.Child("foo").Watch(notifications))
+	<-notifications // initial notification
+
+	require.NoError(t, fb.Close())
+	select {
+	case _, ok := <-notifications:
+		assert.False(t, ok, "notifications should be closed")
+	case <-time.After(time.Second):
+		require.FailNow(t, "the watch was not stopped")
+	}
+}
+
+func TestCloseStopsWatchesBeingStarted(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	var wg sync.WaitGroup
+	ended := make(chan bool, 20)
+	for i := 0; i < cap(ended); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notifications := make(chan Event)
+			if err := fb.Child("foo").Watch(notifications); err != nil {
+				ended <- true
+				return
+			}
+			for range notifications {
+			}
+			ended <- true
+		}()
+	}
+	require.NoError(t, fb.Close())
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "a watch started while closing was not stopped")
+	}
+	assert.Len(t, ended, cap(ended))
+}
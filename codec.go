@@ -0,0 +1,154 @@
+package firego
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Codec transparently rewrites values on their way to and from Firebase,
+// e.g. to encrypt or compress them. Both methods operate on the generic
+// JSON representation of a value: maps, slices, strings, json.Numbers,
+// bools and nil. Numbers are json.Numbers so that integers too large for a
+// float64 are not rounded by a codec that does not touch them.
+type Codec interface {
+	// Encode is called with every value before it is written.
+	Encode(v interface{}) (interface{}, error)
+	// Decode is called with every value after it is read.
+	Decode(v interface{}) (interface{}, error)
+}
+
+// LocationCodec is a Codec whose output depends on where a value is
+// stored, e.g. one that binds ciphertexts to their location. EncodeAt and
+// DecodeAt are called in place of Encode and Decode with the path of the
+// value from the root of the database, split into segments. The keys of a
+// multi-path update are paths below it.
+type LocationCodec interface {
+	Codec
+	EncodeAt(path []string, v interface{}) (interface{}, error)
+	DecodeAt(path []string, v interface{}) (interface{}, error)
+}
+
+// WithCodec creates a new Firebase reference that passes every value it
+// writes through c.Encode and every value it reads through c.Decode.
+// Codecs are inherited by children and applied in the order they were
+// added when writing, and in reverse order when reading.
+func (fb *Firebase) WithCodec(c Codec) *Firebase {
+	cp := fb.copy()
+	cp.codecs = append(append([]Codec(nil), fb.codecs...), c)
+	return cp
+}
+
+// encode marshals v into the JSON that is sent to Firebase.
+func (fb *Firebase) encode(v interface{}) ([]byte, error) {
+	if len(fb.codecs) == 0 {
+		return json.Marshal(v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	n, err := decodeNumbers(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range fb.codecs {
+		if lc, ok := c.(LocationCodec); ok {
+			n, err = lc.EncodeAt(fb.segments(), n)
+		} else {
+			n, err = c.Encode(n)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(n)
+}
+
+// locatesValues reports whether a codec of the reference has to know the
+// location of the values it encodes, see LocationCodec.
+func (fb *Firebase) locatesValues() bool {
+	for _, c := range fb.codecs {
+		if _, ok := c.(LocationCodec); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeNumbers decodes the JSON value b into its generic representation,
+// with json.Numbers for numbers.
+func decodeNumbers(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// encodeReader returns the body that is sent to Firebase for the JSON
+// encoded value read from r. r is returned as is unless the reference has
+// codecs.
+func (fb *Firebase) encodeReader(r io.Reader) (io.Reader, error) {
+	if len(fb.codecs) == 0 {
+		return r, nil
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	b, err := fb.encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// decodeBytes applies the codecs and transforms of the reference to the
+// JSON encoded value found at the reference.
+func (fb *Firebase) decodeBytes(b []byte) ([]byte, error) {
+	if len(fb.codecs) == 0 && len(fb.transforms) == 0 {
+		return b, nil
+	}
+
+	v, err := decodeNumbers(b)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = fb.decodeValue(nil, v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// decodeValue applies the codecs and transforms of the reference to v,
+// the value found at path relative to the reference.
+func (fb *Firebase) decodeValue(path []string, v interface{}) (interface{}, error) {
+	v, err := fb.decodeCodecs(path, v)
+	if err != nil {
+		return nil, err
+	}
+	return fb.transformValue(path, v), nil
+}
+
+// decodeCodecs applies the codecs of the reference, but not its
+// transforms, to v, the value found at path relative to the reference.
+func (fb *Firebase) decodeCodecs(path []string, v interface{}) (interface{}, error) {
+	for i := len(fb.codecs) - 1; i >= 0; i-- {
+		var err error
+		if lc, ok := fb.codecs[i].(LocationCodec); ok {
+			v, err = lc.DecodeAt(append(fb.segments(), path...), v)
+		} else {
+			v, err = fb.codecs[i].Decode(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,85 @@
+package firego
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+// suffixCodec appends its suffix to every string it encodes and strips
+// it when decoding.
+type suffixCodec string
+
+func (s suffixCodec) Encode(v interface{}) (interface{}, error) {
+	if str, ok := v.(string); ok {
+		return str + string(s), nil
+	}
+	return v, nil
+}
+
+func (s suffixCodec) Decode(v interface{}) (interface{}, error) {
+	if str, ok := v.(string); ok && len(str) >= len(s) {
+		return str[:len(str)-len(s)], nil
+	}
+	return v, nil
+}
+
+func TestWithCodec(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).WithCodec(suffixCodec("-a")).WithCodec(suffixCodec("-b"))
+	child := fb.Child("value")
+	require.NoError(t, child.Set("hello"))
+	assert.Equal(t, "hello-a-b", server.Get("value"))
+
+	var v string
+	require.NoError(t, child.Value(&v))
+	assert.Equal(t, "hello", v)
+
+	ref, err := fb.Push("pushed")
+	require.NoError(t, err)
+	assert.NotNil(t, ref)
+}
+
+func TestCodecLargeIntegers(t *testing.T) {
+	t.Parallel()
+	var written string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "PUT":
+			b, _ := ioutil.ReadAll(req.Body)
+			written = string(b)
+			w.Write(b)
+		case req.Header.Get("Accept") == "text/event-stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(`event: put` + "\n" + `data: {"path":"/","data":{"id":9007199254740993}}` + "\n\n"))
+		default:
+			w.Write([]byte(`{"id":9007199254740993}`))
+		}
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).WithCodec(suffixCodec("-a"))
+	var v struct {
+		ID int64 `json:"id"`
+	}
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, int64(9007199254740993), v.ID)
+
+	require.NoError(t, fb.Set(map[string]int64{"id": 9007199254740993}))
+	assert.JSONEq(t, `{"id":9007199254740993}`, written)
+
+	events := make(chan Event)
+	require.NoError(t, fb.Watch(events))
+	event := <-events
+	assert.JSONEq(t, `{"id":9007199254740993}`, string(event.Raw))
+	fb.StopWatching()
+}
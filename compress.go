@@ -0,0 +1,115 @@
+package firego
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+)
+
+// compressedField is the marker field under which Compression stores
+// compressed values.
+const compressedField = "_gz"
+
+// Compression is a Codec that gzips and base64 encodes string values
+// longer than Threshold bytes before they are written, storing them as
+// {"_gz": "<data>"}, and restores them after they are read. Binary data
+// marshalled by encoding/json is a base64 string and is compressed too.
+type Compression struct {
+	// Threshold is the length, in bytes, above which strings are
+	// compressed.
+	Threshold int
+}
+
+// NewCompression creates a Compression codec for strings longer than
+// threshold bytes.
+func NewCompression(threshold int) *Compression {
+	return &Compression{Threshold: threshold}
+}
+
+// Encode compresses the large strings inside v. Strings that would not
+// shrink are left as they are.
+func (c *Compression) Encode(v interface{}) (interface{}, error) {
+	switch node := v.(type) {
+	case string:
+		if len(node) <= c.Threshold {
+			return v, nil
+		}
+
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write([]byte(node)); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+		if len(encoded) >= len(node) {
+			return v, nil
+		}
+		return map[string]interface{}{compressedField: encoded}, nil
+	case map[string]interface{}:
+		for k, child := range node {
+			child, err := c.Encode(child)
+			if err != nil {
+				return nil, err
+			}
+			node[k] = child
+		}
+	case []interface{}:
+		for i, child := range node {
+			child, err := c.Encode(child)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = child
+		}
+	}
+	return v, nil
+}
+
+// Decode restores the compressed strings inside v.
+func (c *Compression) Decode(v interface{}) (interface{}, error) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if encoded, ok := node[compressedField].(string); ok && len(node) == 1 {
+			return decompress(encoded)
+		}
+		for k, child := range node {
+			child, err := c.Decode(child)
+			if err != nil {
+				return nil, err
+			}
+			node[k] = child
+		}
+	case []interface{}:
+		for i, child := range node {
+			child, err := c.Decode(child)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = child
+		}
+	}
+	return v, nil
+}
+
+func decompress(encoded string) (interface{}, error) {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
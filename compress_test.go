@@ -0,0 +1,46 @@
+package firego
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).WithCodec(NewCompression(64))
+	doc := map[string]interface{}{
+		"title": "short",
+		"body":  strings.Repeat("lorem ipsum ", 100),
+		"pages": []interface{}{strings.Repeat("a", 200), "b"},
+	}
+	require.NoError(t, fb.Child("doc").Set(doc))
+
+	stored := server.Get("doc").(map[string]interface{})
+	assert.Equal(t, "short", stored["title"])
+	require.IsType(t, map[string]interface{}{}, stored["body"])
+	assert.Contains(t, stored["body"], compressedField)
+	assert.True(t, len(stored["body"].(map[string]interface{})[compressedField].(string)) < 1200)
+
+	var v map[string]interface{}
+	require.NoError(t, fb.Child("doc").Value(&v))
+	assert.Equal(t, doc, v)
+}
+
+func TestCompressionIncompressible(t *testing.T) {
+	t.Parallel()
+	c := NewCompression(4)
+	v, err := c.Encode("abcdefgh")
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefgh", v)
+
+	_, err = c.Decode(map[string]interface{}{compressedField: "not base64!"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+package firego
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// keyCursor is the key part of a two-argument cursor, it breaks the tie
+// between the children whose ordering value equals the cursor's.
+type keyCursor struct {
+	value string
+	key   string
+	// exclusive cursors leave out the child they point to.
+	exclusive bool
+}
+
+// excludes reports whether the child with the given key and ordering
+// value is the one an exclusive cursor points to.
+func (c *keyCursor) excludes(value, key string) bool {
+	return c != nil && c.exclusive && value == c.value && (c.key == "" || key == c.key)
+}
+
+// StartAtKey is like StartAtValue, but of the children whose ordering
+// value equals v, only the ones whose key is key or sorts after it are
+// returned, like startAt(value, key) in the official SDKs. It makes it
+// possible to resume an ordered query after the last child of a page even
+// if other children share its value.
+//
+// The REST API only supports the value part of the cursor, the key part is
+// applied to the response of Value. The limits of the query are applied by
+// Firebase before, so fewer children than the limit may be returned.
+//
+//	OrderByChild("age").StartAtKey(25, "uid123").LimitToFirst(10)
+func (fb *Firebase) StartAtKey(v interface{}, key string) *Firebase {
+	c := fb.StartAtValue(v)
+	c.startKey = &keyCursor{value: jsonLiteral(v), key: key}
+	return c
+}
+
+// EndAtKey is like EndAtValue, but of the children whose ordering value
+// equals v, only the ones whose key is key or sorts before it are
+// returned, see StartAtKey.
+func (fb *Firebase) EndAtKey(v interface{}, key string) *Firebase {
+	c := fb.EndAtValue(v)
+	c.endKey = &keyCursor{value: jsonLiteral(v), key: key}
+	return c
+}
+
+// StartAfter is like StartAtKey but the child the cursor points to is
+// left out as well, like startAfter(value, key) in the official SDKs. An
+// empty key leaves out every child whose ordering value equals v.
+//
+// The REST API only supports inclusive cursors, so if the query is limited
+// with LimitToFirst one more child is requested and the extra one is
+// removed from the response.
+//
+//	OrderByChild("age").StartAfter(25, "uid123").LimitToFirst(10)
+func (fb *Firebase) StartAfter(v interface{}, key string) *Firebase {
+	c := fb.StartAtValue(v)
+	c.startKey = &keyCursor{value: jsonLiteral(v), key: key, exclusive: true}
+	return c
+}
+
+// EndBefore is like EndAtKey but the child the cursor points to is left
+// out as well, see StartAfter. If the query is limited with LimitToLast
+// one more child is requested and the extra one is removed from the
+// response.
+func (fb *Firebase) EndBefore(v interface{}, key string) *Firebase {
+	c := fb.EndAtValue(v)
+	c.endKey = &keyCursor{value: jsonLiteral(v), key: key, exclusive: true}
+	return c
+}
+
+// cursorSlack returns the reference to request the value of the reference
+// from: a copy asking for one more child if an exclusive cursor may take
+// the place of one within the limit of the query.
+func (fb *Firebase) cursorSlack() *Firebase {
+	param := ""
+	switch {
+	case fb.startKey != nil && fb.startKey.exclusive:
+		param = limitToFirstParam
+	case fb.endKey != nil && fb.endKey.exclusive:
+		param = limitToLastParam
+	default:
+		return fb
+	}
+	limit, err := strconv.Atoi(fb.queryParams().Get(param))
+	if err != nil || limit <= 0 {
+		return fb
+	}
+	c := fb.copy()
+	c.params.Set(param, strconv.Itoa(limit+1))
+	return c
+}
+
+// applyKeyCursors removes the children of body that are outside of the
+// key cursors of the reference.
+func (fb *Firebase) applyKeyCursors(body []byte) ([]byte, error) {
+	if fb.startKey == nil && fb.endKey == nil {
+		return body, nil
+	}
+	var children map[string]interface{}
+	if err := json.Unmarshal(body, &children); err != nil || children == nil {
+		// not an object
+		return body, nil
+	}
+
+	orderBy := fb.ordering()
+	for k, child := range children {
+		value := jsonLiteral(orderValue(orderBy, k, child))
+		if c := fb.startKey; c != nil && value == c.value && byKey([]string{k, c.key}).Less(0, 1) {
+			delete(children, k)
+		}
+		if c := fb.endKey; c != nil && value == c.value && byKey([]string{c.key, k}).Less(0, 1) {
+			delete(children, k)
+		}
+		if fb.startKey.excludes(value, k) || fb.endKey.excludes(value, k) {
+			delete(children, k)
+		}
+	}
+	fb.trimSlack(orderBy, children)
+	return json.Marshal(children)
+}
+
+// ordering returns what the query of the reference orders the children by,
+// "$key" if it is not ordered.
+func (fb *Firebase) ordering() string {
+	orderBy := fb.queryParams().Get(orderByParam)
+	if s, err := unquote(orderBy); err == nil && s != "" {
+		return s
+	}
+	return "$key"
+}
+
+// trimSlack removes the extra child cursorSlack asked for if the exclusive
+// cursor did not take its place.
+func (fb *Firebase) trimSlack(orderBy string, children map[string]interface{}) {
+	params := fb.queryParams()
+	first, last := params.Get(limitToFirstParam), params.Get(limitToLastParam)
+	limit, err := strconv.Atoi(first + last)
+	if err != nil || limit <= 0 || len(children) <= limit {
+		return
+	}
+
+	keys := make([]string, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+	sort.Sort(byOrder{keys: keys, orderBy: orderBy, children: children})
+	if first != "" {
+		keys = keys[limit:]
+	} else {
+		keys = keys[:len(keys)-limit]
+	}
+	for _, k := range keys {
+		delete(children, k)
+	}
+}
+
+// byOrder sorts the keys of children the way Firebase orders them by
+// orderBy: null first, then false, true, numbers, strings and objects,
+// with ties broken by key.
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-ordered-data
+type byOrder struct {
+	keys     []string
+	orderBy  string
+	children map[string]interface{}
+}
+
+func (o byOrder) Len() int      { return len(o.keys) }
+func (o byOrder) Swap(i, j int) { o.keys[i], o.keys[j] = o.keys[j], o.keys[i] }
+func (o byOrder) Less(i, j int) bool {
+	ki, kj := o.keys[i], o.keys[j]
+	if o.orderBy == "$key" {
+		return byKey{ki, kj}.Less(0, 1)
+	}
+	a := orderValue(o.orderBy, ki, o.children[ki])
+	b := orderValue(o.orderBy, kj, o.children[kj])
+	if c := compareOrder(a, b); c != 0 {
+		return c < 0
+	}
+	return byKey{ki, kj}.Less(0, 1)
+}
+
+// compareOrder returns -1, 0 or 1 depending on whether the ordering value a
+// sorts before, with or after b, objects are all equal.
+func compareOrder(a, b interface{}) int {
+	if ra, rb := orderRank(a), orderRank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch a := a.(type) {
+	case float64:
+		switch b := b.(float64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case string:
+		switch b := b.(string); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// orderRank returns the rank of the type of v in the Firebase ordering.
+func orderRank(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	}
+	return 5
+}
+
+// orderValue returns the value Firebase orders the child with the given
+// key by.
+func orderValue(orderBy, key string, child interface{}) interface{} {
+	switch orderBy {
+	case "$key":
+		return key
+	case "$value":
+		return child
+	case "$priority":
+		return valueAt(child, []string{".priority"})
+	}
+	return valueAt(child, splitPath(orderBy))
+}
@@ -0,0 +1,118 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyCursors(t *testing.T) {
+	t.Parallel()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		queries = append(queries, req.URL.Query().Encode())
+		w.Write([]byte(`{
+			"a": {"age": 25},
+			"b": {"age": 25},
+			"c": {"age": 25},
+			"d": {"age": 30}
+		}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).OrderByChild("age")
+
+	var v map[string]interface{}
+	require.NoError(t, fb.StartAtKey(25, "b").Value(&v))
+	assert.Equal(t, `orderBy=%22age%22&startAt=25`, queries[0])
+	assert.Len(t, v, 3)
+	assert.NotContains(t, v, "a")
+
+	v = nil
+	require.NoError(t, fb.StartAtKey(25, "b").EndAtKey(25, "b").Value(&v))
+	// the server does not apply the value part of the cursors
+	assert.Len(t, v, 2)
+	assert.Contains(t, v, "b")
+	assert.Contains(t, v, "d")
+
+	// a new cursor value replaces the key
+	v = nil
+	require.NoError(t, fb.StartAtKey(25, "b").StartAtValue(25).Value(&v))
+	assert.Len(t, v, 4)
+
+	v = nil
+	require.NoError(t, fb.EndAtKey(30, "a").Value(&v))
+	assert.Len(t, v, 3)
+	assert.NotContains(t, v, "d")
+}
+
+func TestExclusiveCursors(t *testing.T) {
+	t.Parallel()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		queries = append(queries, req.URL.Query().Encode())
+		w.Write([]byte(`{
+			"a": {"age": 25},
+			"b": {"age": 25},
+			"c": {"age": 25},
+			"d": {"age": 30}
+		}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).OrderByChild("age")
+
+	var v map[string]interface{}
+	require.NoError(t, fb.StartAfter(25, "b").Value(&v))
+	assert.Equal(t, `orderBy=%22age%22&startAt=25`, queries[0])
+	assert.Equal(t, []string{"c", "d"}, sortedKeys(v))
+
+	// one more child is requested and the last one is trimmed
+	v = nil
+	require.NoError(t, fb.StartAfter(25, "").LimitToFirst(3).Value(&v))
+	assert.Equal(t, `limitToFirst=4&orderBy=%22age%22&startAt=25`, queries[1])
+	assert.Equal(t, []string{"d"}, sortedKeys(v))
+
+	v = nil
+	require.NoError(t, fb.StartAfter(25, "a").LimitToFirst(2).Value(&v))
+	assert.Equal(t, []string{"b", "c"}, sortedKeys(v))
+
+	v = nil
+	require.NoError(t, fb.EndBefore(30, "d").LimitToLast(2).Value(&v))
+	assert.Equal(t, `endAt=30&limitToLast=3&orderBy=%22age%22`, queries[3])
+	assert.Equal(t, []string{"b", "c"}, sortedKeys(v))
+}
+
+func TestByOrder(t *testing.T) {
+	t.Parallel()
+	children := map[string]interface{}{
+		"n":  map[string]interface{}{"v": nil},
+		"f":  map[string]interface{}{"v": false},
+		"t":  map[string]interface{}{"v": true},
+		"2":  map[string]interface{}{"v": 2.0},
+		"10": map[string]interface{}{"v": 10.0},
+		"s":  map[string]interface{}{"v": "a"},
+		"o":  map[string]interface{}{"v": map[string]interface{}{}},
+		"m":  map[string]interface{}{},
+	}
+	keys := []string{"o", "s", "10", "2", "t", "f", "n", "m"}
+	sort.Sort(byOrder{keys: keys, orderBy: "v", children: children})
+	assert.Equal(t, []string{"m", "n", "f", "t", "2", "10", "s", "o"}, keys)
+}
+
+func TestOrderValue(t *testing.T) {
+	t.Parallel()
+	child := map[string]interface{}{
+		"address":   map[string]interface{}{"zip": "10115"},
+		".priority": 3.0,
+	}
+	assert.Equal(t, "k", orderValue("$key", "k", child))
+	assert.Equal(t, child, orderValue("$value", "k", child))
+	assert.Equal(t, 3.0, orderValue("$priority", "k", child))
+	assert.Equal(t, "10115", orderValue("address/zip", "k", child))
+	assert.Nil(t, orderValue("missing", "k", child))
+}
@@ -0,0 +1,156 @@
+package firego
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultDeleteBatchSize is the number of children removed per request by
+// DeleteLarge unless configured otherwise.
+const defaultDeleteBatchSize = 500
+
+// DeleteOptions configures DeleteLarge.
+type DeleteOptions struct {
+	// BatchSize is the number of children removed per request, it
+	// defaults to 500.
+	BatchSize int
+	// Rate limits the number of children removed per second, zero does
+	// not limit it.
+	Rate float64
+	// StartAfter resumes an interrupted delete: the children up to and
+	// including this key, in key order, are not removed again.
+	StartAfter string
+	// Progress, if set, is updated after every batch. The LastKey of an
+	// update can be used as StartAfter to resume.
+	Progress Progress
+	// Checkpoints, if set, saves the last removed key after every batch
+	// and is used in place of StartAfter to resume.
+	Checkpoints CheckpointStore
+}
+
+// DeleteLarge removes the Firebase reference in batches of children
+// instead of a single request, which can time out or exceed the limits of
+// Firebase for locations with millions of children. The keys of the
+// children are listed with a shallow read, or page by page with their
+// values if there are too many keys for a single request, and removed in
+// key order using multi-path updates, the reference itself is removed
+// last.
+//
+// Reference https://firebase.google.com/docs/database/usage/limits
+func (fb *Firebase) DeleteLarge(ctx context.Context, opts DeleteOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultDeleteBatchSize
+	}
+
+	cp := fb.checkpoint("delete", opts.Checkpoints)
+	startAfter, ok, err := cp.load()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		startAfter = opts.StartAfter
+	}
+
+	next, total, err := fb.deleteBatches(ctx, startAfter, batchSize)
+	if err != nil {
+		return err
+	}
+	t := fb.track("delete", opts.Progress)
+	t.update.Total = total
+
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		keys, err := next()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		if opts.Rate > 0 && !first {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(float64(batchSize) / opts.Rate * float64(time.Second))):
+			}
+		}
+		update := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			update[k] = nil
+		}
+		if err := fb.location().UpdateContext(ctx, update); err != nil {
+			return err
+		}
+
+		last := keys[len(keys)-1]
+		t.report(len(keys), 0, last)
+		if err := cp.save(last); err != nil {
+			return err
+		}
+	}
+	if err := fb.location().RemoveContext(ctx); err != nil {
+		return err
+	}
+	return cp.clear()
+}
+
+// deleteBatches returns a function listing the keys of the children to
+// remove after startAfter, batchSize at a time, along with their number if
+// it is known. The keys are listed with a shallow read, unless Firebase
+// refuses to list that many keys in a single request, then the children
+// are listed page by page.
+func (fb *Firebase) deleteBatches(ctx context.Context, startAfter string, batchSize int) (func() ([]string, error), int64, error) {
+	keys, err := fb.KeysContext(ctx)
+	if err == nil {
+		if startAfter != "" {
+			i := sort.Search(len(keys), func(i int) bool {
+				return byKey{startAfter, keys[i]}.Less(0, 1)
+			})
+			keys = keys[i:]
+		}
+		total := int64(len(keys))
+		return func() ([]string, error) {
+			n := batchSize
+			if n > len(keys) {
+				n = len(keys)
+			}
+			batch := keys[:n]
+			keys = keys[n:]
+			return batch, nil
+		}, total, nil
+	}
+	if !tooLarge(err) {
+		return nil, 0, err
+	}
+
+	p, err := fb.location().pager(batchSize, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	if startAfter != "" {
+		p.startAfterKey(startAfter)
+	}
+	return func() ([]string, error) {
+		kvs, err := p.next(ctx)
+		keys := make([]string, len(kvs))
+		for i, kv := range kvs {
+			keys[i] = kv.Key
+		}
+		return keys, err
+	}, 0, nil
+}
+
+// location returns a copy of the reference without query parameters.
+func (fb *Firebase) location() *Firebase {
+	c := fb.copy()
+	for _, p := range []string{orderByParam, startAtParam, endAtParam, equalToParam, limitToFirstParam, limitToLastParam, shallowParam, formatParam} {
+		c.params.Del(p)
+	}
+	c.startKey, c.endKey = nil, nil
+	return c
+}
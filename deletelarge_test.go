@@ -0,0 +1,145 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestDeleteLarge(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	children := map[string]interface{}{}
+	for i := 0; i < 25; i++ {
+		children["child"+strconv.Itoa(100+i)] = map[string]interface{}{"n": i}
+	}
+	server.Set("big", children)
+	server.Set("other", true)
+
+	var updates []ProgressUpdate
+	fb := New(server.URL + "/big")
+	err := fb.DeleteLarge(context.Background(), DeleteOptions{
+		BatchSize: 10,
+		Progress:  ProgressFunc(func(u ProgressUpdate) { updates = append(updates, u) }),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, updates, 3)
+	for i, done := range []int64{10, 20, 25} {
+		assert.Equal(t, done, updates[i].Done)
+		assert.Equal(t, int64(25), updates[i].Total)
+		assert.Equal(t, "delete", updates[i].Op)
+		assert.Equal(t, "/big", updates[i].Path)
+	}
+	assert.Equal(t, "child124", updates[2].LastKey)
+	assert.Nil(t, server.Get("big"))
+	assert.Equal(t, true, server.Get("other"))
+}
+
+func TestDeleteLargeResume(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("big", map[string]interface{}{"1": 1, "2": 2, "10": 10, "a": "a"})
+
+	var removed []string
+	fb := New(server.URL + "/big")
+	err := fb.DeleteLarge(context.Background(), DeleteOptions{
+		BatchSize:  1,
+		Rate:       1000,
+		StartAfter: "2",
+		Progress:   ProgressFunc(func(u ProgressUpdate) { removed = append(removed, u.LastKey) }),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10", "a"}, removed)
+}
+
+func TestDeleteLargeCanceled(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("big", map[string]interface{}{"a": 1, "b": 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fb := New(server.URL + "/big")
+	err := fb.DeleteLarge(ctx, DeleteOptions{
+		BatchSize: 1,
+		Progress:  ProgressFunc(func(ProgressUpdate) { cancel() }),
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, map[string]interface{}{"b": 2.0}, server.Get("big"))
+}
+
+func TestDeleteLargePaged(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx     sync.Mutex
+		data    = map[string]interface{}{}
+		removed bool
+	)
+	for i := 0; i < 25; i++ {
+		data["child"+strconv.Itoa(100+i)] = float64(i)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		q := req.URL.Query()
+		switch {
+		case req.Method == "PATCH":
+			var update map[string]interface{}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&update))
+			for k := range update {
+				delete(data, k)
+			}
+			w.Write([]byte("{}"))
+		case req.Method == "DELETE":
+			removed = true
+			w.Write([]byte("null"))
+		case q.Get(shallowParam) != "":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Data requested exceeds the maximum size that can be accessed with a single request."}`))
+		default:
+			var start string
+			if s := q.Get(startAtParam); s != "" {
+				require.NoError(t, json.Unmarshal([]byte(s), &start))
+			}
+			limit, err := strconv.Atoi(q.Get(limitToFirstParam))
+			require.NoError(t, err)
+			page := map[string]interface{}{}
+			for _, k := range sortedKeys(data) {
+				if k >= start && len(page) < limit {
+					page[k] = data[k]
+				}
+			}
+			json.NewEncoder(w).Encode(page)
+		}
+	}))
+	defer server.Close()
+
+	var last []string
+	fb := New(server.URL+"/big", WithHTTPClient(&http.Client{}))
+	err := fb.DeleteLarge(context.Background(), DeleteOptions{
+		BatchSize:  10,
+		StartAfter: "child104",
+		Progress:   ProgressFunc(func(u ProgressUpdate) { last = append(last, u.LastKey) }),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"child114", "child124"}, last)
+	assert.Len(t, data, 5, "the children up to StartAfter are kept")
+	assert.True(t, removed)
+}
@@ -0,0 +1,279 @@
+package firego
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Demux shares a single watch of a reference between many subscribers of
+// the locations below it, to stay clear of the limits of Firebase on
+// concurrent streams and of the connections a process can open. Every
+// subscriber receives the events of its location as if it watched it
+// itself, with paths relative to it. The Demux owns the watch on the
+// reference.
+//
+// Events are sent to the subscribers one at a time and a subscriber that
+// does not receive its events holds up the others, until it unsubscribes.
+type Demux struct {
+	// Reconnect configures how dropped watches are re-established, see
+	// WatchReconnect.
+	Reconnect ReconnectOptions
+	// Replay is the number of the last put and patch events of the
+	// reference the Demux retains for late subscribers, which are sent
+	// the value of their location before these events, followed by the
+	// ones that affect it, instead of only its current value. The events
+	// before a resync are not replayed.
+	Replay int
+
+	ref *Firebase
+
+	mtx     sync.Mutex
+	subs    []*subscription
+	pending []*subscription
+	added   chan struct{}
+	ended   bool
+
+	done chan struct{}
+}
+
+// subscription is a subscriber of a Demux.
+type subscription struct {
+	path []string
+	ch   chan Event
+
+	// mtx is held while sending to ch, so that ch is not closed during
+	// a send
+	mtx    sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewDemux creates a Demux of the locations below ref.
+func NewDemux(ref *Firebase) *Demux {
+	return &Demux{ref: ref, added: make(chan struct{}, 1)}
+}
+
+// Start starts watching the reference in the background. It fails like
+// WatchReconnect does.
+func (d *Demux) Start() error {
+	d.done = make(chan struct{})
+	notifications := make(chan Event)
+	if err := d.ref.WatchReconnect(notifications, d.Reconnect); err != nil {
+		return err
+	}
+	go d.run(notifications)
+	return nil
+}
+
+// Stop tears down the watch and closes the channels of the subscribers.
+func (d *Demux) Stop() {
+	d.ref.StopWatching()
+	<-d.done
+}
+
+// Subscribe sends the events of the location at path, relative to the
+// reference, to ch, starting with a put event of its current value, see
+// Replay. The channel is closed when the returned function is called or the watch
+// ends, the reason is then given by the WatchErr of the reference.
+func (d *Demux) Subscribe(path string, ch chan Event) (unsubscribe func()) {
+	sub := &subscription{path: splitPath(path), ch: ch, done: make(chan struct{})}
+	d.mtx.Lock()
+	if d.ended {
+		d.mtx.Unlock()
+		sub.close()
+		return func() {}
+	}
+	d.pending = append(d.pending, sub)
+	d.mtx.Unlock()
+	select {
+	case d.added <- struct{}{}:
+	default:
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(sub.done)
+			d.remove(sub)
+			sub.close()
+		})
+	}
+}
+
+func (d *Demux) run(notifications chan Event) {
+	defer func() {
+		d.mtx.Lock()
+		subs := append(d.subs, d.pending...)
+		d.subs, d.pending, d.ended = nil, nil, true
+		d.mtx.Unlock()
+		for _, sub := range subs {
+			sub.close()
+		}
+		close(d.done)
+	}()
+
+	// the value of the location is base with the recent events applied
+	var (
+		base    interface{}
+		recent  []Event
+		started bool
+	)
+	for {
+		select {
+		case event, ok := <-notifications:
+			if !ok {
+				return
+			}
+			d.flush(started, base, recent)
+			switch {
+			case event.Type == EventTypeResynced:
+				base, recent, started = event.Data, nil, true
+			case !started || d.Replay <= 0:
+				if v, ok := withEvent(base, event); ok {
+					base, started = v, true
+				}
+			case event.Type == EventTypePut || event.Type == EventTypePatch:
+				if recent = append(recent, event); len(recent) > d.Replay {
+					base, _ = withEvent(base, recent[0])
+					recent = append(recent[:0], recent[1:]...)
+				}
+			}
+			for _, sub := range d.subscribers() {
+				if e, ok := rebase(event, sub.path); ok {
+					sub.send(e)
+				}
+			}
+		case <-d.added:
+			d.flush(started, base, recent)
+		}
+	}
+}
+
+// flush makes the pending subscriptions regular ones, once the value of
+// the location was received they are first sent a put event of the value
+// of their location in base, and then the recent events that affect it.
+func (d *Demux) flush(started bool, base interface{}, recent []Event) {
+	d.mtx.Lock()
+	pending := d.pending
+	d.subs = append(d.subs, pending...)
+	d.pending = nil
+	d.mtx.Unlock()
+	if !started {
+		return
+	}
+	for _, sub := range pending {
+		v := valueAt(base, sub.path)
+		raw, _ := json.Marshal(v)
+		sub.send(Event{Type: EventTypePut, Path: "/", Data: v, Raw: raw})
+		for _, event := range recent {
+			if e, ok := rebase(event, sub.path); ok {
+				sub.send(e)
+			}
+		}
+	}
+}
+
+func (d *Demux) subscribers() []*subscription {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return append([]*subscription(nil), d.subs...)
+}
+
+func (d *Demux) remove(sub *subscription) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for _, list := range []*[]*subscription{&d.subs, &d.pending} {
+		for i, s := range *list {
+			if s == sub {
+				*list = append((*list)[:i:i], (*list)[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// send sends e to the subscriber unless it unsubscribed.
+func (s *subscription) send(e Event) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- e:
+	case <-s.done:
+	}
+}
+
+func (s *subscription) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// rebase returns the event a watch of the location at sub, relative to the
+// watched location, would have received for event. It is false if the event
+// does not affect that location.
+func rebase(event Event, sub []string) (Event, bool) {
+	path := splitPath(event.Path)
+	switch event.Type {
+	case EventTypeResynced:
+		return rebased(EventTypeResynced, "/", valueAt(event.Data, sub)), true
+	case EventTypePut, EventTypePatch:
+	default:
+		return event, true
+	}
+
+	if hasPathPrefix(path, sub) {
+		// written at or below the location
+		event.Path = "/" + strings.Join(path[len(sub):], "/")
+		return event, true
+	}
+	if !hasPathPrefix(sub, path) {
+		return Event{}, false
+	}
+	rest := sub[len(path):]
+	if event.Type == EventTypePut {
+		return rebased(EventTypePut, "/", valueAt(event.Data, rest)), true
+	}
+
+	data, _ := event.Data.(map[string]interface{})
+	patch := map[string]interface{}{}
+	for _, k := range sortedKeys(data) {
+		key := splitPath(k)
+		switch {
+		case hasPathPrefix(rest, key):
+			// the location is replaced
+			return rebased(EventTypePut, "/", valueAt(data[k], rest[len(key):])), true
+		case hasPathPrefix(key, rest):
+			patch[strings.Join(key[len(rest):], "/")] = data[k]
+		}
+	}
+	if len(patch) == 0 {
+		return Event{}, false
+	}
+	return rebased(EventTypePatch, "/", patch), true
+}
+
+// rebased creates an event of the given type carrying v.
+func rebased(typ, path string, v interface{}) Event {
+	raw, _ := json.Marshal(v)
+	return Event{Type: typ, Path: path, Data: v, Raw: raw}
+}
+
+// hasPathPrefix reports whether path is at or below prefix.
+func hasPathPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i, seg := range prefix {
+		if path[i] != seg {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestRebase(t *testing.T) {
+	t.Parallel()
+	sub := splitPath("rooms/r1")
+	tests := []struct {
+		name  string
+		event Event
+		want  *Event
+	}{
+		{
+			name:  "below",
+			event: Event{Type: EventTypePut, Path: "/rooms/r1/title", Data: "hi"},
+			want:  &Event{Type: EventTypePut, Path: "/title", Data: "hi"},
+		},
+		{
+			name:  "at",
+			event: Event{Type: EventTypePatch, Path: "/rooms/r1", Data: map[string]interface{}{"a": 1.0}},
+			want:  &Event{Type: EventTypePatch, Path: "/", Data: map[string]interface{}{"a": 1.0}},
+		},
+		{
+			name: "put above",
+			event: Event{Type: EventTypePut, Path: "/", Data: map[string]interface{}{
+				"rooms": map[string]interface{}{"r1": "x", "r2": "y"},
+			}},
+			want: &Event{Type: EventTypePut, Path: "/", Data: "x"},
+		},
+		{
+			name:  "put elsewhere",
+			event: Event{Type: EventTypePut, Path: "/rooms/r2", Data: "y"},
+		},
+		{
+			name: "patch above replacing the location",
+			event: Event{Type: EventTypePatch, Path: "/", Data: map[string]interface{}{
+				"rooms/r1": nil,
+				"rooms/r2": "y",
+			}},
+			want: &Event{Type: EventTypePut, Path: "/"},
+		},
+		{
+			name: "patch above writing below the location",
+			event: Event{Type: EventTypePatch, Path: "/rooms", Data: map[string]interface{}{
+				"r1/title": "hi",
+				"r1/topic": "go",
+				"r2":       "y",
+			}},
+			want: &Event{Type: EventTypePatch, Path: "/", Data: map[string]interface{}{"title": "hi", "topic": "go"}},
+		},
+		{
+			name:  "patch elsewhere",
+			event: Event{Type: EventTypePatch, Path: "/rooms", Data: map[string]interface{}{"r2": "y"}},
+		},
+		{
+			name:  "keep-alive",
+			event: Event{Type: EventTypeKeepAlive},
+			want:  &Event{Type: EventTypeKeepAlive},
+		},
+	}
+	for _, test := range tests {
+		got, ok := rebase(test.event, sub)
+		if test.want == nil {
+			assert.False(t, ok, test.name)
+			continue
+		}
+		require.True(t, ok, test.name)
+		assert.Equal(t, test.want.Type, got.Type, test.name)
+		assert.Equal(t, test.want.Path, got.Path, test.name)
+		assert.Equal(t, test.want.Data, got.Data, test.name)
+	}
+}
+
+func TestDemux(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("rooms/r1/title", "general")
+	d := NewDemux(New(server.URL + "/rooms"))
+	r1, r2 := make(chan Event), make(chan Event)
+	unsubscribe := d.Subscribe("r1", r1)
+	require.NoError(t, d.Start())
+
+	next := func(ch chan Event) Event {
+		select {
+		case e := <-ch:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+		return Event{}
+	}
+	e := next(r1)
+	assert.Equal(t, "/", e.Path)
+	assert.Equal(t, map[string]interface{}{"title": "general"}, e.Data)
+
+	// a late subscriber receives the current value first
+	d.Subscribe("r2", r2)
+	e = next(r2)
+	assert.Equal(t, EventTypePut, e.Type)
+	assert.Nil(t, e.Data)
+
+	server.Set("rooms/r2/title", "random")
+	e = next(r2)
+	assert.Equal(t, "/title", e.Path)
+	assert.Equal(t, "random", e.Data)
+
+	server.Set("rooms/r1/title", "lobby")
+	e = next(r1)
+	assert.Equal(t, "/title", e.Path)
+	assert.Equal(t, `"lobby"`, string(e.Raw))
+
+	unsubscribe()
+	_, ok := <-r1
+	assert.False(t, ok)
+	server.Set("rooms/r1/title", "closed")
+
+	d.Stop()
+	for range r2 {
+	}
+}
+
+func TestDemuxReplay(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("rooms/r1/messages/m1", "hi")
+	d := NewDemux(New(server.URL + "/rooms"))
+	d.Replay = 2
+	all := make(chan Event)
+	d.Subscribe("", all)
+	require.NoError(t, d.Start())
+	defer d.Stop()
+
+	next := func(ch chan Event) Event {
+		select {
+		case e := <-ch:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+		return Event{}
+	}
+	next(all)
+	for _, m := range []string{"m2", "m3", "m4"} {
+		server.Set("rooms/r1/messages/"+m, m)
+		next(all)
+	}
+	server.Set("rooms/r2/title", "random")
+	next(all)
+
+	// the value before the last 2 events, then the ones of the location
+	r1 := make(chan Event)
+	unsubscribe := d.Subscribe("r1/messages", r1)
+	e := next(r1)
+	assert.Equal(t, EventTypePut, e.Type)
+	assert.Equal(t, "/", e.Path)
+	assert.Equal(t, map[string]interface{}{"m1": "hi", "m2": "m2", "m3": "m3"}, e.Data)
+	e = next(r1)
+	assert.Equal(t, "/m4", e.Path)
+	assert.Equal(t, "m4", e.Data)
+	unsubscribe()
+
+	// only the retained events of the location are replayed
+	r2 := make(chan Event)
+	d.Subscribe("r2", r2)
+	assert.Nil(t, next(r2).Data)
+	e = next(r2)
+	assert.Equal(t, "/title", e.Path)
+	assert.Equal(t, "random", e.Data)
+
+	server.Set("rooms/r2/title", "lobby")
+	assert.Equal(t, "lobby", next(all).Data)
+	assert.Equal(t, "lobby", next(r2).Data)
+}
@@ -0,0 +1,59 @@
+package firego
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrNotObject is returned by Diff when a value is not a JSON object.
+var ErrNotObject = errors.New("diff of a value that is not an object")
+
+// Diff returns the smallest multi-path Patch that turns from into to when
+// applied with Update to a location holding from, so that only the
+// changed parts of a large value have to be sent. Both values must encode
+// to JSON objects, or null which is treated as an empty object.
+func Diff(from, to interface{}) (Patch, error) {
+	f, err := diffObject(from)
+	if err != nil {
+		return nil, err
+	}
+	t, err := diffObject(to)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Patch{}
+	diff(p, "", f, t)
+	return p, nil
+}
+
+func diffObject(v interface{}) (map[string]interface{}, error) {
+	n, err := normalize(v)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := n.(map[string]interface{})
+	if !ok && n != nil {
+		return nil, ErrNotObject
+	}
+	return m, nil
+}
+
+func diff(p Patch, prefix string, from, to map[string]interface{}) {
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			p[prefix+k] = nil
+		}
+	}
+	for k, t := range to {
+		f := from[k]
+		fm, fok := f.(map[string]interface{})
+		tm, tok := t.(map[string]interface{})
+		switch {
+		case fok && tok:
+			diff(p, prefix+k+"/", fm, tm)
+		case !reflect.DeepEqual(f, t):
+			p[prefix+k] = t
+		}
+	}
+}
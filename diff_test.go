@@ -0,0 +1,70 @@
+package firego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	from := map[string]interface{}{
+		"name":    "bob",
+		"age":     30,
+		"address": map[string]interface{}{"city": "NYC", "zip": "10001"},
+		"tags":    []string{"a", "b"},
+		"gone":    map[string]interface{}{"x": 1},
+	}
+	to := map[string]interface{}{
+		"name":    "bob",
+		"age":     31,
+		"address": map[string]interface{}{"city": "NYC", "street": "Broadway"},
+		"tags":    []string{"a", "c"},
+		"new":     true,
+	}
+
+	p, err := Diff(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, Patch{
+		"age":            31.0,
+		"address/zip":    nil,
+		"address/street": "Broadway",
+		"tags":           []interface{}{"a", "c"},
+		"gone":           nil,
+		"new":            true,
+	}, p)
+
+	p, err = Diff(to, to)
+	require.NoError(t, err)
+	assert.Empty(t, p)
+
+	p, err = Diff(nil, map[string]int{"a": 1})
+	require.NoError(t, err)
+	assert.Equal(t, Patch{"a": 1.0}, p)
+}
+
+func TestDiffNotObject(t *testing.T) {
+	t.Parallel()
+	_, err := Diff("a", map[string]int{})
+	assert.Equal(t, ErrNotObject, err)
+	_, err = Diff(map[string]int{}, []int{1})
+	assert.Equal(t, ErrNotObject, err)
+}
+
+func TestDiffUpdate(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	from := map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 2.0}, "d": 3.0}
+	to := map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "e": 4.0}}
+	server.Set("node", from)
+
+	p, err := Diff(from, to)
+	require.NoError(t, err)
+	require.NoError(t, New(server.URL+"/node").Update(p))
+	assert.Equal(t, to, server.Get("node"))
+}
@@ -0,0 +1,224 @@
+package firego
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// encryptedPrefix marks string values produced by FieldEncryption.
+const encryptedPrefix = "enc:"
+
+// ErrCiphertext is returned when an encrypted value cannot be decrypted.
+var ErrCiphertext = errors.New("malformed ciphertext")
+
+// Cipher encrypts and decrypts field values. The additional data is
+// authenticated but not encrypted: a ciphertext only decrypts with the
+// additional data it was encrypted with.
+type Cipher interface {
+	Encrypt(plaintext, additionalData []byte) ([]byte, error)
+	Decrypt(ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// NewAESGCM returns a Cipher that seals values with AES-GCM under key,
+// which must be 16, 24 or 32 bytes long. A random nonce is generated for
+// every value and prepended to its ciphertext.
+func NewAESGCM(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm{aead}, nil
+}
+
+type gcm struct {
+	aead cipher.AEAD
+}
+
+func (g gcm) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, g.aead.NonceSize(), g.aead.NonceSize()+len(plaintext)+g.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return g.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (g gcm) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	n := g.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrCiphertext
+	}
+	return g.aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+}
+
+// KeyWrapper wraps and unwraps data keys, typically by calling out to a
+// key management service.
+type KeyWrapper interface {
+	WrapKey(key []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// NewEnvelopeCipher returns a Cipher that seals every value with a fresh
+// AES-256-GCM data key and stores the data key, wrapped by w, alongside
+// the ciphertext. Decrypting a value unwraps its data key through w.
+func NewEnvelopeCipher(w KeyWrapper) Cipher {
+	return envelope{w}
+}
+
+type envelope struct {
+	w KeyWrapper
+}
+
+func (e envelope) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	c, err := NewAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := c.Encrypt(plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := e.w.WrapKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 2, 2+len(wrapped)+len(sealed))
+	binary.BigEndian.PutUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, sealed...), nil
+}
+
+func (e envelope) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 2 {
+		return nil, ErrCiphertext
+	}
+	n := int(binary.BigEndian.Uint16(ciphertext))
+	if len(ciphertext) < 2+n {
+		return nil, ErrCiphertext
+	}
+	key, err := e.w.UnwrapKey(ciphertext[2 : 2+n])
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext[2+n:], additionalData)
+}
+
+// FieldEncryption is a Codec that encrypts the values of the declared
+// fields, wherever they appear in a written value, and decrypts them after
+// they are read. Encrypted fields are stored as strings, which means
+// Firebase queries and rules cannot inspect their contents.
+type FieldEncryption struct {
+	cipher Cipher
+	fields map[string]bool
+}
+
+// NewFieldEncryption creates a FieldEncryption codec that encrypts the
+// given field names with c.
+func NewFieldEncryption(c Cipher, fields ...string) *FieldEncryption {
+	f := &FieldEncryption{cipher: c, fields: map[string]bool{}}
+	for _, field := range fields {
+		f.fields[field] = true
+	}
+	return f
+}
+
+// Encode encrypts the declared fields of v, see EncodeAt.
+func (f *FieldEncryption) Encode(v interface{}) (interface{}, error) {
+	return f.EncodeAt(nil, v)
+}
+
+// Decode decrypts the declared fields of v, see DecodeAt.
+func (f *FieldEncryption) Decode(v interface{}) (interface{}, error) {
+	return f.DecodeAt(nil, v)
+}
+
+// EncodeAt encrypts the declared fields of v, the value stored at path.
+// The path of every field is authenticated with its ciphertext, which
+// then cannot be moved to another field or record without failing to
+// decrypt.
+func (f *FieldEncryption) EncodeAt(path []string, v interface{}) (interface{}, error) {
+	return f.walk(path, v, f.encrypt)
+}
+
+// DecodeAt decrypts the declared fields of v, the value stored at path.
+// Fields that are not encrypted are returned unchanged.
+func (f *FieldEncryption) DecodeAt(path []string, v interface{}) (interface{}, error) {
+	return f.walk(path, v, f.decrypt)
+}
+
+// walk calls fn with the path and the value of every declared field
+// found at or inside v, the value at path.
+func (f *FieldEncryption) walk(path []string, v interface{}, fn func([]string, interface{}) (interface{}, error)) (interface{}, error) {
+	if len(path) > 0 && f.fields[path[len(path)-1]] && v != nil {
+		return fn(path, v)
+	}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			// multi-path update keys are paths below v
+			child, err := f.walk(append(append([]string(nil), path...), splitPath(k)...), child, fn)
+			if err != nil {
+				return nil, err
+			}
+			node[k] = child
+		}
+	case []interface{}:
+		for i, child := range node {
+			child, err := f.walk(append(append([]string(nil), path...), strconv.Itoa(i)), child, fn)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = child
+		}
+	}
+	return v, nil
+}
+
+func (f *FieldEncryption) encrypt(path []string, v interface{}) (interface{}, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := f.cipher.Encrypt(plaintext, []byte(strings.Join(path, "/")))
+	if err != nil {
+		return nil, err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (f *FieldEncryption) decrypt(path []string, v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, encryptedPrefix) {
+		return v, nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(s[len(encryptedPrefix):])
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	plaintext, err := f.cipher.Decrypt(ciphertext, []byte(strings.Join(path, "/")))
+	if err != nil {
+		return nil, err
+	}
+	return decodeNumbers(plaintext)
+}
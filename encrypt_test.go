@@ -0,0 +1,129 @@
+package firego
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestFieldEncryption(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	c, err := NewAESGCM(testKey)
+	require.NoError(t, err)
+	fb := New(server.URL).WithCodec(NewFieldEncryption(c, "ssn", "card"))
+
+	user := map[string]interface{}{
+		"name": "bob",
+		"ssn":  "123-45-6789",
+		"card": map[string]interface{}{"number": "4111", "cvc": 123.0},
+	}
+	require.NoError(t, fb.Child("users/bob").Set(user))
+
+	stored := server.Get("users/bob").(map[string]interface{})
+	assert.Equal(t, "bob", stored["name"])
+	for _, field := range []string{"ssn", "card"} {
+		require.IsType(t, "", stored[field])
+		assert.True(t, strings.HasPrefix(stored[field].(string), encryptedPrefix))
+		assert.NotContains(t, stored[field], "4111")
+	}
+
+	var v map[string]interface{}
+	require.NoError(t, fb.Child("users").Value(&v))
+	assert.Equal(t, map[string]interface{}{"bob": user}, v)
+
+	require.NoError(t, fb.Update(map[string]interface{}{"users/bob/ssn": "000"}))
+	stored = server.Get("users/bob").(map[string]interface{})
+	assert.True(t, strings.HasPrefix(stored["ssn"].(string), encryptedPrefix))
+}
+
+func TestFieldEncryptionBindsLocations(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	c, err := NewAESGCM(testKey)
+	require.NoError(t, err)
+	fb := New(server.URL).WithCodec(NewFieldEncryption(c, "ssn", "pin"))
+	require.NoError(t, fb.Child("users/bob").Set(map[string]interface{}{"ssn": "123", "pin": "0000"}))
+	require.NoError(t, fb.Child("users/eve").Set(map[string]interface{}{"ssn": "456"}))
+
+	// fields are read at their own location, whatever the reference
+	var ssn string
+	require.NoError(t, fb.Child("users/bob/ssn").Value(&ssn))
+	assert.Equal(t, "123", ssn)
+
+	key, err := fb.Child("users").PushKey(map[string]interface{}{"ssn": "789"})
+	require.NoError(t, err)
+	require.NoError(t, fb.Child("users/"+key+"/ssn").Value(&ssn))
+	assert.Equal(t, "789", ssn)
+
+	// ciphertexts moved to another field or record do not decrypt
+	stored := server.Get("users/bob").(map[string]interface{})
+	server.Set("users/eve/ssn", stored["ssn"])
+	assert.Error(t, fb.Child("users/eve/ssn").Value(&ssn))
+	server.Set("users/bob/ssn", stored["pin"])
+	assert.Error(t, fb.Child("users/bob").Value(&map[string]interface{}{}))
+}
+
+func TestFieldEncryptionPlainValues(t *testing.T) {
+	t.Parallel()
+	c, err := NewAESGCM(testKey)
+	require.NoError(t, err)
+	f := NewFieldEncryption(c, "ssn")
+
+	v, err := f.Decode(map[string]interface{}{"ssn": "not encrypted"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ssn": "not encrypted"}, v)
+
+	_, err = f.Decode(map[string]interface{}{"ssn": encryptedPrefix + "!!"})
+	assert.Equal(t, ErrCiphertext, err)
+}
+
+type xorWrapper struct{ wraps int }
+
+func (x *xorWrapper) WrapKey(key []byte) ([]byte, error) {
+	x.wraps++
+	return x.UnwrapKey(key)
+}
+
+func (x *xorWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	out := make([]byte, len(wrapped))
+	for i, b := range wrapped {
+		out[i] = b ^ 0xff
+	}
+	return out, nil
+}
+
+func TestEnvelopeCipher(t *testing.T) {
+	t.Parallel()
+	w := &xorWrapper{}
+	c := NewEnvelopeCipher(w)
+
+	ciphertext, err := c.Encrypt([]byte("secret"), []byte("users/bob/ssn"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, w.wraps)
+
+	plaintext, err := c.Decrypt(ciphertext, []byte("users/bob/ssn"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+	_, err = c.Decrypt(ciphertext, []byte("users/eve/ssn"))
+	assert.Error(t, err)
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = c.Decrypt(ciphertext, []byte("users/bob/ssn"))
+	assert.Error(t, err)
+
+	_, err = c.Decrypt([]byte{0}, nil)
+	assert.Equal(t, ErrCiphertext, err)
+}
@@ -0,0 +1,99 @@
+package firego
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The errors an Error wraps, depending on the response of Firebase, so that
+// they can be checked with errors.Is.
+var (
+	// ErrUnauthorized is wrapped when the request was not authenticated,
+	// e.g. because the auth token expired.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrPermissionDenied is wrapped when security rules denied the
+	// request.
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrNotFound is wrapped when the database does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrPreconditionFailed is wrapped when the ETag of a conditional
+	// request did not match the value at the location.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrIndexNotDefined is wrapped when a query orders by a child that
+	// has no ".indexOn" rule.
+	ErrIndexNotDefined = errors.New("index not defined")
+)
+
+// Error is returned when Firebase responds to a request with an error
+// status, e.g. because security rules denied it.
+type Error struct {
+	// StatusCode is the HTTP status of the response, 0 for the reason
+	// of a cancel event received by Watch.
+	StatusCode int
+	// Message is the error message sent by Firebase, or the body of the
+	// response if it did not hold one.
+	Message string
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the URL of the request, with the auth token redacted.
+	URL string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("firego: %s %s: %s", e.Method, e.URL, e.Message)
+	}
+	return fmt.Sprintf("firego: %s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the error among ErrUnauthorized, ErrPermissionDenied,
+// ErrNotFound, ErrPreconditionFailed and ErrIndexNotDefined that describes
+// e, nil if none does.
+func (e *Error) Unwrap() error {
+	switch {
+	case strings.EqualFold(e.Message, "Permission denied"), e.StatusCode == http.StatusForbidden:
+		// rules deny requests with a 401 as well
+		return ErrPermissionDenied
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case strings.HasPrefix(e.Message, "Index not defined"):
+		return ErrIndexNotDefined
+	}
+	return nil
+}
+
+// newError creates the Error for a response to a request made on the
+// reference.
+func (fb *Firebase) newError(method string, status int, body []byte) *Error {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	msg := string(body)
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != "" {
+		msg = resp.Error
+	}
+	return &Error{
+		StatusCode: status,
+		Message:    msg,
+		Method:     method,
+		URL:        fb.redactedURL(),
+	}
+}
+
+// redactedURL is like String but hides the auth token, so that it can be
+// logged.
+func (fb *Firebase) redactedURL() string {
+	params := fb.queryParams()
+	if params.Get(authParam) == "" {
+		return fb.String()
+	}
+	params.Set(authParam, "REDACTED")
+	return fb.url + "/.json?" + params.Encode()
+}
@@ -0,0 +1,66 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "PUT":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error" : "Permission denied"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>Bad Gateway</html>"))
+		}
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}), WithAuth("secret"))
+	err := fb.Child("users").Set(1)
+	require.IsType(t, (*Error)(nil), err)
+	assert.Equal(t, &Error{
+		StatusCode: http.StatusUnauthorized,
+		Message:    "Permission denied",
+		Method:     "PUT",
+		URL:        server.URL + "/users/.json?auth=REDACTED",
+	}, err)
+	assert.Equal(t, "firego: PUT "+server.URL+"/users/.json?auth=REDACTED: 401 Permission denied", err.Error())
+	assert.NotContains(t, err.Error(), "secret")
+
+	var v interface{}
+	err = fb.Value(&v)
+	require.IsType(t, (*Error)(nil), err)
+	assert.Equal(t, http.StatusBadGateway, err.(*Error).StatusCode)
+	assert.Equal(t, "<html>Bad Gateway</html>", err.(*Error).Message)
+	assert.Equal(t, "GET", err.(*Error).Method)
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{http.StatusUnauthorized, "Permission denied", ErrPermissionDenied},
+		{http.StatusForbidden, "Forbidden", ErrPermissionDenied},
+		{http.StatusUnauthorized, "Auth token is expired", ErrUnauthorized},
+		{http.StatusNotFound, "Firebase error. Please ensure that you spelled the name of your Firebase correctly", ErrNotFound},
+		{http.StatusPreconditionFailed, "", ErrPreconditionFailed},
+		{http.StatusBadRequest, `Index not defined, add ".indexOn": "height", for path "/dinosaurs", to the rules`, ErrIndexNotDefined},
+		{http.StatusBadRequest, "Invalid data; couldn't parse JSON object", nil},
+		{http.StatusInternalServerError, "", nil},
+	}
+	for _, test := range tests {
+		err := &Error{StatusCode: test.status, Message: test.message}
+		assert.Equal(t, test.want, err.Unwrap(), "%d %s", test.status, test.message)
+	}
+}
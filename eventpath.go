@@ -0,0 +1,75 @@
+package firego
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Keys returns the keys of the path of the event, relative to the watched
+// reference, none for the watched location itself.
+func (e Event) Keys() []string {
+	return splitPath(e.Path)
+}
+
+// Ref returns the reference of the location the event applies to, fb
+// being the watched reference.
+func (e Event) Ref(fb *Firebase) *Firebase {
+	if len(e.Keys()) == 0 {
+		return fb
+	}
+	return fb.Child(strings.Join(e.Keys(), "/"))
+}
+
+// ApplyEvent applies the changes of a put, patch or EventTypeResynced event
+// to the value dst points to, which holds the value of the watched
+// location, e.g. a map or a struct. Fields and entries the event removes
+// are zeroed. Other events leave dst untouched.
+func ApplyEvent(dst interface{}, event Event) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("ApplyEvent requires a non-nil pointer")
+	}
+	b, err := json.Marshal(dst)
+	if err != nil {
+		return err
+	}
+	var current interface{}
+	if err := json.Unmarshal(b, &current); err != nil {
+		return err
+	}
+	next, ok := withEvent(current, event)
+	if !ok {
+		return nil
+	}
+
+	if b, err = json.Marshal(next); err != nil {
+		return err
+	}
+	fresh := reflect.New(v.Elem().Type())
+	if err := json.Unmarshal(b, fresh.Interface()); err != nil {
+		return err
+	}
+	v.Elem().Set(fresh.Elem())
+	return nil
+}
+
+// withEvent returns a copy of v with the changes of a put, patch or
+// resynced event applied, false for the other events.
+func withEvent(v interface{}, event Event) (interface{}, bool) {
+	path := splitPath(event.Path)
+	switch event.Type {
+	case EventTypePut:
+		return withPath(v, path, event.Data), true
+	case EventTypePatch:
+		data, _ := event.Data.(map[string]interface{})
+		for _, k := range sortedKeys(data) {
+			v = withPath(v, append(path[:len(path):len(path)], splitPath(k)...), data[k])
+		}
+		return v, true
+	case EventTypeResynced:
+		return event.Data, true
+	}
+	return v, false
+}
@@ -0,0 +1,54 @@
+package firego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRef(t *testing.T) {
+	t.Parallel()
+	fb := New("https://example.firebaseio.com/users")
+	event := Event{Type: EventTypePut, Path: "/a/name"}
+	assert.Equal(t, []string{"a", "name"}, event.Keys())
+	assert.Equal(t, "https://example.firebaseio.com/users/a/name/.json", event.Ref(fb).String())
+
+	event.Path = "/"
+	assert.Empty(t, event.Keys())
+	assert.Equal(t, fb, event.Ref(fb))
+}
+
+func TestApplyEvent(t *testing.T) {
+	t.Parallel()
+	type user struct {
+		Name string            `json:"name"`
+		Tags map[string]bool   `json:"tags"`
+		Meta map[string]string `json:"meta,omitempty"`
+	}
+	var u user
+	require.NoError(t, ApplyEvent(&u, Event{Type: EventTypePut, Path: "/", Data: map[string]interface{}{
+		"name": "Ann",
+		"tags": map[string]interface{}{"admin": true},
+	}}))
+	assert.Equal(t, user{Name: "Ann", Tags: map[string]bool{"admin": true}}, u)
+
+	require.NoError(t, ApplyEvent(&u, Event{Type: EventTypePatch, Path: "/", Data: map[string]interface{}{
+		"tags/admin": nil,
+		"tags/ops":   true,
+		"meta":       map[string]interface{}{"team": "db"},
+	}}))
+	assert.Equal(t, user{Name: "Ann", Tags: map[string]bool{"ops": true}, Meta: map[string]string{"team": "db"}}, u)
+
+	require.NoError(t, ApplyEvent(&u, Event{Type: EventTypePut, Path: "/name", Data: nil}))
+	assert.Equal(t, "", u.Name)
+	require.NoError(t, ApplyEvent(&u, Event{Type: EventTypeKeepAlive}))
+	assert.Equal(t, map[string]bool{"ops": true}, u.Tags)
+
+	m := map[string]interface{}{"a": 1.0}
+	require.NoError(t, ApplyEvent(&m, Event{Type: EventTypeResynced, Path: "/", Data: map[string]interface{}{"b": 2.0}}))
+	assert.Equal(t, map[string]interface{}{"b": 2.0}, m)
+
+	assert.Error(t, ApplyEvent(u, Event{Type: EventTypePut}))
+	assert.Error(t, ApplyEvent(&u, Event{Type: EventTypePut, Path: "/name", Data: 1.0}))
+}
@@ -0,0 +1,62 @@
+//go:build go1.23
+// +build go1.23
+
+package firego
+
+import (
+	"context"
+	"iter"
+)
+
+// Events watches the reference like WatchContext and returns its events as
+// an iterator, which ends when ctx is done, StopWatching is called or the
+// loop is broken out of:
+//
+//	for event, err := range fb.Events(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(event.Type, event.Path)
+//	}
+//
+// The errors that end the watch are yielded with a zero Event: the error
+// establishing the watch, the one of an EventTypeError event, ErrWatchCanceled
+// or an *Error after an EventTypeCancel event, and the error of ctx.
+func (fb *Firebase) Events(ctx context.Context) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		notifications := make(chan Event)
+		if err := fb.WatchContext(watchCtx, notifications); err != nil {
+			yield(Event{}, err)
+			return
+		}
+
+		var err error
+		for event := range notifications {
+			if event.Type == EventTypeError {
+				err, _ = event.Data.(error)
+				continue
+			}
+			if !yield(event, nil) {
+				cancel()
+				for range notifications {
+					// wait for the connection to be torn down
+				}
+				return
+			}
+			if event.Type == EventTypeCancel {
+				err = ErrWatchCanceled
+				if e, ok := event.Data.(error); ok {
+					err = e
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			yield(Event{}, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+//go:build go1.23
+// +build go1.23
+
+package firego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":2}\n\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	// breaking out of the loop stops the watch
+	var values []interface{}
+	for event, err := range fb.Events(context.Background()) {
+		require.NoError(t, err)
+		values = append(values, event.Data)
+		if len(values) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []interface{}{1.0, 2.0}, values)
+	assert.False(t, fb.isWatching())
+
+	// so does the context, whose error ends the loop
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errs []error
+	for _, err := range fb.Events(ctx) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cancel()
+	}
+	assert.Equal(t, []error{context.Canceled}, errs)
+}
+
+func TestEventsCanceled(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: cancel\ndata: null\n\n"))
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	var types []string
+	var last error
+	for event, err := range fb.Events(context.Background()) {
+		types = append(types, event.Type)
+		last = err
+	}
+	assert.Equal(t, []string{EventTypeCancel, ""}, types)
+	assert.Equal(t, ErrWatchCanceled, last)
+}
@@ -8,19 +8,19 @@ import (
 )
 
 func ExampleFirebase_Auth() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	fb.Auth("my-token")
 }
 
 func ExampleFirebase_Child() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	childFB := fb.Child("some/child/path")
 
 	log.Printf("My new ref %s\n", childFB)
 }
 
 func ExampleFirebase_Shallow() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb.Shallow(true)
 	// Remove query parameter
@@ -28,7 +28,7 @@ func ExampleFirebase_Shallow() {
 }
 
 func ExampleFirebase_IncludePriority() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb.IncludePriority(true)
 	// Remove query parameter
@@ -36,7 +36,7 @@ func ExampleFirebase_IncludePriority() {
 }
 
 func ExampleFirebase_StartAt() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb = fb.StartAt("a")
 	// Remove query parameter
@@ -44,7 +44,7 @@ func ExampleFirebase_StartAt() {
 }
 
 func ExampleFirebase_EndAt() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb = fb.EndAt("a")
 	// Remove query parameter
@@ -52,7 +52,7 @@ func ExampleFirebase_EndAt() {
 }
 
 func ExampleFirebase_OrderBy() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb = fb.OrderBy("a")
 	// Remove query parameter
@@ -60,23 +60,23 @@ func ExampleFirebase_OrderBy() {
 }
 
 func ExampleFirebase_LimitToFirst() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb = fb.LimitToFirst(5)
 	// Remove query parameter
-	fb = fb.LimitToFirst(-1)
+	fb = fb.LimitToFirst(0)
 }
 
 func ExampleFirebase_LimitToLast() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	// Set value
 	fb = fb.LimitToLast(8)
 	// Remove query parameter
-	fb = fb.LimitToLast(-1)
+	fb = fb.LimitToLast(0)
 }
 
 func ExampleFirebase_Push() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 	newRef, err := fb.Push("my-value")
 	if err != nil {
 		log.Fatal(err)
@@ -86,14 +86,14 @@ func ExampleFirebase_Push() {
 }
 
 func ExampleFirebase_Remove() {
-	fb := firego.New("https://someapp.firebaseio.com/some/value", nil)
+	fb := firego.New("https://someapp.firebaseio.com/some/value")
 	if err := fb.Remove(); err != nil {
 		log.Fatal(err)
 	}
 }
 
 func ExampleFirebase_Set() {
-	fb := firego.New("https://someapp.firebaseio.com", nil)
+	fb := firego.New("https://someapp.firebaseio.com")
 
 	v := map[string]interface{}{
 		"foo": "bar",
@@ -106,14 +106,14 @@ func ExampleFirebase_Set() {
 }
 
 func ExampleFirebase_Update() {
-	fb := firego.New("https://someapp.firebaseio.com/some/value", nil)
+	fb := firego.New("https://someapp.firebaseio.com/some/value")
 	if err := fb.Update("new-value"); err != nil {
 		log.Fatal(err)
 	}
 }
 
 func ExampleFirebase_Value() {
-	fb := firego.New("https://someapp.firebaseio.com/some/value", nil)
+	fb := firego.New("https://someapp.firebaseio.com/some/value")
 	var v interface{}
 	if err := fb.Value(v); err != nil {
 		log.Fatal(err)
@@ -123,7 +123,7 @@ func ExampleFirebase_Value() {
 }
 
 func ExampleFirebase_Watch() {
-	fb := firego.New("https://someapp.firebaseio.com/some/value", nil)
+	fb := firego.New("https://someapp.firebaseio.com/some/value")
 	notifications := make(chan firego.Event)
 	if err := fb.Watch(notifications); err != nil {
 		log.Fatal(err)
@@ -141,7 +141,7 @@ func ExampleFirebase_Watch() {
 }
 
 func ExampleFirebase_StopWatching() {
-	fb := firego.New("https://someapp.firebaseio.com/some/value", nil)
+	fb := firego.New("https://someapp.firebaseio.com/some/value")
 	notifications := make(chan firego.Event)
 	if err := fb.Watch(notifications); err != nil {
 		log.Fatal(err)
@@ -156,3 +156,26 @@ func ExampleFirebase_StopWatching() {
 
 	fb.StopWatching()
 }
+
+func ExampleCollection_Save() {
+	fb := firego.New("https://someapp.firebaseio.com")
+	users := firego.NewCollection(fb, "users", firego.Index{Path: "index/usersByEmail", Field: "email"})
+
+	v := map[string]string{"email": "bob@example.com", "name": "Bob"}
+	if err := users.Save("uid1", v); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleFirebase_SoftDelete() {
+	fb := firego.New("https://someapp.firebaseio.com/users")
+	if err := fb.Child("uid1").SoftDelete(); err != nil {
+		log.Fatal(err)
+	}
+
+	var deleted map[string]interface{}
+	if err := fb.ListDeleted(&deleted); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Deleted users %v\n", deleted)
+}
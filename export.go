@@ -0,0 +1,222 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// defaultExportPageSize is the number of children fetched per request by
+// Export unless configured otherwise.
+const defaultExportPageSize = 1000
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// PageSize is the number of children fetched per request, it
+	// defaults to 1000.
+	PageSize int
+	// Progress, if set, is updated after every child.
+	Progress Progress
+	// Checkpoints, if set, saves the last exported key after every
+	// page. A resumed export continues the JSON object written by the
+	// interrupted one, so w should append to the previous output, and
+	// the children written after the last checkpoint are written again.
+	Checkpoints CheckpointStore
+}
+
+// Export writes the children of the Firebase reference to w as a single
+// JSON object. Children are fetched page by page in key order, so
+// locations too large for a single request can be exported without
+// holding more than a page in memory. They are written as stored, the
+// codecs and transforms of the reference are not applied.
+func (fb *Firebase) Export(ctx context.Context, w io.Writer, opts ExportOptions) error {
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultExportPageSize
+	}
+
+	cp := fb.checkpoint("export", opts.Checkpoints)
+	last, resumed, err := cp.load()
+	if err != nil {
+		return err
+	}
+
+	t := fb.track("export", opts.Progress)
+	if keys, err := fb.KeysContext(ctx); err == nil {
+		t.update.Total = int64(len(keys))
+		if resumed {
+			t.update.Done = int64(sort.Search(len(keys), func(i int) bool {
+				return byKey{last, keys[i]}.Less(0, 1)
+			}))
+		}
+	}
+
+	// the children are exported as stored, without the codecs and
+	// transforms of the reference, so that Import restores them as they
+	// were
+	p, err := fb.location().pager(pageSize, true)
+	if err != nil {
+		return err
+	}
+	sep := "{"
+	if resumed {
+		sep = ","
+		p.startAfterKey(last)
+	}
+	for {
+		kvs, err := p.next(ctx)
+		if err != nil {
+			return err
+		}
+		if len(kvs) == 0 {
+			break
+		}
+		for _, kv := range kvs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			k, err := json.Marshal(kv.Key)
+			if err != nil {
+				return err
+			}
+			n, err := io.WriteString(w, sep+string(k)+":"+string(kv.Value))
+			if err != nil {
+				return err
+			}
+			sep = ","
+			t.report(1, int64(n), kv.Key)
+		}
+		if err := cp.save(kvs[len(kvs)-1].Key); err != nil {
+			return err
+		}
+	}
+
+	if sep == "{" {
+		// no children were written
+		_, err = io.WriteString(w, "{}")
+	} else {
+		_, err = io.WriteString(w, "}")
+	}
+	if err != nil {
+		return err
+	}
+	return cp.clear()
+}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// BatchSize is the number of children written per request, it
+	// defaults to 500.
+	BatchSize int
+	// Progress, if set, is updated after every batch.
+	Progress Progress
+	// Checkpoints, if set, saves the last imported key after every
+	// batch. A resumed import skips the children of r up to and
+	// including that key, so r must provide the children in the same
+	// order as the interrupted import.
+	Checkpoints CheckpointStore
+}
+
+// Import reads a JSON object, like the output of Export, from r and writes
+// its children into the Firebase reference using multi-path updates of
+// BatchSize children. Children of the reference that are not in the
+// object are kept. Like Export, the children are written as they are, the
+// codecs of the reference are not applied.
+func (fb *Firebase) Import(ctx context.Context, r io.Reader, opts ImportOptions) error {
+	dst := fb.location()
+	dst.codecs = nil
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultDeleteBatchSize
+	}
+
+	cp := fb.checkpoint("import", opts.Checkpoints)
+	skipTo, skipping, err := cp.load()
+	if err != nil {
+		return err
+	}
+
+	t := fb.track("import", opts.Progress)
+	counter := &countingReader{r: r}
+	dec := json.NewDecoder(counter)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return errors.New("import data is not a JSON object")
+	}
+
+	var (
+		batch   = make(map[string]interface{}, batchSize)
+		lastKey string
+		read    int64
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := dst.UpdateContext(ctx, batch); err != nil {
+			return err
+		}
+		t.report(len(batch), counter.n-read, lastKey)
+		read = counter.n
+		batch = make(map[string]interface{}, batchSize)
+		return cp.save(lastKey)
+	}
+
+	for dec.More() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		// a pointer since json.RawMessage only marshals through a
+		// pointer before Go 1.8
+		value := new(json.RawMessage)
+		if err := dec.Decode(value); err != nil {
+			return err
+		}
+
+		if skipping {
+			// imported before the interruption
+			skipping = key != skipTo
+			continue
+		}
+
+		batch[key] = value
+		lastKey = key
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if skipping {
+		return fmt.Errorf("checkpoint key %q is not in the import data", skipTo)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return cp.clear()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,120 @@
+package firego
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"b": map[string]interface{}{"x": 1.0},
+		"a": "A",
+		"2": true,
+	}
+	server, requests := newPagingServer(t, data)
+	defer server.Close()
+
+	var (
+		buf     bytes.Buffer
+		updates []ProgressUpdate
+	)
+	fb := New(server.URL)
+	err := fb.Export(context.Background(), &buf, ExportOptions{
+		PageSize: 2,
+		Progress: ProgressFunc(func(u ProgressUpdate) { updates = append(updates, u) }),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"2":true,"a":"A","b":{"x":1}}`, buf.String())
+	assert.Equal(t, 3, *requests)
+	require.Len(t, updates, 3)
+	assert.Equal(t, "export", updates[2].Op)
+	assert.Equal(t, int64(3), updates[2].Done)
+	assert.Equal(t, int64(3), updates[2].Total)
+	assert.Equal(t, "b", updates[2].LastKey)
+	assert.Equal(t, int64(buf.Len()-1), updates[2].Bytes)
+}
+
+func TestExportEmpty(t *testing.T) {
+	t.Parallel()
+	server, _ := newPagingServer(t, map[string]interface{}{})
+	defer server.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, New(server.URL).Export(context.Background(), &buf, ExportOptions{}))
+	assert.Equal(t, `{}`, buf.String())
+}
+
+func TestExportStored(t *testing.T) {
+	t.Parallel()
+	stored := `{"a":"A-x","n":9007199254740993}`
+	var imported string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "PATCH" {
+			b, _ := ioutil.ReadAll(req.Body)
+			imported = string(b)
+		}
+		w.Write([]byte(stored))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).WithCodec(suffixCodec("-x"))
+	require.NoError(t, fb.Export(context.Background(), &buf, ExportOptions{}))
+	assert.Equal(t, stored, buf.String(), "codecs are not applied and large integers are kept")
+
+	require.NoError(t, fb.Import(context.Background(), &buf, ImportOptions{}))
+	assert.JSONEq(t, stored, imported)
+	assert.Contains(t, imported, "9007199254740993")
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/keep", "me")
+
+	var updates []ProgressUpdate
+	input := `{"a": {"name": "A"}, "b": [1, 2], "c": "C"}`
+	fb := New(server.URL + "/users")
+	err := fb.Import(context.Background(), strings.NewReader(input), ImportOptions{
+		BatchSize: 2,
+		Progress:  ProgressFunc(func(u ProgressUpdate) { updates = append(updates, u) }),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"keep": "me",
+		"a":    map[string]interface{}{"name": "A"},
+		"b":    []interface{}{1.0, 2.0},
+		"c":    "C",
+	}, server.Get("users"))
+
+	require.Len(t, updates, 2)
+	assert.Equal(t, int64(2), updates[0].Done)
+	assert.Equal(t, int64(3), updates[1].Done)
+	assert.Equal(t, "c", updates[1].LastKey)
+	assert.Equal(t, "/users", updates[1].Path)
+	assert.Equal(t, int64(-1), updates[1].Total)
+	assert.Equal(t, int64(len(input)), updates[1].Bytes)
+}
+
+func TestImportInvalid(t *testing.T) {
+	t.Parallel()
+	fb := New(URL)
+	for _, input := range []string{`[1, 2]`, `{"a": `, ``} {
+		assert.Error(t, fb.Import(context.Background(), strings.NewReader(input), ImportOptions{}), input)
+	}
+}
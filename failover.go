@@ -0,0 +1,186 @@
+package firego
+
+import (
+	"net/http"
+	_url "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProbeInterval is the default time between health probes of an
+// unreachable primary database.
+const DefaultProbeInterval = 10 * time.Second
+
+// Failover is an http.RoundTripper that sends reads to fallback database
+// URLs, e.g. read replicas, while the primary database is unreachable.
+// Writes always go to the primary. Once failed over, the primary is probed
+// in the background and reads fail back as soon as it responds again.
+//
+// A request counts as failed if no response was received or the response
+// has a 5xx status code.
+type Failover struct {
+	// Transport performs the requests, http.DefaultTransport is used if
+	// nil.
+	Transport http.RoundTripper
+	// ProbeInterval between health probes of the primary, it defaults to
+	// DefaultProbeInterval.
+	ProbeInterval time.Duration
+
+	// targets holds the primary followed by the fallbacks
+	targets []*_url.URL
+
+	mtx    sync.Mutex
+	active int
+	stop   chan struct{}
+}
+
+// NewFailover creates a Failover from the primary database URL to the
+// given fallback URLs, tried in order.
+func NewFailover(primary string, fallbacks ...string) (*Failover, error) {
+	f := &Failover{}
+	for _, raw := range append([]string{primary}, fallbacks...) {
+		u, err := _url.Parse(sanitizeURL(raw))
+		if err != nil {
+			return nil, err
+		}
+		f.targets = append(f.targets, u)
+	}
+	return f, nil
+}
+
+// Active returns the URL of the database that reads are currently sent to.
+func (f *Failover) Active() string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.targets[f.active].String()
+}
+
+// Close stops probing the primary.
+func (f *Failover) Close() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *Failover) RoundTrip(req *http.Request) (*http.Response, error) {
+	primary := f.targets[0]
+	if req.URL.Host != primary.Host || (req.Method != "GET" && req.Method != "HEAD") {
+		return f.transport().RoundTrip(req)
+	}
+
+	f.mtx.Lock()
+	start := f.active
+	f.mtx.Unlock()
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i := start; i < len(f.targets); i++ {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = f.transport().RoundTrip(f.rewrite(req, f.targets[i]))
+		if !failed(resp, err) {
+			f.setActive(i)
+			return resp, nil
+		}
+	}
+	return resp, err
+}
+
+func (f *Failover) transport() http.RoundTripper {
+	if f.Transport != nil {
+		return f.Transport
+	}
+	return http.DefaultTransport
+}
+
+// rewrite returns a copy of req sent to target instead of the primary.
+func (f *Failover) rewrite(req *http.Request, target *_url.URL) *http.Request {
+	if target == f.targets[0] {
+		return req
+	}
+	u := *req.URL
+	u.Scheme = target.Scheme
+	u.Host = target.Host
+	u.Path = target.Path + strings.TrimPrefix(u.Path, f.targets[0].Path)
+	// e.g. the namespace of an emulator
+	query := u.Query()
+	for k, v := range target.Query() {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+
+	r := new(http.Request)
+	*r = *req
+	r.URL = &u
+	r.Host = ""
+	return r
+}
+
+func (f *Failover) setActive(i int) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.active == i {
+		return
+	}
+	f.active = i
+	if i > 0 && f.stop == nil {
+		f.stop = make(chan struct{})
+		go f.probe(f.stop)
+	}
+}
+
+// probe checks the health of the primary until it responds again.
+func (f *Failover) probe(stop chan struct{}) {
+	interval := f.ProbeInterval
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	u := *f.targets[0]
+	u.Path += "/.json"
+	query := u.Query()
+	query.Set(shallowParam, "true")
+	u.RawQuery = query.Encode()
+	probeURL := u.String()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		req, err := http.NewRequest("GET", probeURL, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := f.transport().RoundTrip(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if failed(resp, err) {
+			continue
+		}
+
+		f.mtx.Lock()
+		f.active = 0
+		if f.stop == stop {
+			f.stop = nil
+		}
+		f.mtx.Unlock()
+		return
+	}
+}
+
+func failed(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
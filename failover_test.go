@@ -0,0 +1,139 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailover(t *testing.T) {
+	t.Parallel()
+
+	var down int32 = 1
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`"primary"`))
+	}))
+	defer primary.Close()
+
+	var fallbackReq *http.Request
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fallbackReq = req
+		if req.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte(`"fallback"`))
+	}))
+	defer fallback.Close()
+
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+
+	f, err := NewFailover(primary.URL, unreachable.URL, fallback.URL+"/replica")
+	require.NoError(t, err)
+	f.ProbeInterval = 10 * time.Millisecond
+	defer f.Close()
+
+	fb := New(primary.URL, WithTransport(f))
+
+	var v string
+	require.NoError(t, fb.Child("foo").Value(&v))
+	assert.Equal(t, "fallback", v)
+	assert.Equal(t, "/replica/foo/.json", fallbackReq.URL.Path)
+	assert.Equal(t, fallback.URL+"/replica", f.Active())
+
+	// writes are never failed over
+	assert.Error(t, fb.Set("bar"))
+	assert.Equal(t, "GET", fallbackReq.Method)
+
+	atomic.StoreInt32(&down, 0)
+	deadline := time.Now().Add(time.Second)
+	for f.Active() != primary.URL {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "did not fail back to the primary")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, "primary", v)
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`"bad gateway"`))
+	}))
+	defer server.Close()
+
+	f, err := NewFailover(server.URL, server.URL+"/replica")
+	require.NoError(t, err)
+	defer f.Close()
+
+	fb := New(server.URL, WithTransport(f))
+	var v string
+	assert.Error(t, fb.Value(&v))
+	assert.Equal(t, server.URL, f.Active())
+}
+
+func TestFailoverEmulatorNamespaces(t *testing.T) {
+	t.Parallel()
+
+	var (
+		down   int32 = 1
+		probed int32
+	)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get(shallowParam) == "true" {
+			assert.Equal(t, "/.json", req.URL.Path)
+			assert.Equal(t, "primary", req.URL.Query().Get("ns"))
+			atomic.StoreInt32(&probed, 1)
+		}
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`"primary"`))
+	}))
+	defer primary.Close()
+
+	var fallbackReq *http.Request
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fallbackReq = req
+		w.Write([]byte(`"fallback"`))
+	}))
+	defer fallback.Close()
+
+	f, err := NewFailover(primary.URL+"?ns=primary", fallback.URL+"?ns=replica")
+	require.NoError(t, err)
+	f.ProbeInterval = 10 * time.Millisecond
+	defer f.Close()
+
+	fb := New(primary.URL+"?ns=primary", WithTransport(f))
+	var v string
+	require.NoError(t, fb.Child("foo").Value(&v))
+	assert.Equal(t, "fallback", v)
+	assert.Equal(t, "/foo/.json", fallbackReq.URL.Path)
+	assert.Equal(t, "replica", fallbackReq.URL.Query().Get("ns"))
+
+	atomic.StoreInt32(&down, 0)
+	deadline := time.Now().Add(time.Second)
+	for f.Active() != primary.URL+"?ns=primary" {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "did not fail back to the primary")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probed))
+}
@@ -4,9 +4,9 @@ Package firego is a REST client for Firebase (https://firebase.com).
 package firego
 
 import (
-	"bytes"
-	"errors"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -16,9 +16,10 @@ import (
 	"time"
 )
 
-// TimeoutDuration is the length of time any request will have to establish
-// a connection and receive headers from Firebase before returning
-// an ErrTimeout error.
+// TimeoutDuration is the default timeout of the references created by New,
+// see WithTimeout. Changing it only affects references created afterwards.
+//
+// Deprecated: use WithTimeout, which can differ between references.
 var TimeoutDuration = 30 * time.Second
 
 var defaultRedirectLimit = 30
@@ -37,25 +38,55 @@ const (
 	orderByParam      = "orderBy"
 	startAtParam      = "startAt"
 	endAtParam        = "endAt"
+	equalToParam      = "equalTo"
 	formatVal         = "export"
 	limitToFirstParam = "limitToFirst"
 	limitToLastParam  = "limitToLast"
+	downloadParam     = "download"
+	printParam        = "print"
+	silentVal         = "silent"
+	timeoutParam      = "timeout"
+	writeSizeParam    = "writeSizeLimit"
 )
 
-// Firebase represents a location in the cloud.
+// Firebase represents a location in the cloud. A reference is safe for
+// concurrent use, including the methods that change its parameters in
+// place such as Shallow and Auth.
 type Firebase struct {
-	url    string
-	params _url.Values
-	client *http.Client
+	url       string
+	params    _url.Values
+	paramsMtx sync.RWMutex
+	client    *http.Client
+	logger    Logger
+	tokens    TokenSource
+	header    http.Header
+	life      *lifecycle
+
+	maxEventSize int
+
+	startKey *keyCursor
+	endKey   *keyCursor
 
-	watchMtx     sync.Mutex
-	watching     bool
-	stopWatching chan struct{}
+	codecs     []Codec
+	transforms []transform
+	cache      *cache
+
+	watchMtx sync.Mutex
+	watches  map[*watch]struct{}
+	watchErr error
+
+	listenersMtx sync.Mutex
+	listeners    *valueListeners
 }
 
 func sanitizeURL(url string) string {
 	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
-		url = "https://" + url
+		if isLocalHost(hostname(url)) {
+			// the emulator does not serve TLS
+			url = "http://" + url
+		} else {
+			url = "https://" + url
+		}
 	}
 
 	if strings.HasSuffix(url, "/") {
@@ -85,33 +116,57 @@ func redirectPreserveHeaders(req *http.Request, via []*http.Request) error {
 	return nil
 }
 
-// New creates a new Firebase reference,
-// if client is nil, http.DefaultClient is used.
-func New(url string, client *http.Client) *Firebase {
-
-	if client == nil {
-		var tr *http.Transport
-		tr = &http.Transport{
-			DisableKeepAlives: true, // https://code.google.com/p/go/issues/detail?id=3514
-			Dial: func(network, address string) (net.Conn, error) {
-				start := time.Now()
-				c, err := net.DialTimeout(network, address, TimeoutDuration)
-				tr.ResponseHeaderTimeout = TimeoutDuration - time.Since(start)
-				return c, err
-			},
-		}
-
-		client = &http.Client{
-			Transport:     tr,
-			CheckRedirect: redirectPreserveHeaders,
+// New creates a new Firebase reference configured by opts, by default
+// requests are sent with a client whose transport times out after
+// TimeoutDuration.
+//
+// The url can be a https://<namespace>.firebaseio.com URL, a
+// https://<namespace>.<region>.firebasedatabase.app URL or an emulator URL
+// like http://localhost:9000?ns=<namespace>. New does not validate url,
+// use ValidateURL for that.
+func New(url string, opts ...Option) *Firebase {
+	var o options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
 		}
 	}
+	if o.logger == nil {
+		o.logger = stdLogger{}
+	}
 
-	return &Firebase{
-		url:          sanitizeURL(url),
+	base, ns := resolveURL(url)
+	fb := &Firebase{
+		url:          base,
 		params:       _url.Values{},
-		client:       client,
-		stopWatching: make(chan struct{}),
+		client:       o.httpClient(),
+		logger:       o.logger,
+		tokens:       o.tokens,
+		maxEventSize: o.maxEventSize,
+		life:         newLifecycle(),
+	}
+	if ns != "" {
+		fb.params.Set(nsParam, ns)
+	}
+	if o.auth != "" {
+		fb.params.Set(authParam, o.auth)
+	}
+	return fb
+}
+
+// defaultTransport returns the transport used when no client or transport
+// is configured, timeout defaults to TimeoutDuration. Connecting and then
+// receiving the headers of a response each have timeout to complete.
+func defaultTransport(timeout time.Duration) *http.Transport {
+	if timeout <= 0 {
+		timeout = TimeoutDuration
+	}
+	return &http.Transport{
+		DisableKeepAlives: true, // https://code.google.com/p/go/issues/detail?id=3514
+		Dial: func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, timeout)
+		},
+		ResponseHeaderTimeout: timeout,
 	}
 }
 
@@ -120,12 +175,37 @@ func New(url string, client *http.Client) *Firebase {
 func (fb *Firebase) String() string {
 	path := fb.url + "/.json"
 
+	fb.paramsMtx.RLock()
+	defer fb.paramsMtx.RUnlock()
 	if len(fb.params) > 0 {
 		path += "?" + fb.params.Encode()
 	}
 	return path
 }
 
+// queryParams returns a copy of the query parameters of the reference.
+func (fb *Firebase) queryParams() _url.Values {
+	fb.paramsMtx.RLock()
+	defer fb.paramsMtx.RUnlock()
+	params := make(_url.Values, len(fb.params))
+	for k, v := range fb.params {
+		params[k] = v
+	}
+	return params
+}
+
+// setParam sets the query parameter key of the reference to value, or
+// removes it if value is empty.
+func (fb *Firebase) setParam(key, value string) {
+	fb.paramsMtx.Lock()
+	defer fb.paramsMtx.Unlock()
+	if value != "" {
+		fb.params.Set(key, value)
+	} else {
+		fb.params.Del(key)
+	}
+}
+
 // Child creates a new Firebase reference for the requested
 // child with the same configuration as the parent.
 func (fb *Firebase) Child(child string) *Firebase {
@@ -134,33 +214,177 @@ func (fb *Firebase) Child(child string) *Firebase {
 	return c
 }
 
+// Key returns the last segment of the location of the reference, it is
+// empty for the root of the database.
+func (fb *Firebase) Key() string {
+	segments := fb.segments()
+	if len(segments) == 0 {
+		return ""
+	}
+	return segments[len(segments)-1]
+}
+
+// Path returns the location of the reference relative to the root of the
+// database, e.g. "/users/uid1". The root's path is "/".
+func (fb *Firebase) Path() string {
+	return "/" + strings.Join(fb.segments(), "/")
+}
+
+// Parent returns a reference to the parent location with the same
+// configuration, without query parameters. It returns nil for the root
+// of the database.
+func (fb *Firebase) Parent() *Firebase {
+	segments := fb.segments()
+	if len(segments) == 0 {
+		return nil
+	}
+	return fb.at(segments[:len(segments)-1])
+}
+
+// Root returns a reference to the root of the database with the same
+// configuration, without query parameters.
+func (fb *Firebase) Root() *Firebase {
+	return fb.at(nil)
+}
+
+// at returns a reference to the location given by segments, relative to
+// the root of the database.
+func (fb *Firebase) at(segments []string) *Firebase {
+	c := fb.location()
+	u, err := _url.Parse(fb.url)
+	if err != nil {
+		return c
+	}
+	u.Path = ""
+	if len(segments) > 0 {
+		u.Path = "/" + strings.Join(segments, "/")
+	}
+	c.url = u.String()
+	return c
+}
+
 func (fb *Firebase) copy() *Firebase {
 	c := &Firebase{
 		url:          fb.url,
-		params:       _url.Values{},
+		params:       fb.queryParams(),
 		client:       fb.client,
-		stopWatching: make(chan struct{}),
+		logger:       fb.logger,
+		tokens:       fb.tokens,
+		maxEventSize: fb.maxEventSize,
+		header:       fb.header,
+		life:         fb.life,
+		startKey:     fb.startKey,
+		endKey:       fb.endKey,
+		codecs:       fb.codecs,
+		transforms:   fb.transforms,
+		cache:        fb.cache,
 	}
+	return c
+}
 
-	// making sure to manually copy the map items into a new
-	// map to avoid modifying the map reference.
-	for k, v := range fb.params {
-		c.params[k] = v
+// WithHeader creates a new Firebase reference that sends the header key with
+// value in all of its requests, including the ones of Watch. Headers are
+// inherited by children.
+func (fb *Firebase) WithHeader(key, value string) *Firebase {
+	c := fb.copy()
+	c.header = http.Header{}
+	for k, v := range fb.header {
+		c.header[k] = v
 	}
+	c.header.Set(key, value)
 	return c
 }
 
-func (fb *Firebase) makeRequest(method string, body []byte) (*http.Request, error) {
-	return http.NewRequest(method, fb.String(), bytes.NewReader(body))
+func (fb *Firebase) makeRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	if err := fb.validateQuery(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, fb.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range fb.header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	return req.WithContext(ctx), nil
+}
+
+// response is the outcome of a request that reached Firebase.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// doRequest sends a request configured by opts and returns the body of a
+// successful response.
+func (fb *Firebase) doRequest(ctx context.Context, method string, body io.Reader, opts []RequestOption) ([]byte, error) {
+	fb, r := fb.prepare(opts)
+	resp, err := fb.do(ctx, method, body, r.header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status/200 != 1 {
+		return nil, fb.newError(method, resp.status, resp.body)
+	}
+	if r.etag != nil {
+		*r.etag = resp.header.Get("ETag")
+	}
+	return resp.body, nil
+}
+
+// do sends a request with the given headers to Firebase, an error is only
+// returned if no response was received. If ctx is done before the response
+// is received, its error is returned.
+func (fb *Firebase) do(ctx context.Context, method string, body io.Reader, header http.Header) (*response, error) {
+	resp, err := fb.send(ctx, method, body, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fb.closedErr(ctx, err)
+	}
+	return &response{
+		status: resp.StatusCode,
+		header: resp.Header,
+		body:   respBody,
+	}, nil
+}
+
+// send is like do but leaves reading and closing the body of the response
+// to the caller. The request is canceled if the client is closed.
+func (fb *Firebase) send(ctx context.Context, method string, body io.Reader, header http.Header) (*http.Response, error) {
+	if fb.life.isClosed() {
+		return nil, ErrClosed
+	}
+	rctx, cancel := fb.life.context(ctx)
+	resp, err := fb.roundTrip(rctx, method, body, header)
+	if err != nil {
+		cancel()
+		return nil, fb.closedErr(ctx, err)
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
 }
 
-func (fb *Firebase) doRequest(method string, body []byte) ([]byte, error) {
-	req, err := fb.makeRequest(method, body)
+func (fb *Firebase) roundTrip(ctx context.Context, method string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := fb.makeRequest(ctx, method, body)
 	if err != nil {
 		return nil, err
 	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
 	resp, err := fb.client.Do(req)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	switch err := err.(type) {
 	default:
 		return nil, err
@@ -186,14 +410,5 @@ func (fb *Firebase) doRequest(method string, body []byte) ([]byte, error) {
 
 		return nil, err
 	}
-
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode/200 != 1 {
-		return nil, errors.New(string(respBody))
-	}
-	return respBody, nil
+	return resp, nil
 }
@@ -1,9 +1,12 @@
 package firego
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -37,7 +40,7 @@ func TestNew(t *testing.T) {
 	}
 
 	for _, url := range testURLs {
-		fb := New(url, nil)
+		fb := New(url)
 		assert.Equal(t, URL, fb.url, "givenURL: %s", url)
 	}
 }
@@ -54,7 +57,7 @@ func TestNewWithProvidedHttpClient(t *testing.T) {
 	}
 
 	for _, url := range testURLs {
-		fb := New(url, client)
+		fb := New(url, WithHTTPClient(client))
 		assert.Equal(t, URL, fb.url, "givenURL: %s", url)
 		assert.Equal(t, client, fb.client)
 	}
@@ -63,7 +66,7 @@ func TestNewWithProvidedHttpClient(t *testing.T) {
 func TestChild(t *testing.T) {
 	t.Parallel()
 	var (
-		parent    = New(URL, nil)
+		parent    = New(URL)
 		childNode = "node"
 		child     = parent.Child(childNode)
 	)
@@ -71,9 +74,41 @@ func TestChild(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("%s/%s", parent.url, childNode), child.url)
 }
 
+func TestNavigation(t *testing.T) {
+	t.Parallel()
+	ref := New(URL + "/users/uid1/name").OrderBy("$key")
+	ref.Auth("token")
+
+	assert.Equal(t, "name", ref.Key())
+	assert.Equal(t, "/users/uid1/name", ref.Path())
+
+	parent := ref.Parent()
+	require.NotNil(t, parent)
+	assert.Equal(t, URL+"/users/uid1", parent.url)
+	assert.Equal(t, "uid1", parent.Key())
+	assert.Equal(t, "token", parent.params.Get(authParam))
+	assert.Empty(t, parent.params.Get(orderByParam))
+
+	root := ref.Root()
+	assert.Equal(t, URL, root.url)
+	assert.Equal(t, "", root.Key())
+	assert.Equal(t, "/", root.Path())
+	assert.Nil(t, root.Parent())
+	assert.Equal(t, URL+"/users", root.Child("users").url)
+	assert.Equal(t, root.url, parent.Parent().Parent().url)
+}
+
+func TestNavigationEmulator(t *testing.T) {
+	t.Parallel()
+	ref := New("http://localhost:9000/users?ns=app")
+	assert.Equal(t, "users", ref.Key())
+	assert.Equal(t, "http://localhost:9000", ref.Root().url)
+	assert.Equal(t, "app", ref.Root().params.Get(nsParam))
+}
+
 func TestChild_Issue26(t *testing.T) {
 	t.Parallel()
-	parent := New(URL, nil)
+	parent := New(URL)
 	child1 := parent.Child("one")
 	child2 := child1.Child("two")
 
@@ -81,37 +116,106 @@ func TestChild_Issue26(t *testing.T) {
 	assert.Len(t, child2.params, 0)
 }
 
-func TestTimeoutDuration_Headers(t *testing.T) {
-	defer func(dur time.Duration) { TimeoutDuration = dur }(TimeoutDuration)
-	TimeoutDuration = time.Millisecond
-
+func TestTimeout_Headers(t *testing.T) {
+	t.Parallel()
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		time.Sleep(2 * TimeoutDuration)
+		time.Sleep(20 * time.Millisecond)
 	}))
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL, WithTimeout(time.Millisecond))
 	err := fb.Value("")
 	assert.NotNil(t, err)
 	assert.IsType(t, ErrTimeout{}, err)
 
-	// ResponseHeaderTimeout should be TimeoutDuration less the time it took to dial, and should be positive
 	require.IsType(t, (*http.Transport)(nil), fb.client.Transport)
-	tr := fb.client.Transport.(*http.Transport)
-	assert.True(t, tr.ResponseHeaderTimeout < TimeoutDuration)
-	assert.True(t, tr.ResponseHeaderTimeout > 0)
+	assert.Equal(t, time.Millisecond, fb.client.Transport.(*http.Transport).ResponseHeaderTimeout)
 }
 
-func TestTimeoutDuration_Dial(t *testing.T) {
-	defer func(dur time.Duration) { TimeoutDuration = dur }(TimeoutDuration)
-	TimeoutDuration = time.Microsecond
-
-	fb := New("http://dialtimeouterr.or/", nil)
+func TestTimeout_Dial(t *testing.T) {
+	t.Parallel()
+	fb := New("http://dialtimeouterr.or/", WithTimeout(time.Microsecond))
 	err := fb.Value("")
 	assert.NotNil(t, err)
 	assert.IsType(t, ErrTimeout{}, err)
+}
+
+func TestTimeout_Concurrent(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("null"))
+	}))
+	defer server.Close()
 
-	// ResponseHeaderTimeout should be negative since the total duration was consumed when dialing
+	fast := New(server.URL, WithTimeout(time.Second))
+	slow := New(server.URL, WithTimeout(time.Minute))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		for _, fb := range []*Firebase{fast, slow} {
+			go func(fb *Firebase) {
+				defer wg.Done()
+				var v interface{}
+				assert.NoError(t, fb.Value(&v))
+			}(fb)
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, time.Second, fast.client.Transport.(*http.Transport).ResponseHeaderTimeout)
+	assert.Equal(t, time.Minute, slow.client.Transport.(*http.Transport).ResponseHeaderTimeout)
+}
+
+func TestTimeoutDuration(t *testing.T) {
+	defer func(dur time.Duration) { TimeoutDuration = dur }(TimeoutDuration)
+	TimeoutDuration = time.Minute
+
+	fb := New(URL)
 	require.IsType(t, (*http.Transport)(nil), fb.client.Transport)
-	assert.True(t, fb.client.Transport.(*http.Transport).ResponseHeaderTimeout < 0)
+	assert.Equal(t, time.Minute, fb.client.Transport.(*http.Transport).ResponseHeaderTimeout)
+}
+
+func TestContextCanceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// cancel while the first request is in flight
+			cancel()
+		}
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	fb := New(server.URL)
+	var v interface{}
+	assert.Equal(t, context.Canceled, fb.ValueContext(ctx, &v))
+	assert.Equal(t, context.Canceled, fb.SetContext(ctx, 1))
+	assert.Equal(t, context.Canceled, fb.UpdateContext(ctx, map[string]int{"a": 1}))
+	assert.Equal(t, context.Canceled, fb.RemoveContext(ctx))
+	_, err := fb.PushContext(ctx, 1)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, fb.TransactionContext(ctx, func(v interface{}) (interface{}, error) {
+		return v, nil
+	}))
+}
+
+func TestWithHeader(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(`{"foo":"bar"}`)
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	traced := fb.WithHeader("X-Request-ID", "abc").WithHeader("X-Tenant", "t1")
+	var v interface{}
+	require.NoError(t, traced.Child("a").Value(&v))
+	require.NoError(t, traced.WithHeader("X-Request-ID", "def").Value(&v))
+	require.NoError(t, fb.Value(&v))
+
+	require.Len(t, server.receivedReqs, 3)
+	assert.Equal(t, "abc", server.receivedReqs[0].Header.Get("X-Request-ID"))
+	assert.Equal(t, "t1", server.receivedReqs[0].Header.Get("X-Tenant"))
+	assert.Equal(t, "def", server.receivedReqs[1].Header.Get("X-Request-ID"))
+	assert.Equal(t, "t1", server.receivedReqs[1].Header.Get("X-Tenant"))
+	assert.Empty(t, server.receivedReqs[2].Header.Get("X-Request-ID"))
 }
@@ -0,0 +1,116 @@
+/*
+Package geo implements GeoFire-style location queries on top of firego.
+
+Every location is stored under its key as a geohash ("g") and its
+coordinates ("l"). Proximity searches are answered by issuing startAt/endAt
+range queries over the geohash prefixes that cover the search area and
+filtering the results client-side. Firebase rules should declare an index
+on "g" for the locations reference.
+*/
+package geo
+
+import (
+	"math"
+	"sort"
+
+	"github.com/zabawaba99/firego"
+)
+
+// earthRadius is the mean radius of the earth in kilometers.
+const earthRadius = 6371.0
+
+// Location is the value stored for every key in an Index.
+type Location struct {
+	// Hash is the geohash of the location.
+	Hash string `json:"g"`
+	// Coords are the latitude and longitude of the location.
+	Coords [2]float64 `json:"l"`
+}
+
+// Result is a location that matched a query.
+type Result struct {
+	Key string
+	Lat float64
+	Lng float64
+	// Distance from the center of the query, in kilometers.
+	Distance float64
+}
+
+// Index stores locations under a Firebase reference.
+type Index struct {
+	ref *firego.Firebase
+}
+
+// New creates an Index that stores locations under ref.
+func New(ref *firego.Firebase) *Index {
+	return &Index{ref: ref}
+}
+
+// Set stores the location of key.
+func (i *Index) Set(key string, lat, lng float64) error {
+	return i.ref.Child(key).Set(Location{
+		Hash:   Encode(lat, lng, DefaultPrecision),
+		Coords: [2]float64{lat, lng},
+	})
+}
+
+// Remove deletes the location of key.
+func (i *Index) Remove(key string) error {
+	return i.ref.Child(key).Remove()
+}
+
+// QueryAtLocation returns every location within radius kilometers of the
+// given point, closest first.
+func (i *Index) QueryAtLocation(lat, lng, radius float64) ([]Result, error) {
+	seen := map[string]bool{}
+	var results []Result
+	for _, hash := range coveringHashes(lat, lng, radius) {
+		var locations map[string]Location
+		// quoting the hash keeps all-digit hashes from being sent as numbers
+		query := i.ref.OrderBy("g").StartAt(`"` + hash + `"`).EndAt(hash + "~")
+		if err := query.Value(&locations); err != nil {
+			return nil, err
+		}
+
+		for key, l := range locations {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			d := Distance(lat, lng, l.Coords[0], l.Coords[1])
+			if d > radius {
+				continue
+			}
+			results = append(results, Result{
+				Key:      key,
+				Lat:      l.Coords[0],
+				Lng:      l.Coords[1],
+				Distance: d,
+			})
+		}
+	}
+
+	sort.Sort(byDistance(results))
+	return results, nil
+}
+
+// Distance returns the great-circle distance, in kilometers, between two
+// points.
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLng := radians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+type byDistance []Result
+
+func (r byDistance) Len() int           { return len(r) }
+func (r byDistance) Less(i, j int) bool { return r[i].Distance < r[j].Distance }
+func (r byDistance) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
@@ -0,0 +1,63 @@
+package geo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	idx := New(firego.New(server.URL).Child("locations"))
+	require.NoError(t, idx.Set("home", 57.64911, 10.40744))
+
+	assert.Equal(t, map[string]interface{}{
+		"g": "u4pruydqqv",
+		"l": []interface{}{57.64911, 10.40744},
+	}, server.Get("locations/home"))
+}
+
+func TestQueryAtLocation(t *testing.T) {
+	t.Parallel()
+	locations := map[string]Location{
+		"near": {Hash: Encode(57.650, 10.408, DefaultPrecision), Coords: [2]float64{57.650, 10.408}},
+		"mid":  {Hash: Encode(57.655, 10.410, DefaultPrecision), Coords: [2]float64{57.655, 10.410}},
+		"far":  {Hash: Encode(57.700, 10.500, DefaultPrecision), Coords: [2]float64{57.700, 10.500}},
+	}
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		assert.Equal(t, `"g"`, q.Get("orderBy"))
+		queries = append(queries, q.Get("startAt"))
+		json.NewEncoder(w).Encode(locations)
+	}))
+	defer server.Close()
+
+	idx := New(firego.New(server.URL))
+	results, err := idx.QueryAtLocation(57.64911, 10.40744, 1)
+	require.NoError(t, err)
+	assert.Len(t, queries, 9)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "near", results[0].Key)
+	assert.Equal(t, "mid", results[1].Key)
+	assert.True(t, results[0].Distance < results[1].Distance)
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+	// London to Paris
+	assert.InDelta(t, 343.5, Distance(51.5074, -0.1278, 48.8566, 2.3522), 1)
+	assert.Equal(t, 0.0, Distance(10, 10, 10, 10))
+}
@@ -0,0 +1,97 @@
+package geo
+
+import "math"
+
+// DefaultPrecision is the number of characters of the geohashes stored
+// by an Index.
+const DefaultPrecision = 10
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of the given point with the requested number
+// of characters.
+func Encode(lat, lng float64, precision int) string {
+	var (
+		latRange = [2]float64{-90, 90}
+		lngRange = [2]float64{-180, 180}
+		hash     = make([]byte, 0, precision)
+		even     = true
+		bit      uint
+		ch       int
+	)
+
+	for len(hash) < precision {
+		r, v := &latRange, lat
+		if even {
+			r, v = &lngRange, lng
+		}
+
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+
+		even = !even
+		if bit++; bit == 5 {
+			hash = append(hash, base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// cellSize returns the height and width, in degrees, of a geohash cell
+// with the given number of characters.
+func cellSize(precision int) (lat, lng float64) {
+	bits := uint(precision * 5)
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// coveringHashes returns geohash prefixes whose cells together cover the
+// circle of radius kilometers around the given point.
+func coveringHashes(lat, lng, radius float64) []string {
+	precision := precisionFor(lat, radius)
+	if precision == 0 {
+		// the area is too large for any cell, query everything
+		return []string{""}
+	}
+
+	dLat, dLng := cellSize(precision)
+	seen := map[string]bool{}
+	var hashes []string
+	for _, y := range []float64{-1, 0, 1} {
+		for _, x := range []float64{-1, 0, 1} {
+			pLat := math.Max(-90, math.Min(90, lat+y*dLat))
+			pLng := math.Mod(lng+x*dLng+540, 360) - 180
+			h := Encode(pLat, pLng, precision)
+			if !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+		}
+	}
+	return hashes
+}
+
+// precisionFor returns the longest geohash whose cells are at least
+// radius kilometers in both directions at the given latitude, so that the
+// circle is covered by the cell containing its center and the neighbours
+// of that cell.
+func precisionFor(lat, radius float64) int {
+	kmPerDegree := earthRadius * math.Pi / 180
+	for p := 12; p > 0; p-- {
+		dLat, dLng := cellSize(p)
+		height := dLat * kmPerDegree
+		width := dLng * kmPerDegree * math.Cos(radians(lat))
+		if height >= radius && width >= radius {
+			return p
+		}
+	}
+	return 0
+}
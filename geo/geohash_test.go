@@ -0,0 +1,25 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "u4pruydqqvj", Encode(57.64911, 10.40744, 11))
+	assert.Equal(t, "ezs42", Encode(42.6, -5.6, 5))
+	assert.Equal(t, "s0000", Encode(0, 0, 5))
+}
+
+func TestCoveringHashes(t *testing.T) {
+	t.Parallel()
+	hashes := coveringHashes(57.64911, 10.40744, 1)
+	assert.Len(t, hashes, 9)
+	for _, h := range hashes {
+		assert.Len(t, h, precisionFor(57.64911, 1))
+	}
+
+	assert.Equal(t, []string{""}, coveringHashes(0, 0, 20000))
+}
@@ -0,0 +1,49 @@
+//go:build go1.18
+// +build go1.18
+
+package firego
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Get returns the value of fb decoded as a T, the zero value of T if the
+// location does not exist. Queries are made by calling Get on a query
+// reference or by passing RequestOptions:
+//
+//	users, err := firego.Get[map[string]User](fb, firego.WithOrderBy("age"), firego.WithLimitToFirst(10))
+func Get[T any](fb *Firebase, opts ...RequestOption) (T, error) {
+	return GetContext[T](context.Background(), fb, opts...)
+}
+
+// GetContext is like Get but the request is canceled when ctx is done.
+func GetContext[T any](ctx context.Context, fb *Firebase, opts ...RequestOption) (T, error) {
+	v, _, err := LookupContext[T](ctx, fb, opts...)
+	return v, err
+}
+
+// Lookup is like Get but also reports whether the location exists, so
+// that a missing value can be told apart from a zero one.
+func Lookup[T any](fb *Firebase, opts ...RequestOption) (T, bool, error) {
+	return LookupContext[T](context.Background(), fb, opts...)
+}
+
+// LookupContext is like Lookup but the request is canceled when ctx is
+// done.
+func LookupContext[T any](ctx context.Context, fb *Firebase, opts ...RequestOption) (T, bool, error) {
+	var (
+		v   T
+		raw json.RawMessage
+	)
+	if err := fb.ValueContext(ctx, &raw, opts...); err != nil {
+		return v, false, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return v, false, nil
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return v, true, err
+	}
+	return v, true, nil
+}
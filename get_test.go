@@ -0,0 +1,52 @@
+//go:build go1.18
+// +build go1.18
+
+package firego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	server.Set("users/a", map[string]interface{}{"name": "Ann", "age": 30})
+	fb := New(server.URL)
+
+	u, err := Get[user](fb.Child("users/a"))
+	require.NoError(t, err)
+	assert.Equal(t, user{Name: "Ann", Age: 30}, u)
+
+	users, err := Get[map[string]user](fb.Child("users"), WithOrderBy("$key"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]user{"a": {Name: "Ann", Age: 30}}, users)
+
+	age, ok, err := Lookup[int](fb.Child("users/a/age"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 30, age)
+
+	// missing locations are the zero value
+	age, ok, err = Lookup[int](fb.Child("users/b/age"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, age)
+
+	p, err := Get[*user](fb.Child("users/b"))
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = Get[int](fb.Child("users/a/name"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package firego
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	_url "net/url"
+	"strconv"
+	"strings"
+)
+
+// Index declares a secondary index that maps the value of a record field
+// back to the record's key, e.g. index/usersByEmail/<escapedEmail> -> uid.
+type Index struct {
+	// Path is the location of the index, relative to the root
+	// reference of the Collection.
+	Path string
+	// Field is the record field whose value is indexed. Nested
+	// fields are separated by a "/".
+	Field string
+}
+
+// Collection is a set of records stored under a common path whose
+// declared indexes are kept up to date on every Save and Delete.
+type Collection struct {
+	root    *Firebase
+	path    string
+	indexes []Index
+}
+
+// NewCollection creates a Collection of records stored at path, relative
+// to root, maintaining the given indexes.
+func NewCollection(root *Firebase, path string, indexes ...Index) *Collection {
+	return &Collection{
+		root:    root,
+		path:    strings.Trim(path, "/"),
+		indexes: indexes,
+	}
+}
+
+// Save writes v as the record identified by key. The record and all of
+// its index entries are written in a single multi-path update, and index
+// entries pointing at a previous value of the indexed fields are removed.
+//
+// Reference https://www.firebase.com/docs/web/guide/saving-data.html#section-update
+func (c *Collection) Save(key string, v interface{}) error {
+	old, err := c.record(key)
+	if err != nil {
+		return err
+	}
+
+	val, err := normalize(v)
+	if err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{c.recordPath(key): v}
+	c.fanOut(update, key, old, val)
+	return c.root.Update(update)
+}
+
+// Delete removes the record identified by key along with all of the
+// index entries pointing at it.
+func (c *Collection) Delete(key string) error {
+	old, err := c.record(key)
+	if err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{c.recordPath(key): nil}
+	c.fanOut(update, key, old, nil)
+	return c.root.Update(update)
+}
+
+// LookupKey returns the key of the record whose indexed field equals
+// value, or an empty string if no such record exists.
+func (c *Collection) LookupKey(idx Index, value string) (string, error) {
+	var key string
+	err := c.root.Child(strings.Trim(idx.Path, "/") + "/" + escapePathSegment(EscapeKey(value))).Value(&key)
+	return key, err
+}
+
+// escapePathSegment escapes s for use as a single segment of the path of
+// a URL, which is decoded back to s by Firebase: the escapes of EscapeKey
+// are part of the key.
+func escapePathSegment(s string) string {
+	return (&_url.URL{Path: s}).EscapedPath()
+}
+
+func (c *Collection) recordPath(key string) string {
+	if c.path == "" {
+		return key
+	}
+	return c.path + "/" + key
+}
+
+func (c *Collection) record(key string) (interface{}, error) {
+	var v interface{}
+	if err := c.root.Child(c.recordPath(key)).Value(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// fanOut adds the index entries for the transition of the record
+// identified by key from prev to next into update.
+func (c *Collection) fanOut(update map[string]interface{}, key string, prev, next interface{}) {
+	for _, idx := range c.indexes {
+		path := strings.Trim(idx.Path, "/")
+		oldVal := fieldString(prev, idx.Field)
+		newVal := fieldString(next, idx.Field)
+
+		if oldVal != "" && oldVal != newVal {
+			update[path+"/"+EscapeKey(oldVal)] = nil
+		}
+		if newVal != "" {
+			update[path+"/"+EscapeKey(newVal)] = key
+		}
+	}
+}
+
+// normalize converts v into its generic JSON representation.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n interface{}
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// lookup returns the value found by walking the "/" separated field
+// through a generic JSON value.
+func lookup(v interface{}, field string) interface{} {
+	for _, part := range strings.Split(strings.Trim(field, "/"), "/") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// fieldString returns the string form of a primitive field, or an empty
+// string if the field is missing or not a primitive.
+func fieldString(v interface{}, field string) string {
+	return primitiveString(lookup(v, field))
+}
+
+// primitiveString returns the string form of a primitive value, or an
+// empty string if v is not a primitive.
+func primitiveString(v interface{}) string {
+	switch f := v.(type) {
+	case string:
+		return f
+	case float64:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(f)
+	}
+	return ""
+}
+
+// EscapeKey escapes the characters that Firebase does not allow in keys
+// (".", "$", "#", "[", "]", "/" and ASCII control characters) so that
+// arbitrary values, like email addresses, can be used as keys.
+func EscapeKey(key string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(key); i++ {
+		switch ch := key[i]; {
+		case ch < 0x20, ch == 0x7f, strings.IndexByte(".$#[]/%", ch) >= 0:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// UnescapeKey reverses EscapeKey.
+func UnescapeKey(key string) (string, error) {
+	var b bytes.Buffer
+	for i := 0; i < len(key); i++ {
+		if key[i] != '%' {
+			b.WriteByte(key[i])
+			continue
+		}
+		if i+2 >= len(key) {
+			return "", fmt.Errorf("invalid escape in key %q", key)
+		}
+		ch, err := strconv.ParseUint(key[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in key %q", key)
+		}
+		b.WriteByte(byte(ch))
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,121 @@
+package firego
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego/internal/treeserver"
+)
+
+// newPatchServer responds to GET requests with current and records the
+// body of every PATCH request it receives.
+func newPatchServer(t *testing.T, current string, patches *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "GET":
+			fmt.Fprint(w, current)
+		case "PATCH":
+			var m map[string]interface{}
+			b, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(b, &m))
+			*patches = append(*patches, m)
+			w.Write(b)
+		}
+	}))
+}
+
+func TestCollectionSave(t *testing.T) {
+	t.Parallel()
+	var patches []map[string]interface{}
+	server := newPatchServer(t, `{"email":"old@example.com","name":"bob"}`, &patches)
+	defer server.Close()
+
+	users := NewCollection(New(server.URL), "users", Index{Path: "index/usersByEmail", Field: "email"})
+	err := users.Save("uid1", map[string]string{"email": "new@example.com", "name": "bob"})
+	require.NoError(t, err)
+
+	require.Len(t, patches, 1)
+	assert.Equal(t, map[string]interface{}{
+		"users/uid1":                           map[string]interface{}{"email": "new@example.com", "name": "bob"},
+		"index/usersByEmail/old@example%2Ecom": nil,
+		"index/usersByEmail/new@example%2Ecom": "uid1",
+	}, patches[0])
+}
+
+func TestCollectionSaveUnchangedIndex(t *testing.T) {
+	t.Parallel()
+	var patches []map[string]interface{}
+	server := newPatchServer(t, `{"age":30}`, &patches)
+	defer server.Close()
+
+	users := NewCollection(New(server.URL), "users", Index{Path: "index/byAge", Field: "age"})
+	require.NoError(t, users.Save("uid1", map[string]int{"age": 30}))
+
+	require.Len(t, patches, 1)
+	assert.Equal(t, map[string]interface{}{
+		"users/uid1":     map[string]interface{}{"age": float64(30)},
+		"index/byAge/30": "uid1",
+	}, patches[0])
+}
+
+func TestCollectionDelete(t *testing.T) {
+	t.Parallel()
+	var patches []map[string]interface{}
+	server := newPatchServer(t, `{"profile":{"email":"a@b.c"}}`, &patches)
+	defer server.Close()
+
+	users := NewCollection(New(server.URL), "/users/", Index{Path: "index/byEmail", Field: "profile/email"})
+	require.NoError(t, users.Delete("uid1"))
+
+	require.Len(t, patches, 1)
+	assert.Equal(t, map[string]interface{}{
+		"users/uid1":            nil,
+		"index/byEmail/a@b%2Ec": nil,
+	}, patches[0])
+}
+
+func TestEscapeKey(t *testing.T) {
+	t.Parallel()
+	for _, key := range []string{"plain", "a.b$c#d[e]f/g", "100%", "tab\tnewline\n"} {
+		escaped := EscapeKey(key)
+		assert.NotContains(t, escaped, ".")
+		assert.NotContains(t, escaped, "/")
+
+		unescaped, err := UnescapeKey(escaped)
+		require.NoError(t, err)
+		assert.Equal(t, key, unescaped)
+	}
+
+	_, err := UnescapeKey("bad%4")
+	assert.Error(t, err)
+}
+
+func TestCollectionLookupKey(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+
+	byEmail := Index{Path: "index/byEmail", Field: "email"}
+	users := NewCollection(New(server.URL), "users", byEmail)
+	require.NoError(t, users.Save("uid1", map[string]string{"email": "a@b.c"}))
+	require.NoError(t, users.Save("uid2", map[string]string{"email": "100%/sure?#"}))
+	assert.Equal(t, "uid1", server.Get("index/byEmail/a@b%2Ec"))
+
+	key, err := users.LookupKey(byEmail, "a@b.c")
+	require.NoError(t, err)
+	assert.Equal(t, "uid1", key)
+	key, err = users.LookupKey(byEmail, "100%/sure?#")
+	require.NoError(t, err)
+	assert.Equal(t, "uid2", key)
+
+	key, err = users.LookupKey(byEmail, "nobody@b.c")
+	require.NoError(t, err)
+	assert.Empty(t, key)
+}
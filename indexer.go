@@ -0,0 +1,332 @@
+package firego
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// indexerPageSize is the number of children fetched per request when
+	// scanning records and indexes.
+	indexerPageSize = 1000
+	// indexerBatchSize is the number of index entries written per
+	// request when repairing indexes.
+	indexerBatchSize = 500
+)
+
+// Drift is an index entry that does not match the records of a
+// Collection.
+type Drift struct {
+	// Index holding the entry.
+	Index Index
+	// Value is the indexed value the entry is for.
+	Value string
+	// Expected is the key of a record with the value, or empty if no
+	// record has it and the entry should not exist.
+	Expected string
+	// Actual is the key the entry points to, or empty if the entry is
+	// missing.
+	Actual string
+}
+
+// Indexer keeps the indexes of a Collection consistent with its records,
+// including records written by clients that do not use Collection.Save.
+type Indexer struct {
+	// RetryInterval between attempts to re-establish the watch, it
+	// defaults to 5 seconds.
+	RetryInterval time.Duration
+	// OnError, if set, is called with the errors encountered in the
+	// background.
+	OnError func(error)
+
+	c       *Collection
+	watched *Firebase
+
+	mtx sync.Mutex
+	// indexed holds the value of every indexed field of every record,
+	// as last seen
+	indexed map[string][]string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewIndexer creates an Indexer for the indexes of c.
+func NewIndexer(c *Collection) *Indexer {
+	return &Indexer{c: c, indexed: map[string][]string{}}
+}
+
+// Check scans the records and indexes page by page and reports the index
+// entries that are missing, stale or point at the wrong record.
+func (ix *Indexer) Check(ctx context.Context) ([]Drift, error) {
+	// expected maps every index to its values and the records having them
+	expected := make([]map[string][]string, len(ix.c.indexes))
+	for i := range expected {
+		expected[i] = map[string][]string{}
+	}
+	indexed := map[string][]string{}
+	err := ix.c.records().forEachChild(ctx, indexerPageSize, func(key string, v interface{}) error {
+		values := make([]string, len(ix.c.indexes))
+		for i, idx := range ix.c.indexes {
+			if values[i] = fieldString(v, idx.Field); values[i] != "" {
+				expected[i][values[i]] = append(expected[i][values[i]], key)
+			}
+		}
+		indexed[key] = values
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []Drift
+	for i, idx := range ix.c.indexes {
+		seen := map[string]bool{}
+		err := ix.c.root.Child(strings.Trim(idx.Path, "/")).forEachChild(ctx, indexerPageSize, func(escaped string, v interface{}) error {
+			value, err := UnescapeKey(escaped)
+			if err != nil {
+				value = escaped
+			}
+			seen[value] = true
+
+			actual, _ := v.(string)
+			keys := expected[i][value]
+			if !contains(keys, actual) {
+				d := Drift{Index: idx, Value: value, Actual: actual}
+				if len(keys) > 0 {
+					d.Expected = keys[len(keys)-1]
+				}
+				drift = append(drift, d)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range sortedValues(expected[i]) {
+			if !seen[value] {
+				keys := expected[i][value]
+				drift = append(drift, Drift{Index: idx, Value: value, Expected: keys[len(keys)-1]})
+			}
+		}
+	}
+
+	ix.mtx.Lock()
+	ix.indexed = indexed
+	ix.mtx.Unlock()
+	return drift, nil
+}
+
+// Repair runs Check and fixes the drift it found, which also builds
+// indexes from scratch. It returns the drift that was fixed.
+func (ix *Indexer) Repair(ctx context.Context) ([]Drift, error) {
+	drift, err := ix.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := map[string]interface{}{}
+	for _, d := range drift {
+		path := strings.Trim(d.Index.Path, "/") + "/" + EscapeKey(d.Value)
+		if d.Expected != "" {
+			update[path] = d.Expected
+		} else {
+			update[path] = nil
+		}
+		if len(update) == indexerBatchSize {
+			if err := ix.c.root.Update(update); err != nil {
+				return nil, err
+			}
+			update = map[string]interface{}{}
+		}
+	}
+	if len(update) > 0 {
+		if err := ix.c.root.Update(update); err != nil {
+			return nil, err
+		}
+	}
+	return drift, nil
+}
+
+// Start repairs the indexes and then keeps them up to date by watching
+// the records in the background.
+func (ix *Indexer) Start(ctx context.Context) error {
+	if _, err := ix.Repair(ctx); err != nil {
+		return err
+	}
+	ix.watched = ix.c.records()
+	ix.stop = make(chan struct{})
+	ix.done = make(chan struct{})
+	go ix.run(ix.watched)
+	return nil
+}
+
+// Stop tears down the watch.
+func (ix *Indexer) Stop() {
+	close(ix.stop)
+	ix.watched.StopWatching()
+	<-ix.done
+}
+
+func (ix *Indexer) run(records *Firebase) {
+	defer close(ix.done)
+
+	interval := ix.RetryInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		notifications := make(chan Event)
+		if err := records.Watch(notifications); err != nil {
+			ix.report(err)
+		} else {
+			select {
+			case <-ix.stop:
+				// Stop may have run before the watch was established
+				records.StopWatching()
+			default:
+			}
+			for event := range notifications {
+				ix.apply(event)
+			}
+		}
+
+		select {
+		case <-ix.stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (ix *Indexer) apply(event Event) {
+	path := splitPath(event.Path)
+	switch event.Type {
+	case EventTypePut:
+		ix.report(ix.write(path, event.Data))
+	case EventTypePatch:
+		data, _ := event.Data.(map[string]interface{})
+		for k, v := range data {
+			ix.report(ix.write(append(path[:len(path):len(path)], splitPath(k)...), v))
+		}
+	case EventTypeError:
+		ix.report(event.Data.(error))
+	}
+}
+
+// write updates the indexes for a remote write of v at path, relative to
+// the records.
+func (ix *Indexer) write(path []string, v interface{}) error {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+
+	update := map[string]interface{}{}
+	if len(path) == 0 {
+		// a snapshot of every record
+		records, _ := v.(map[string]interface{})
+		for key := range ix.indexed {
+			if _, ok := records[key]; !ok {
+				ix.reindex(update, key, nil, nil)
+			}
+		}
+		for key, record := range records {
+			ix.reindex(update, key, nil, record)
+		}
+	} else {
+		ix.reindex(update, path[0], path[1:], v)
+	}
+
+	if len(update) == 0 {
+		return nil
+	}
+	return ix.c.root.Update(update)
+}
+
+// reindex adds the index entries for a write of v at path, relative to
+// the record identified by key, into update.
+func (ix *Indexer) reindex(update map[string]interface{}, key string, path []string, v interface{}) {
+	prev := ix.indexed[key]
+	next := make([]string, len(ix.c.indexes))
+	changed := false
+	for i, idx := range ix.c.indexes {
+		if prev != nil {
+			next[i] = prev[i]
+		}
+		value, affected := fieldAfterWrite(splitPath(idx.Field), path, v)
+		if !affected || value == next[i] {
+			continue
+		}
+
+		indexPath := strings.Trim(idx.Path, "/")
+		if next[i] != "" {
+			update[indexPath+"/"+EscapeKey(next[i])] = nil
+		}
+		if value != "" {
+			update[indexPath+"/"+EscapeKey(value)] = key
+		}
+		next[i] = value
+		changed = true
+	}
+
+	switch {
+	case v == nil && len(path) == 0:
+		delete(ix.indexed, key)
+	case changed || prev == nil:
+		ix.indexed[key] = next
+	}
+}
+
+// fieldAfterWrite returns the value of field after a write of v at path,
+// both relative to a record, and whether the write affected the field.
+func fieldAfterWrite(field, path []string, v interface{}) (string, bool) {
+	for i, seg := range path {
+		if i == len(field) {
+			// written below the field, which is no longer a primitive
+			// unless the write removed something that did not exist
+			return "", v != nil
+		}
+		if field[i] != seg {
+			return "", false
+		}
+	}
+	rest := field[len(path):]
+	if len(rest) == 0 {
+		return primitiveString(v), true
+	}
+	return fieldString(v, strings.Join(rest, "/")), true
+}
+
+func (ix *Indexer) report(err error) {
+	if err != nil && ix.OnError != nil {
+		ix.OnError(err)
+	}
+}
+
+// records returns the reference holding the records of the Collection.
+func (c *Collection) records() *Firebase {
+	if c.path == "" {
+		return c.root.location()
+	}
+	return c.root.Child(c.path)
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedValues(m map[string][]string) []string {
+	values := make(map[string]interface{}, len(m))
+	for v := range m {
+		values[v] = nil
+	}
+	return sortedKeys(values)
+}
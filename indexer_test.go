@@ -0,0 +1,114 @@
+package firego
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+var byEmail = Index{Path: "index/byEmail", Field: "email"}
+
+func TestIndexerCheckRepair(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users", map[string]interface{}{
+		"u1": map[string]interface{}{"email": "a@example.com"},
+		"u2": map[string]interface{}{"email": "b@example.com"},
+		"u3": map[string]interface{}{"email": "c@example.com"},
+		"u4": map[string]interface{}{"name": "no email"},
+	})
+	server.Set("index/byEmail", map[string]interface{}{
+		"a@example%2Ecom": "u1",
+		"b@example%2Ecom": "u9",
+		"z@example%2Ecom": "u1",
+	})
+
+	ix := NewIndexer(NewCollection(New(server.URL), "users", byEmail))
+	drift, err := ix.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Drift{
+		{Index: byEmail, Value: "b@example.com", Expected: "u2", Actual: "u9"},
+		{Index: byEmail, Value: "z@example.com", Actual: "u1"},
+		{Index: byEmail, Value: "c@example.com", Expected: "u3"},
+	}, drift)
+
+	fixed, err := ix.Repair(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, drift, fixed)
+	assert.Equal(t, map[string]interface{}{
+		"a@example%2Ecom": "u1",
+		"b@example%2Ecom": "u2",
+		"c@example%2Ecom": "u3",
+	}, server.Get("index/byEmail"))
+
+	drift, err = ix.Check(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+}
+
+func TestIndexerStart(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/u1", map[string]interface{}{"email": "a@example.com"})
+
+	ix := NewIndexer(NewCollection(New(server.URL), "users", byEmail))
+	ix.RetryInterval = 50 * time.Millisecond
+	require.NoError(t, ix.Start(context.Background()))
+	defer ix.Stop()
+	assert.Equal(t, "u1", server.Get("index/byEmail/a@example%2Ecom"))
+
+	index := func() interface{} { return server.Get("index/byEmail") }
+	eventuallyEqual := func(expected interface{}) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !assert.ObjectsAreEqual(expected, index()) {
+			if time.Now().After(deadline) {
+				require.Equal(t, expected, index())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	server.Set("users/u2", map[string]interface{}{"email": "b@example.com"})
+	eventuallyEqual(map[string]interface{}{"a@example%2Ecom": "u1", "b@example%2Ecom": "u2"})
+
+	server.Set("users/u2/email", "c@example.com")
+	eventuallyEqual(map[string]interface{}{"a@example%2Ecom": "u1", "c@example%2Ecom": "u2"})
+
+	server.Delete("users/u1")
+	eventuallyEqual(map[string]interface{}{"c@example%2Ecom": "u2"})
+}
+
+func TestFieldAfterWrite(t *testing.T) {
+	t.Parallel()
+	field := []string{"address", "city"}
+	for _, tt := range []struct {
+		path     []string
+		v        interface{}
+		value    string
+		affected bool
+	}{
+		{nil, map[string]interface{}{"address": map[string]interface{}{"city": "NYC"}}, "NYC", true},
+		{nil, nil, "", true},
+		{[]string{"address"}, map[string]interface{}{"city": "LA"}, "LA", true},
+		{[]string{"address", "city"}, "SF", "SF", true},
+		{[]string{"address", "city"}, 7.0, "7", true},
+		{[]string{"address", "city", "x"}, "y", "", true},
+		{[]string{"address", "city", "x"}, nil, "", false},
+		{[]string{"address", "zip"}, "10001", "", false},
+		{[]string{"name"}, "bob", "", false},
+	} {
+		value, affected := fieldAfterWrite(field, tt.path, tt.v)
+		assert.Equal(t, tt.value, value, "%v", tt.path)
+		assert.Equal(t, tt.affected, affected, "%v", tt.path)
+	}
+}
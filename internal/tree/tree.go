@@ -0,0 +1,49 @@
+// Package tree provides helpers for the values of Firebase locations
+// decoded into an interface{}, shared by the packages of firego that keep
+// a copy of a location up to date.
+package tree
+
+import "strings"
+
+// Split returns the segments of a slash separated path, leading, trailing
+// and repeated slashes are ignored.
+func Split(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// Get returns the value at path below v, nil if there is none.
+func Get(v interface{}, path []string) interface{} {
+	for _, p := range path {
+		m, _ := v.(map[string]interface{})
+		v = m[p]
+	}
+	return v
+}
+
+// Set returns v with the value at path replaced by child. The objects
+// left empty are removed like Firebase does, so it returns nil if nothing
+// is left of v.
+func Set(v interface{}, path []string, child interface{}) interface{} {
+	if len(path) == 0 {
+		return child
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+	}
+	if c := Set(m[path[0]], path[1:], child); c != nil {
+		m[path[0]] = c
+	} else {
+		delete(m, path[0])
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,28 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, Split("/"))
+	assert.Equal(t, []string{"a", "b"}, Split("//a/b/"))
+}
+
+func TestGetSet(t *testing.T) {
+	t.Parallel()
+	var v interface{}
+	v = Set(v, Split("a/b"), 1.0)
+	v = Set(v, Split("a/c"), "x")
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": "x"}}, v)
+	assert.Equal(t, "x", Get(v, Split("a/c")))
+	assert.Nil(t, Get(v, Split("a/c/d")))
+
+	v = Set(v, Split("a/b"), nil)
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"c": "x"}}, v)
+	assert.Nil(t, Set(v, Split("a/c"), nil), "empty objects are removed")
+	assert.Equal(t, 2.0, Set(v, nil, 2.0))
+}
@@ -0,0 +1,104 @@
+// Package treeserver provides an in-memory Firebase database for tests that
+// rely on conditional requests, which firetest does not support.
+package treeserver
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/zabawaba99/firego/internal/tree"
+)
+
+// Server is an in-memory database served over the REST API. Every response
+// carries the ETag of the value of its location, PUT requests honor
+// if-match, POST requests name the children they add in order and server
+// timestamps are set to 1.
+type Server struct {
+	*httptest.Server
+
+	mtx   sync.Mutex
+	root  interface{}
+	count int
+}
+
+// New starts a Server whose database holds the JSON value data.
+func New(data string) *Server {
+	s := &Server{}
+	json.Unmarshal([]byte(data), &s.root)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Get returns the value at path.
+func (s *Server) Get(path string) interface{} {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return tree.Get(s.root, tree.Split(path))
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	path := tree.Split(strings.TrimSuffix(req.URL.Path, ".json"))
+	b, _ := json.Marshal(tree.Get(s.root, path))
+	sum := sha1.Sum(b)
+	etag := hex.EncodeToString(sum[:])
+	w.Header().Set("ETag", etag)
+
+	var body interface{}
+	raw, _ := ioutil.ReadAll(req.Body)
+	json.Unmarshal(raw, &body)
+
+	switch req.Method {
+	case "PUT":
+		if m := req.Header.Get("if-match"); m != "" && m != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write(b)
+			return
+		}
+		s.set(path, body)
+		w.Write(raw)
+	case "POST":
+		s.count++
+		name := fmt.Sprintf("-push%03d", s.count)
+		s.set(append(path, name), body)
+		fmt.Fprintf(w, `{"name":%q}`, name)
+	case "PATCH":
+		for k, v := range body.(map[string]interface{}) {
+			s.set(append(path[:len(path):len(path)], tree.Split(k)...), v)
+		}
+		w.Write(raw)
+	case "DELETE":
+		s.set(path, nil)
+		w.Write([]byte("null"))
+	default:
+		w.Write(b)
+	}
+}
+
+func (s *Server) set(path []string, v interface{}) {
+	s.root = tree.Set(s.root, path, timestamps(v))
+}
+
+// timestamps replaces the server timestamp placeholders in v by 1.
+func timestamps(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if m[".sv"] == "timestamp" {
+		return float64(1)
+	}
+	for k, child := range m {
+		m[k] = timestamps(child)
+	}
+	return m
+}
@@ -0,0 +1,55 @@
+package treeserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, s *Server, method, path, body string, header http.Header) (*http.Response, string) {
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(b)
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	s := New(`{"a":{"b":1}}`)
+	defer s.Close()
+
+	resp, body := do(t, s, "GET", "/a.json", "", nil)
+	assert.Equal(t, `{"b":1}`, body)
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	resp, _ = do(t, s, "PUT", "/a.json", `{"b":2}`, http.Header{"If-Match": {"stale"}})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	assert.Equal(t, 1.0, s.Get("a/b"))
+
+	resp, _ = do(t, s, "PUT", "/a.json", `{"b":2,"at":{".sv":"timestamp"}}`, http.Header{"If-Match": {etag}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"b": 2.0, "at": 1.0}, s.Get("a"))
+
+	_, body = do(t, s, "POST", "/list.json", `"x"`, nil)
+	assert.Equal(t, `{"name":"-push001"}`, body)
+	assert.Equal(t, "x", s.Get("/list/-push001/"))
+
+	do(t, s, "PATCH", "/.json", `{"a/b":null,"a/at":null,"c/d":true}`, nil)
+	assert.Nil(t, s.Get("a"), "empty nodes are removed")
+	assert.Equal(t, true, s.Get("c/d"))
+
+	do(t, s, "DELETE", "/c.json", "", nil)
+	assert.Nil(t, s.Get("c"))
+}
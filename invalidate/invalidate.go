@@ -0,0 +1,233 @@
+// Package invalidate calls user registered callbacks when the data at
+// locations matching a path pattern changes, so that services caching
+// Firebase data can evict stale entries.
+//
+// Patterns are relative to the watched reference and a "*" segment matches
+// any key, e.g. "users/*/profile". Callbacks receive the concrete location
+// that changed, e.g. "users/uid1/profile".
+package invalidate
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firego/internal/tree"
+)
+
+// DefaultRetryInterval is the default time between attempts to
+// re-establish the watch.
+const DefaultRetryInterval = 5 * time.Second
+
+// Func is called with the location, matching a registered pattern, whose
+// data changed.
+type Func func(path string)
+
+type handler struct {
+	pattern []string
+	fn      Func
+	// known holds the matching locations that exist, so that their
+	// removal by a write to a parent location is noticed
+	known map[string]bool
+}
+
+// Invalidator watches a reference and calls the handlers of the patterns
+// matching the locations that changed. The Invalidator owns the watch on
+// the reference.
+type Invalidator struct {
+	// RetryInterval between attempts to re-establish the watch, it
+	// defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+	// OnError, if set, is called with the errors encountered in the
+	// background.
+	OnError func(error)
+
+	ref *firego.Firebase
+
+	mtx      sync.Mutex
+	handlers []*handler
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates an Invalidator for ref.
+func New(ref *firego.Firebase) *Invalidator {
+	return &Invalidator{ref: ref}
+}
+
+// Handle registers fn for the locations matching pattern. Every location
+// is reported once when the watch is established, since changes may have
+// been missed while it was down.
+func (inv *Invalidator) Handle(pattern string, fn Func) {
+	inv.mtx.Lock()
+	defer inv.mtx.Unlock()
+	inv.handlers = append(inv.handlers, &handler{
+		pattern: tree.Split(pattern),
+		fn:      fn,
+		known:   map[string]bool{},
+	})
+}
+
+// Start starts watching the reference in the background.
+func (inv *Invalidator) Start() {
+	inv.stop = make(chan struct{})
+	inv.done = make(chan struct{})
+	go inv.run()
+}
+
+// Stop tears down the watch.
+func (inv *Invalidator) Stop() {
+	close(inv.stop)
+	inv.ref.StopWatching()
+	<-inv.done
+}
+
+func (inv *Invalidator) run() {
+	defer close(inv.done)
+
+	interval := inv.RetryInterval
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+
+	for {
+		notifications := make(chan firego.Event)
+		if err := inv.ref.Watch(notifications); err != nil {
+			inv.report(err)
+		} else {
+			select {
+			case <-inv.stop:
+				// Stop may have run before the watch was established
+				inv.ref.StopWatching()
+			default:
+			}
+			for event := range notifications {
+				inv.apply(event)
+			}
+		}
+
+		select {
+		case <-inv.stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (inv *Invalidator) apply(event firego.Event) {
+	path := tree.Split(event.Path)
+	switch event.Type {
+	case firego.EventTypePut:
+		inv.write(path, event.Data)
+	case firego.EventTypePatch:
+		data, _ := event.Data.(map[string]interface{})
+		for k, v := range data {
+			inv.write(append(path[:len(path):len(path)], tree.Split(k)...), v)
+		}
+	case firego.EventTypeError:
+		inv.report(event.Data.(error))
+	}
+}
+
+// write calls the handlers affected by a write of v at path.
+func (inv *Invalidator) write(path []string, v interface{}) {
+	inv.mtx.Lock()
+	var calls []func()
+	for _, h := range inv.handlers {
+		for _, p := range h.changed(path, v) {
+			fn, p := h.fn, p
+			calls = append(calls, func() { fn(p) })
+		}
+	}
+	inv.mtx.Unlock()
+
+	// handlers run without the lock so they can register more handlers
+	for _, call := range calls {
+		call()
+	}
+}
+
+// changed returns the locations matching the handler's pattern that are
+// affected by a write of v at path, in order.
+func (h *handler) changed(path []string, v interface{}) []string {
+	n := len(path)
+	if n > len(h.pattern) {
+		n = len(h.pattern)
+	}
+	if !match(h.pattern[:n], path[:n]) {
+		return nil
+	}
+
+	if len(path) >= len(h.pattern) {
+		// written at or below a matching location
+		loc := strings.Join(path[:len(h.pattern)], "/")
+		if len(path) == len(h.pattern) && v == nil {
+			delete(h.known, loc)
+		} else {
+			h.known[loc] = true
+		}
+		return []string{loc}
+	}
+
+	// written above the matching locations, which are all replaced
+	affected := map[string]bool{}
+	prefix := strings.Join(path, "/")
+	for loc := range h.known {
+		if prefix == "" || strings.HasPrefix(loc, prefix+"/") {
+			affected[loc] = true
+			delete(h.known, loc)
+		}
+	}
+	for _, loc := range expand(path, h.pattern[len(path):], v) {
+		affected[loc] = true
+		h.known[loc] = true
+	}
+
+	locs := make([]string, 0, len(affected))
+	for loc := range affected {
+		locs = append(locs, loc)
+	}
+	sort.Strings(locs)
+	return locs
+}
+
+// expand returns the locations matching the rest of a pattern that exist
+// in v, the value at prefix.
+func expand(prefix, rest []string, v interface{}) []string {
+	if v == nil {
+		return nil
+	}
+	if len(rest) == 0 {
+		return []string{strings.Join(prefix, "/")}
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var locs []string
+	for k, child := range m {
+		if rest[0] == "*" || rest[0] == k {
+			locs = append(locs, expand(append(prefix[:len(prefix):len(prefix)], k), rest[1:], child)...)
+		}
+	}
+	return locs
+}
+
+func match(pattern, path []string) bool {
+	for i, seg := range pattern {
+		if seg != "*" && seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (inv *Invalidator) report(err error) {
+	if err != nil && inv.OnError != nil {
+		inv.OnError(err)
+	}
+}
@@ -0,0 +1,110 @@
+package invalidate
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firetest"
+)
+
+type recorder struct {
+	mtx   sync.Mutex
+	paths []string
+}
+
+func (r *recorder) record(path string) {
+	r.mtx.Lock()
+	r.paths = append(r.paths, path)
+	r.mtx.Unlock()
+}
+
+// take returns the recorded paths, sorted, and forgets them.
+func (r *recorder) take() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	paths := r.paths
+	r.paths = nil
+	sort.Strings(paths)
+	return paths
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	inv := New(nil)
+	var profiles, all recorder
+	inv.Handle("users/*/profile", profiles.record)
+	inv.Handle("/users/", all.record)
+
+	inv.apply(firego.Event{Type: "put", Path: "/", Data: map[string]interface{}{
+		"users": map[string]interface{}{
+			"u1": map[string]interface{}{"profile": map[string]interface{}{"name": "A"}},
+			"u2": map[string]interface{}{"profile": map[string]interface{}{"name": "B"}},
+			"u3": map[string]interface{}{"settings": true},
+		},
+	}})
+	assert.Equal(t, []string{"users/u1/profile", "users/u2/profile"}, profiles.take())
+	assert.Equal(t, []string{"users"}, all.take())
+
+	// below a matching location
+	inv.apply(firego.Event{Type: "put", Path: "/users/u1/profile/name", Data: "AA"})
+	assert.Equal(t, []string{"users/u1/profile"}, profiles.take())
+
+	// unrelated locations
+	inv.apply(firego.Event{Type: "put", Path: "/users/u3/settings", Data: false})
+	assert.Empty(t, profiles.take())
+	inv.apply(firego.Event{Type: "put", Path: "/posts/p1", Data: "text"})
+	assert.Empty(t, profiles.take())
+	assert.Equal(t, []string{"users", "users"}, all.take())
+
+	// above matching locations, removing u2's profile
+	inv.apply(firego.Event{Type: "put", Path: "/users/u2", Data: map[string]interface{}{"settings": true}})
+	assert.Equal(t, []string{"users/u2/profile"}, profiles.take())
+	inv.apply(firego.Event{Type: "put", Path: "/users/u2", Data: nil})
+	assert.Empty(t, profiles.take())
+
+	// multi-path updates
+	inv.apply(firego.Event{Type: "patch", Path: "/users", Data: map[string]interface{}{
+		"u4/profile": map[string]interface{}{"name": "D"},
+		"u1":         nil,
+	}})
+	assert.Equal(t, []string{"users/u1/profile", "users/u4/profile"}, profiles.take())
+}
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/u1/profile", map[string]interface{}{"name": "A"})
+
+	var profiles recorder
+	inv := New(firego.New(server.URL))
+	inv.RetryInterval = 50 * time.Millisecond
+	inv.Handle("users/*/profile", profiles.record)
+	inv.Start()
+	defer inv.Stop()
+
+	expect := func(expected ...string) {
+		var got []string
+		deadline := time.Now().Add(2 * time.Second)
+		for len(got) < len(expected) && time.Now().Before(deadline) {
+			got = append(got, profiles.take()...)
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.Equal(t, expected, got)
+	}
+
+	expect("users/u1/profile")
+
+	server.Set("users/u1/profile/name", "B")
+	expect("users/u1/profile")
+
+	server.Delete("users/u1")
+	expect("users/u1/profile")
+}
@@ -0,0 +1,159 @@
+package firego
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// pushChars are the characters of push IDs, in ascending order.
+//
+// Reference https://www.firebase.com/blog/2015-02-11-firebase-unique-identifiers.html
+const pushChars = "-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+// ErrNoKeyBetween is returned when no key can be generated between two
+// adjacent keys.
+var ErrNoKeyBetween = errors.New("no key exists between the given keys")
+
+// List is an ordered list stored as the children of a Firebase reference,
+// ordered by their keys. Items appended to the list get push IDs, items
+// inserted between two others get a key that sorts between theirs.
+type List struct {
+	ref *Firebase
+}
+
+// ListItem is an item of a List.
+type ListItem struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// NewList creates a List stored at ref.
+func NewList(ref *Firebase) *List {
+	return &List{ref: ref}
+}
+
+// Append adds v to the end of the list and returns its key.
+func (l *List) Append(v interface{}) (string, error) {
+	ref, err := l.ref.Push(v)
+	if err != nil {
+		return "", err
+	}
+	return ref.url[strings.LastIndex(ref.url, "/")+1:], nil
+}
+
+// InsertBetween adds v between the items with keys before and after and
+// returns its key. An empty before inserts at the start of the list and
+// an empty after inserts at its end.
+func (l *List) InsertBetween(before, after string, v interface{}) (string, error) {
+	key, err := KeyBetween(before, after)
+	if err != nil {
+		return "", err
+	}
+	return key, l.ref.Child(key).Set(v)
+}
+
+// Move moves the item with the given key between the items with keys
+// before and after, in a single update, and returns its new key.
+func (l *List) Move(key, before, after string) (string, error) {
+	newKey, err := KeyBetween(before, after)
+	if err != nil {
+		return "", err
+	}
+
+	// a pointer since json.RawMessage only marshals through a pointer
+	// before Go 1.8
+	v := new(json.RawMessage)
+	if err := l.ref.Child(key).Value(v); err != nil {
+		return "", err
+	}
+	return newKey, l.ref.Update(map[string]interface{}{
+		newKey: v,
+		key:    nil,
+	})
+}
+
+// Items returns the items of the list in order.
+func (l *List) Items() ([]ListItem, error) {
+	var m map[string]json.RawMessage
+	if err := l.ref.Value(&m); err != nil {
+		return nil, err
+	}
+
+	generic := make(map[string]interface{}, len(m))
+	for k := range m {
+		generic[k] = nil
+	}
+	items := make([]ListItem, 0, len(m))
+	for _, k := range sortedKeys(generic) {
+		items = append(items, ListItem{Key: k, Value: m[k]})
+	}
+	return items, nil
+}
+
+// KeyBetween returns a key that sorts after a and before b, using the
+// characters of push IDs. An empty a means the start of the list and an
+// empty b means its end. Generated keys never look like integers, which
+// Firebase would sort ahead of all other keys.
+func KeyBetween(a, b string) (string, error) {
+	if b != "" && a >= b {
+		return "", ErrNoKeyBetween
+	}
+
+	key := midpoint(a, b)
+	if _, err := strconv.ParseInt(key, 10, 32); err == nil {
+		key += midpoint("", "")
+	}
+	if key <= a || (b != "" && key >= b) {
+		return "", ErrNoKeyBetween
+	}
+	return key, nil
+}
+
+// midpoint returns a string between a and b, an empty b is treated as
+// the end of the key space.
+func midpoint(a, b string) string {
+	if b != "" {
+		// skip the common prefix, treating missing characters of a
+		// as the lowest character
+		n := 0
+		for n < len(b) && charAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(suffix(a, n), b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(pushChars, a[0])
+	}
+	digitB := len(pushChars)
+	if b != "" {
+		digitB = strings.IndexByte(pushChars, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(pushChars[(digitA+digitB+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(pushChars[digitA]) + midpoint(suffix(a, 1), "")
+}
+
+func charAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return pushChars[0]
+}
+
+func suffix(s string, i int) string {
+	if i < len(s) {
+		return s[i:]
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package firego
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func listValues(t *testing.T, l *List) []string {
+	items, err := l.Items()
+	require.NoError(t, err)
+
+	var values []string
+	for _, item := range items {
+		var s string
+		require.NoError(t, json.Unmarshal(item.Value, &s))
+		values = append(values, s)
+	}
+	return values
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	l := NewList(New(server.URL).Child("todo"))
+	first, err := l.Append("first")
+	require.NoError(t, err)
+	last, err := l.Append("last")
+	require.NoError(t, err)
+
+	middle, err := l.InsertBetween(first, last, "middle")
+	require.NoError(t, err)
+	_, err = l.InsertBetween("", first, "zeroth")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zeroth", "first", "middle", "last"}, listValues(t, l))
+
+	moved, err := l.Move(middle, last, "")
+	require.NoError(t, err)
+	assert.True(t, moved > last)
+	assert.Equal(t, []string{"zeroth", "first", "last", "middle"}, listValues(t, l))
+}
+
+func TestKeyBetween(t *testing.T) {
+	t.Parallel()
+	cases := [][2]string{
+		{"", ""},
+		{"", "-KpRx"},
+		{"-KpRx", ""},
+		{"-KpRx", "-KpRy"},
+		{"a", "b"},
+		{"a", "a0"},
+		{"1", "3"},
+		{"-", "0"},
+		{"zz", ""},
+	}
+	for _, c := range cases {
+		k, err := KeyBetween(c[0], c[1])
+		require.NoError(t, err, "between %q and %q", c[0], c[1])
+		assert.True(t, k > c[0], "%q should be after %q", k, c[0])
+		if c[1] != "" {
+			assert.True(t, k < c[1], "%q should be before %q", k, c[1])
+		}
+		_, err = strconv.ParseInt(k, 10, 32)
+		assert.Error(t, err, "%q looks like an integer", k)
+	}
+
+	// generate a long run of keys between the same neighbours
+	a, b := "-KpRx", "-KpRy"
+	for i := 0; i < 100; i++ {
+		k, err := KeyBetween(a, b)
+		require.NoError(t, err)
+		require.True(t, a < k && k < b)
+		a = k
+	}
+
+	_, err := KeyBetween("b", "a")
+	assert.Equal(t, ErrNoKeyBetween, err)
+	_, err = KeyBetween("a-", "a--")
+	assert.Equal(t, ErrNoKeyBetween, err)
+}
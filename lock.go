@@ -0,0 +1,74 @@
+package firego
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLocked is returned when acquiring a Lock that is held by another
+// owner.
+var ErrLocked = errors.New("lock is held by another owner")
+
+// Lock is a lease based mutual exclusion lock kept at a Firebase location,
+// for coordinating processes that share a database. The lock expires if
+// its holder does not renew it within its TTL, so that a crashed holder
+// does not keep it forever.
+type Lock struct {
+	ref   *Firebase
+	owner string
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+type lease struct {
+	Owner   string `json:"owner"`
+	Expires int64  `json:"expires"`
+}
+
+// NewLock creates a Lock kept at the location of ref. Owner identifies the
+// holder and must be unique among the processes competing for the lock.
+func NewLock(ref *Firebase, owner string, ttl time.Duration) *Lock {
+	return &Lock{ref: ref, owner: owner, ttl: ttl, now: time.Now}
+}
+
+// Acquire takes the lock, or extends it if it is already held by the
+// owner. It returns ErrLocked if another owner holds an unexpired lease.
+func (l *Lock) Acquire() error {
+	return l.ref.Transaction(func(current interface{}) (interface{}, error) {
+		now := l.now()
+		if cur, ok := currentLease(current); ok && cur.Owner != l.owner && cur.Expires > millis(now) {
+			return nil, ErrLocked
+		}
+		return lease{Owner: l.owner, Expires: millis(now.Add(l.ttl))}, nil
+	})
+}
+
+// Renew extends the lease of a lock held by the owner, it returns
+// ErrLocked if the lock was lost in the meantime.
+func (l *Lock) Renew() error {
+	return l.Acquire()
+}
+
+// Release gives up the lock if it is held by the owner.
+func (l *Lock) Release() error {
+	return l.ref.Transaction(func(current interface{}) (interface{}, error) {
+		if cur, ok := currentLease(current); ok && cur.Owner != l.owner {
+			return current, nil
+		}
+		return nil, nil
+	})
+}
+
+func currentLease(v interface{}) (lease, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return lease{}, false
+	}
+	owner, _ := m["owner"].(string)
+	expires, _ := m["expires"].(float64)
+	return lease{Owner: owner, Expires: int64(expires)}, true
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
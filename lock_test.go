@@ -0,0 +1,54 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`null`)
+	defer server.Close()
+
+	ref := New(server.URL)
+	now := time.Unix(1000, 0)
+	a, b := NewLock(ref, "a", time.Minute), NewLock(ref, "b", time.Minute)
+	a.now = func() time.Time { return now }
+	b.now = a.now
+
+	require.NoError(t, a.Acquire())
+	assert.Equal(t, `{"owner":"a","expires":1060000}`, server.value)
+	assert.Equal(t, ErrLocked, b.Acquire())
+
+	now = now.Add(30 * time.Second)
+	require.NoError(t, a.Renew())
+	assert.Equal(t, `{"owner":"a","expires":1090000}`, server.value)
+
+	// releasing a lock held by another owner leaves it alone
+	require.NoError(t, b.Release())
+	assert.Equal(t, ErrLocked, b.Acquire())
+
+	require.NoError(t, a.Release())
+	assert.Equal(t, `null`, server.value)
+	require.NoError(t, b.Acquire())
+}
+
+func TestLockExpires(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`null`)
+	defer server.Close()
+
+	ref := New(server.URL)
+	now := time.Unix(1000, 0)
+	a, b := NewLock(ref, "a", time.Minute), NewLock(ref, "b", time.Minute)
+	a.now = func() time.Time { return now }
+	b.now = a.now
+
+	require.NoError(t, a.Acquire())
+	now = now.Add(time.Minute + time.Millisecond)
+	require.NoError(t, b.Acquire())
+	assert.Equal(t, ErrLocked, a.Renew())
+}
@@ -0,0 +1,56 @@
+package firego
+
+// MergePolicy combines the current value of a location with an incoming
+// value, both given in their generic JSON representation, into the value
+// that is written.
+type MergePolicy func(current, incoming interface{}) (interface{}, error)
+
+// Merge combines v with the value of ref according to policy and writes
+// the result inside a Transaction, so that concurrent writers never
+// overwrite each other without the policy being consulted.
+func Merge(ref *Firebase, v interface{}, policy MergePolicy) error {
+	return ref.Transaction(func(current interface{}) (interface{}, error) {
+		// the policy may modify incoming, so give it a fresh copy
+		// on every attempt
+		incoming, err := normalize(v)
+		if err != nil {
+			return nil, err
+		}
+		return policy(current, incoming)
+	})
+}
+
+// LastWriteWins is a MergePolicy that replaces the current value with the
+// incoming one.
+func LastWriteWins(current, incoming interface{}) (interface{}, error) {
+	return incoming, nil
+}
+
+// NewestField returns a MergePolicy that merges objects field by field.
+// Every field is expected to be an object carrying its modification time
+// in timestampField; the version with the highest timestamp is kept. An
+// incoming field without a comparable timestamp always replaces the
+// current one, and fields only present in the current value are kept.
+func NewestField(timestampField string) MergePolicy {
+	return func(current, incoming interface{}) (interface{}, error) {
+		cur, ok := current.(map[string]interface{})
+		in, ok2 := incoming.(map[string]interface{})
+		if !ok || !ok2 {
+			return incoming, nil
+		}
+
+		merged := make(map[string]interface{}, len(cur)+len(in))
+		for k, v := range cur {
+			merged[k] = v
+		}
+		for k, v := range in {
+			curTS, ok := fieldNumber(cur[k], timestampField)
+			inTS, ok2 := fieldNumber(v, timestampField)
+			if ok && ok2 && curTS > inTS {
+				continue
+			}
+			merged[k] = v
+		}
+		return merged, nil
+	}
+}
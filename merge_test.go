@@ -0,0 +1,58 @@
+package firego
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`{"a":1}`)
+	defer server.Close()
+
+	err := Merge(New(server.URL), map[string]int{"b": 2}, func(current, incoming interface{}) (interface{}, error) {
+		m := current.(map[string]interface{})
+		for k, v := range incoming.(map[string]interface{}) {
+			m[k] = v
+		}
+		return m, nil
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1,"b":2}`, server.value)
+
+	require.NoError(t, Merge(New(server.URL), "replaced", LastWriteWins))
+	assert.Equal(t, `"replaced"`, server.value)
+}
+
+func TestNewestField(t *testing.T) {
+	t.Parallel()
+	var current, incoming interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name":  {"v": "old", "ts": 5},
+		"email": {"v": "kept", "ts": 9},
+		"nick":  {"v": "only current", "ts": 1}
+	}`), &current))
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name":  {"v": "new", "ts": 6},
+		"email": {"v": "stale", "ts": 8},
+		"city":  {"v": "added"}
+	}`), &incoming))
+
+	merged, err := NewestField("ts")(current, incoming)
+	require.NoError(t, err)
+	b, err := json.Marshal(merged)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name":  {"v": "new", "ts": 6},
+		"email": {"v": "kept", "ts": 9},
+		"nick":  {"v": "only current", "ts": 1},
+		"city":  {"v": "added"}
+	}`, string(b))
+
+	merged, err = NewestField("ts")(nil, incoming)
+	require.NoError(t, err)
+	assert.Equal(t, incoming, merged)
+}
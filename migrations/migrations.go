@@ -0,0 +1,415 @@
+/*
+Package migrations applies ordered, versioned changes to the data in a
+Firebase database, bringing schema evolution discipline to schemaless
+data.
+
+Migrations are registered with a Runner, which records the versions it
+applied in the database itself, so every environment knows which
+migrations it is missing. While migrating, the Runner holds a
+firego.Lock so that processes starting at the same time do not apply the
+same migration twice.
+*/
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zabawaba99/firego"
+)
+
+const (
+	// DefaultPath is the default location, relative to the root, where the
+	// Runner keeps its state.
+	DefaultPath = "_migrations"
+	// DefaultLockTTL is the default time after which the lock of a Runner
+	// that stopped renewing it expires.
+	DefaultLockTTL = time.Minute
+)
+
+// ErrLockLost is returned when the Runner's lock expired and was taken by
+// another owner while migrating.
+var ErrLockLost = errors.New("migration lock was lost")
+
+// Func changes the data at the location of a migration.
+type Func func(ctx context.Context, ref *firego.Firebase) error
+
+// Migration is a single versioned change to the data at a path.
+type Migration struct {
+	// Version orders the migrations, it must be positive and unique.
+	Version int
+	// Name describes the migration.
+	Name string
+	// Path the migration changes, relative to the root of the Runner.
+	Path string
+	// Up applies the migration.
+	Up Func
+	// Down, if set, reverts the migration. It is called by Rollback and,
+	// to undo a partial change, when Up fails.
+	Down Func
+	// DryRun, if set, is called instead of Up when the Runner is in dry-run
+	// mode. It should validate the data and report what Up would change
+	// without writing anything.
+	DryRun Func
+}
+
+// Runner applies the registered migrations that a database is missing.
+type Runner struct {
+	// Path where the applied versions and the lock are kept, relative to
+	// the root. It defaults to DefaultPath.
+	Path string
+	// Owner identifies the Runner when holding the lock, it defaults to
+	// the host name and process id.
+	Owner string
+	// LockTTL is the lease of the lock, which is renewed in the background
+	// while migrating. It defaults to DefaultLockTTL.
+	LockTTL time.Duration
+	// DryRun makes Migrate call the DryRun hooks of the pending migrations
+	// instead of applying them, and Rollback only report the migrations it
+	// would revert. Nothing is recorded and the lock is not taken.
+	DryRun bool
+	// Progress, if set, receives an update after every migration.
+	Progress firego.Progress
+
+	root *firego.Firebase
+
+	mtx        sync.Mutex
+	migrations []Migration
+}
+
+// record is kept for every applied migration.
+type record struct {
+	Name    string      `json:"name"`
+	Path    string      `json:"path"`
+	Applied interface{} `json:"applied"`
+}
+
+// New creates a Runner for the database at root.
+func New(root *firego.Firebase) *Runner {
+	return &Runner{root: root}
+}
+
+// Register adds a migration to the Runner.
+func (r *Runner) Register(m Migration) error {
+	if m.Version <= 0 {
+		return fmt.Errorf("migration %q has a non-positive version", m.Name)
+	}
+	if m.Up == nil {
+		return fmt.Errorf("migration %d has no Up func", m.Version)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, other := range r.migrations {
+		if other.Version == m.Version {
+			return fmt.Errorf("migration %d is already registered", m.Version)
+		}
+	}
+	r.migrations = append(r.migrations, m)
+	sort.Sort(byVersion(r.migrations))
+	return nil
+}
+
+// Applied returns the versions recorded as applied in the database, in
+// ascending order.
+func (r *Runner) Applied(ctx context.Context) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var records map[string]record
+	if err := r.versions().Value(&records); err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(records))
+	for k := range records {
+		v, err := strconv.Atoi(strings.TrimPrefix(k, "v"))
+		if err != nil || !strings.HasPrefix(k, "v") {
+			return nil, fmt.Errorf("unexpected migration record %q", k)
+		}
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// Pending returns the registered migrations that were not applied, in the
+// order they would be applied.
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+
+	var pending []Migration
+	for _, m := range r.registered() {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations in order and returns the ones
+// that were applied. It stops at the first migration that fails, after
+// calling its Down hook if it has one; the migrations applied before it
+// stay applied.
+func (r *Runner) Migrate(ctx context.Context) ([]Migration, error) {
+	if r.DryRun {
+		return r.dryRun(ctx)
+	}
+
+	h, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
+	ctx = h.ctx
+
+	// read after locking, another Runner may have just finished
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	var applied []Migration
+	for _, m := range pending {
+		if err := ctx.Err(); err != nil {
+			return applied, h.err(err)
+		}
+
+		ref := r.ref(m.Path)
+		if err := m.Up(ctx, ref); err != nil {
+			err = fmt.Errorf("migration %d %q failed: %v", m.Version, m.Name, h.err(err))
+			if m.Down != nil {
+				if derr := m.Down(ctx, ref); derr != nil {
+					err = fmt.Errorf("%v, rolling it back failed: %v", err, derr)
+				}
+			}
+			return applied, err
+		}
+
+		rec := record{Name: m.Name, Path: m.Path, Applied: firego.ServerTimestamp}
+		if err := r.versions().Child(key(m.Version)).Set(rec); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+		r.report("migrate", m, len(applied), len(pending), start)
+	}
+	return applied, nil
+}
+
+// Rollback reverts the applied migrations newer than version, newest
+// first, and returns the ones that were reverted. Every migration to
+// revert must be registered and have a Down hook.
+func (r *Runner) Rollback(ctx context.Context, version int) ([]Migration, error) {
+	h := &held{ctx: ctx}
+	if !r.DryRun {
+		var err error
+		if h, err = r.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer h.release()
+		ctx = h.ctx
+	}
+
+	targets, err := r.rollbackTargets(ctx, version)
+	if err != nil || r.DryRun {
+		return targets, err
+	}
+
+	start := time.Now()
+	var reverted []Migration
+	for _, m := range targets {
+		if err := ctx.Err(); err != nil {
+			return reverted, h.err(err)
+		}
+		if err := m.Down(ctx, r.ref(m.Path)); err != nil {
+			return reverted, fmt.Errorf("rolling back migration %d %q failed: %v", m.Version, m.Name, h.err(err))
+		}
+		if err := r.versions().Child(key(m.Version)).Remove(); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, m)
+		r.report("rollback", m, len(reverted), len(targets), start)
+	}
+	return reverted, nil
+}
+
+func (r *Runner) dryRun(ctx context.Context) ([]Migration, error) {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	for i, m := range pending {
+		if err := ctx.Err(); err != nil {
+			return pending[:i], err
+		}
+		if m.DryRun != nil {
+			if err := m.DryRun(ctx, r.ref(m.Path)); err != nil {
+				return pending[:i], fmt.Errorf("migration %d %q failed its dry run: %v", m.Version, m.Name, err)
+			}
+		}
+		r.report("migrate", m, i+1, len(pending), start)
+	}
+	return pending, nil
+}
+
+// rollbackTargets returns the applied migrations newer than version,
+// newest first.
+func (r *Runner) rollbackTargets(ctx context.Context, version int) ([]Migration, error) {
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := map[int]Migration{}
+	for _, m := range r.registered() {
+		registered[m.Version] = m
+	}
+
+	var targets []Migration
+	for i := len(applied) - 1; i >= 0 && applied[i] > version; i-- {
+		m, ok := registered[applied[i]]
+		if !ok {
+			return nil, fmt.Errorf("migration %d is not registered", applied[i])
+		}
+		if m.Down == nil {
+			return nil, fmt.Errorf("migration %d %q cannot be rolled back", m.Version, m.Name)
+		}
+		targets = append(targets, m)
+	}
+	return targets, nil
+}
+
+func (r *Runner) registered() []Migration {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]Migration(nil), r.migrations...)
+}
+
+func (r *Runner) report(op string, m Migration, done, total int, start time.Time) {
+	if r.Progress == nil {
+		return
+	}
+	r.Progress.Report(firego.ProgressUpdate{
+		Op:      op,
+		Path:    r.ref(m.Path).String(),
+		Done:    int64(done),
+		Total:   int64(total),
+		LastKey: strconv.Itoa(m.Version),
+		Elapsed: time.Since(start),
+	})
+}
+
+func (r *Runner) ref(path string) *firego.Firebase {
+	if path = strings.Trim(path, "/"); path == "" {
+		return r.root
+	}
+	return r.root.Child(path)
+}
+
+func (r *Runner) state() *firego.Firebase {
+	path := r.Path
+	if path == "" {
+		path = DefaultPath
+	}
+	return r.ref(path)
+}
+
+func (r *Runner) versions() *firego.Firebase {
+	return r.state().Child("versions")
+}
+
+// held is a lock held by a Runner, its context is canceled if the lock
+// is lost.
+type held struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	lock    *firego.Lock
+	stop    chan struct{}
+	done    chan struct{}
+	lostMtx sync.Mutex
+	lost    bool
+}
+
+func (r *Runner) lock(ctx context.Context) (*held, error) {
+	ttl := r.LockTTL
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+	owner := r.Owner
+	if owner == "" {
+		host, _ := os.Hostname()
+		owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	l := firego.NewLock(r.state().Child("lock"), owner, ttl)
+	if err := l.Acquire(); err != nil {
+		return nil, err
+	}
+
+	h := &held{lock: l, stop: make(chan struct{}), done: make(chan struct{})}
+	h.ctx, h.cancel = context.WithCancel(ctx)
+	go h.renew(ttl / 3)
+	return h, nil
+}
+
+func (h *held) renew(interval time.Duration) {
+	defer close(h.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+		if err := h.lock.Renew(); err == firego.ErrLocked {
+			h.lostMtx.Lock()
+			h.lost = true
+			h.lostMtx.Unlock()
+			h.cancel()
+			return
+		}
+	}
+}
+
+// err replaces the cancellation of the context by the loss of the lock.
+func (h *held) err(err error) error {
+	h.lostMtx.Lock()
+	defer h.lostMtx.Unlock()
+	if h.lost && err == context.Canceled {
+		return ErrLockLost
+	}
+	return err
+}
+
+func (h *held) release() {
+	close(h.stop)
+	<-h.done
+	h.cancel()
+	h.lock.Release()
+}
+
+func key(version int) string {
+	return "v" + strconv.Itoa(version)
+}
+
+type byVersion []Migration
+
+func (s byVersion) Len() int           { return len(s) }
+func (s byVersion) Less(i, j int) bool { return s[i].Version < s[j].Version }
+func (s byVersion) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,249 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firego/internal/treeserver"
+)
+
+// rename moves the field from of every child to the field to.
+func rename(from, to string) Func {
+	return func(ctx context.Context, ref *firego.Firebase) error {
+		var children map[string]map[string]interface{}
+		if err := ref.Value(&children); err != nil {
+			return err
+		}
+		update := map[string]interface{}{}
+		for k, child := range children {
+			if v, ok := child[from]; ok {
+				update[k+"/"+to] = v
+				update[k+"/"+from] = nil
+			}
+		}
+		if len(update) == 0 {
+			return nil
+		}
+		return ref.Update(update)
+	}
+}
+
+func versions(ms []Migration) []int {
+	v := make([]int, len(ms))
+	for i, m := range ms {
+		v[i] = m.Version
+	}
+	return v
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+	r := New(nil)
+	up := func(context.Context, *firego.Firebase) error { return nil }
+
+	require.NoError(t, r.Register(Migration{Version: 2, Up: up}))
+	require.NoError(t, r.Register(Migration{Version: 1, Up: up}))
+	assert.Error(t, r.Register(Migration{Version: 1, Up: up}))
+	assert.Error(t, r.Register(Migration{Version: 0, Up: up}))
+	assert.Error(t, r.Register(Migration{Version: 3}))
+	assert.Equal(t, []int{1, 2}, versions(r.registered()))
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`{"users":{"a":{"mail":"a@x"},"b":{"mail":"b@x"}}}`)
+	defer server.Close()
+
+	r := New(firego.New(server.URL))
+	r.Owner = "test"
+	var updates []firego.ProgressUpdate
+	r.Progress = firego.ProgressFunc(func(u firego.ProgressUpdate) {
+		updates = append(updates, u)
+	})
+	require.NoError(t, r.Register(Migration{
+		Version: 1,
+		Name:    "rename mail to email",
+		Path:    "users",
+		Up:      rename("mail", "email"),
+		Down:    rename("email", "mail"),
+	}))
+	require.NoError(t, r.Register(Migration{
+		Version: 2,
+		Name:    "add settings",
+		Up: func(ctx context.Context, ref *firego.Firebase) error {
+			return ref.Child("settings").Set(map[string]bool{"signup": true})
+		},
+	}))
+
+	applied, err := r.Migrate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, versions(applied))
+	assert.Equal(t, "a@x", server.Get("users/a/email"))
+	assert.Nil(t, server.Get("users/a/mail"))
+	assert.Equal(t, true, server.Get("settings/signup"))
+	assert.Equal(t, map[string]interface{}{
+		"name":    "rename mail to email",
+		"path":    "users",
+		"applied": 1.0,
+	}, server.Get("_migrations/versions/v1"))
+	assert.Nil(t, server.Get("_migrations/lock"), "lock must be released")
+
+	require.Len(t, updates, 2)
+	assert.Equal(t, "migrate", updates[1].Op)
+	assert.Equal(t, int64(2), updates[1].Done)
+	assert.Equal(t, int64(2), updates[1].Total)
+	assert.Equal(t, "2", updates[1].LastKey)
+
+	// everything is applied now
+	applied, err = r.Migrate(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+
+	r := New(firego.New(server.URL))
+	var rolledBack bool
+	require.NoError(t, r.Register(Migration{
+		Version: 1,
+		Up: func(ctx context.Context, ref *firego.Firebase) error {
+			return ref.Child("a").Set(1)
+		},
+	}))
+	require.NoError(t, r.Register(Migration{
+		Version: 2,
+		Up: func(ctx context.Context, ref *firego.Firebase) error {
+			if err := ref.Child("b").Set(1); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+		Down: func(ctx context.Context, ref *firego.Firebase) error {
+			rolledBack = true
+			return ref.Child("b").Remove()
+		},
+	}))
+
+	applied, err := r.Migrate(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+	assert.Equal(t, []int{1}, versions(applied))
+	assert.True(t, rolledBack)
+	assert.Nil(t, server.Get("b"))
+
+	got, err := r.Applied(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, got)
+}
+
+func TestMigrateLocked(t *testing.T) {
+	t.Parallel()
+	expires := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	server := treeserver.New(`{"_migrations":{"lock":{"owner":"other","expires":` + strconv.FormatInt(expires, 10) + `}}}`)
+	defer server.Close()
+
+	r := New(firego.New(server.URL))
+	var called bool
+	require.NoError(t, r.Register(Migration{
+		Version: 1,
+		Up: func(context.Context, *firego.Firebase) error {
+			called = true
+			return nil
+		},
+	}))
+
+	_, err := r.Migrate(context.Background())
+	assert.Equal(t, firego.ErrLocked, err)
+	assert.False(t, called)
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`{"users":{"a":{"mail":"a@x"}}}`)
+	defer server.Close()
+
+	r := New(firego.New(server.URL))
+	r.DryRun = true
+	var checked []string
+	require.NoError(t, r.Register(Migration{
+		Version: 1,
+		Path:    "users",
+		Up:      rename("mail", "email"),
+		DryRun: func(ctx context.Context, ref *firego.Firebase) error {
+			checked = append(checked, ref.String())
+			return nil
+		},
+	}))
+
+	pending, err := r.Migrate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, versions(pending))
+	assert.Equal(t, []string{server.URL + "/users/.json"}, checked)
+	assert.Equal(t, "a@x", server.Get("users/a/mail"))
+	assert.Nil(t, server.Get("_migrations"))
+}
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`{"users":{"a":{"mail":"a@x"}}}`)
+	defer server.Close()
+
+	r := New(firego.New(server.URL))
+	require.NoError(t, r.Register(Migration{
+		Version: 1,
+		Path:    "users",
+		Up:      rename("mail", "email"),
+		Down:    rename("email", "mail"),
+	}))
+	require.NoError(t, r.Register(Migration{
+		Version: 2,
+		Path:    "users",
+		Up:      rename("email", "address"),
+		Down:    rename("address", "email"),
+	}))
+	_, err := r.Migrate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a@x", server.Get("users/a/address"))
+
+	r.DryRun = true
+	reverted, err := r.Rollback(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, versions(reverted))
+	assert.Equal(t, "a@x", server.Get("users/a/address"))
+
+	r.DryRun = false
+	reverted, err = r.Rollback(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, versions(reverted))
+	assert.Equal(t, "a@x", server.Get("users/a/email"))
+
+	applied, err := r.Applied(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, applied)
+}
+
+func TestRollbackWithoutDown(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+
+	r := New(firego.New(server.URL))
+	require.NoError(t, r.Register(Migration{
+		Version: 1,
+		Up:      func(context.Context, *firego.Firebase) error { return nil },
+	}))
+	_, err := r.Migrate(context.Background())
+	require.NoError(t, err)
+
+	_, err = r.Rollback(context.Background(), 0)
+	assert.Error(t, err)
+}
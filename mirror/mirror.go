@@ -0,0 +1,236 @@
+/*
+Package mirror materializes the children of a Firebase location as JSON
+files in a directory, one file per child, and keeps them updated as the
+data changes.
+
+A child's file is named after its key, escaped with firego.EscapeKey, with
+a ".json" extension. Files are replaced atomically so readers never see a
+partially written value.
+*/
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firego/internal/tree"
+)
+
+// DefaultRetryInterval is the default time between attempts to
+// re-establish the watch.
+const DefaultRetryInterval = 5 * time.Second
+
+const ext = ".json"
+
+// Mirror keeps a directory updated with the children of a Firebase
+// reference. The Mirror owns the watch on the reference.
+type Mirror struct {
+	// RetryInterval between attempts to re-establish the watch, it
+	// defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+	// OnError, if set, is called with the errors encountered in the
+	// background.
+	OnError func(error)
+
+	ref *firego.Firebase
+	dir string
+
+	// mtx serializes the writes to dir
+	mtx    sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a Mirror of the children of ref into dir.
+func New(ref *firego.Firebase, dir string) *Mirror {
+	return &Mirror{ref: ref, dir: dir}
+}
+
+// Snapshot writes the current children of the reference into the
+// directory once, removing the files of children that no longer exist.
+func (m *Mirror) Snapshot() error {
+	var v interface{}
+	if err := m.ref.Value(&v); err != nil {
+		return err
+	}
+	return m.write(nil, v)
+}
+
+// Start creates the directory if needed and starts mirroring changes in
+// the background.
+func (m *Mirror) Start() error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+	m.done = make(chan struct{})
+	go m.run(ctx)
+	return nil
+}
+
+// Stop tears down the watch of the Mirror, the other watches of the
+// reference are left alone. It does nothing if the Mirror was not started.
+func (m *Mirror) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+	m.cancel = nil
+}
+
+// Path returns the file that holds the child identified by key.
+func (m *Mirror) Path(key string) string {
+	return filepath.Join(m.dir, firego.EscapeKey(key)+ext)
+}
+
+func (m *Mirror) run(ctx context.Context) {
+	defer close(m.done)
+
+	interval := m.RetryInterval
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+
+	for {
+		notifications := make(chan firego.Event)
+		if err := m.ref.WatchContext(ctx, notifications); err != nil {
+			m.report(err)
+		} else {
+			for event := range notifications {
+				m.apply(event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (m *Mirror) apply(event firego.Event) {
+	path := tree.Split(event.Path)
+	switch event.Type {
+	case firego.EventTypePut:
+		m.report(m.write(path, event.Data))
+	case firego.EventTypePatch:
+		data, _ := event.Data.(map[string]interface{})
+		for k, v := range data {
+			m.report(m.write(append(path[:len(path):len(path)], tree.Split(k)...), v))
+		}
+	case firego.EventTypeError:
+		m.report(event.Data.(error))
+	}
+}
+
+// write applies v at path, relative to the reference, to the directory.
+func (m *Mirror) write(path []string, v interface{}) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if len(path) > 0 {
+		key := path[0]
+		if len(path) > 1 {
+			base, err := m.read(key)
+			if err != nil {
+				return err
+			}
+			v = tree.Set(base, path[1:], v)
+		}
+		return m.writeChild(key, v)
+	}
+
+	// a snapshot of the whole location
+	children, _ := v.(map[string]interface{})
+	files, err := filepath.Glob(filepath.Join(m.dir, "*"+ext))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		key, err := firego.UnescapeKey(strings.TrimSuffix(filepath.Base(f), ext))
+		if err != nil {
+			// not one of ours
+			continue
+		}
+		if _, ok := children[key]; !ok {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	for k, child := range children {
+		if err := m.writeChild(k, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mirror) read(key string) (interface{}, error) {
+	b, err := ioutil.ReadFile(m.Path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	return v, json.Unmarshal(b, &v)
+}
+
+func (m *Mirror) writeChild(key string, v interface{}) error {
+	name := m.Path(key)
+	if v == nil {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if old, err := ioutil.ReadFile(name); err == nil && bytes.Equal(old, b) {
+		// unchanged, e.g. when the snapshot sent after a reconnect is
+		// applied, leave the file and its modification time alone
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(m.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (m *Mirror) report(err error) {
+	if err != nil && m.OnError != nil {
+		m.OnError(err)
+	}
+}
@@ -0,0 +1,172 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firetest"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mirror")
+	require.NoError(t, err)
+	return dir
+}
+
+func contents(m *Mirror, key string) string {
+	b, err := ioutil.ReadFile(m.Path(key))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "stale.json"), []byte(`1`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`keep`), 0644))
+
+	server.Set("posts/a", map[string]interface{}{"title": "A"})
+	server.Set("posts/b.c", 2)
+
+	m := New(firego.New(server.URL+"/posts"), dir)
+	require.NoError(t, m.Snapshot())
+
+	assert.Equal(t, "{\n  \"title\": \"A\"\n}\n", contents(m, "a"))
+	assert.Equal(t, "2\n", contents(m, "b.c"))
+	assert.Equal(t, filepath.Join(dir, "b%2Ec.json"), m.Path("b.c"))
+
+	_, err := os.Stat(filepath.Join(dir, "stale.json"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+}
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	server.Set("posts/a", map[string]interface{}{"title": "A"})
+
+	m := New(firego.New(server.URL+"/posts"), filepath.Join(dir, "posts"))
+	m.RetryInterval = 50 * time.Millisecond
+	require.NoError(t, m.Start())
+	defer m.Stop()
+
+	eventually(t, func() bool { return contents(m, "a") != "" })
+
+	server.Set("posts/a/body", "text")
+	eventually(t, func() bool {
+		return contents(m, "a") == "{\n  \"body\": \"text\",\n  \"title\": \"A\"\n}\n"
+	})
+
+	server.Delete("posts/a")
+	eventually(t, func() bool { return contents(m, "a") == "" })
+}
+
+func TestApplyPatch(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	m := New(nil, dir)
+	m.apply(firego.Event{Type: "put", Path: "/a", Data: map[string]interface{}{"x": 1.0, "y": 2.0}})
+	m.apply(firego.Event{Type: "patch", Path: "/", Data: map[string]interface{}{
+		"a/x": nil,
+		"b":   true,
+	}})
+
+	assert.Equal(t, "{\n  \"y\": 2\n}\n", contents(m, "a"))
+	assert.Equal(t, "true\n", contents(m, "b"))
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "condition was not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnchangedFilesAreKept(t *testing.T) {
+	t.Parallel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	m := New(nil, dir)
+	m.apply(firego.Event{Type: "put", Path: "/", Data: map[string]interface{}{"a": 1.0, "b": 2.0}})
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(m.Path("a"), old, old))
+	require.NoError(t, os.Chtimes(m.Path("b"), old, old))
+
+	// the snapshot sent after reconnecting
+	m.apply(firego.Event{Type: "put", Path: "/", Data: map[string]interface{}{"a": 1.0, "b": 3.0}})
+
+	info, err := os.Stat(m.Path("a"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(old))
+
+	info, err = os.Stat(m.Path("b"))
+	require.NoError(t, err)
+	assert.False(t, info.ModTime().Equal(old))
+	assert.Equal(t, "3\n", contents(m, "b"))
+}
+
+func TestStop(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	New(firego.New(server.URL), dir).Stop()
+
+	ref := firego.New(server.URL + "/posts")
+	notifications := make(chan firego.Event)
+	require.NoError(t, ref.Watch(notifications))
+	defer ref.StopWatching()
+
+	m := New(ref, dir)
+	require.NoError(t, m.Start())
+	server.Set("posts/a", 1)
+	eventually(t, func() bool { return contents(m, "a") != "" })
+	m.Stop()
+	m.Stop()
+
+	// the watches of the caller outlive the Mirror
+	server.Set("posts/b", 2)
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-notifications:
+			require.True(t, ok, "the watch of the caller was torn down")
+			if event.Path == "/b" {
+				return
+			}
+		case <-timeout:
+			require.FailNow(t, "the watch of the caller did not see the change")
+		}
+	}
+}
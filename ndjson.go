@@ -0,0 +1,80 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ndjsonLine is the line WriteNDJSON writes for an event. Data is a
+// pointer since json.RawMessage only marshals through a pointer before
+// Go 1.8.
+type ndjsonLine struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Path      string           `json:"path"`
+	Type      string           `json:"type"`
+	Data      *json.RawMessage `json:"data"`
+}
+
+// WriteNDJSON watches the reference like WatchContext and writes each
+// event to w as a line of newline delimited JSON, with the time it was
+// received, its path, its type and its data:
+//
+//	{"timestamp":"2017-01-02T15:04:05.999Z","path":"/a","type":"put","data":1}
+//
+// e.g. to keep the change history of a location in a file or to pipe it to
+// a log shipper. Keep-alive events are not written and each line is written
+// with a single call to w.
+//
+// It blocks until the watch ends and returns why: the error of ctx, the
+// error writing to w, which stops the watch, or the errors Events yields.
+func (fb *Firebase) WriteNDJSON(ctx context.Context, w io.Writer, opts ...RequestOption) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	notifications := make(chan Event)
+	if err := fb.WatchContext(watchCtx, notifications, opts...); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	var err error
+	for event := range notifications {
+		switch event.Type {
+		case EventTypeKeepAlive:
+			continue
+		case EventTypeError:
+			err, _ = event.Data.(error)
+			continue
+		}
+
+		data := event.Raw
+		if len(data) == 0 {
+			data = json.RawMessage("null")
+		}
+		line := ndjsonLine{
+			Timestamp: time.Now().UTC(),
+			Path:      event.Path,
+			Type:      event.Type,
+			Data:      &data,
+		}
+		if err := enc.Encode(line); err != nil {
+			cancel()
+			for range notifications {
+				// wait for the connection to be torn down
+			}
+			return err
+		}
+
+		if event.Type == EventTypeCancel {
+			err = ErrWatchCanceled
+			if e, ok := event.Data.(error); ok {
+				err = e
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package firego
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"a\":1}}\n\n"))
+		w.Write([]byte("event: keep-alive\ndata: null\n\n"))
+		w.Write([]byte("event: patch\ndata: {\"path\":\"/b\",\"data\":{\"c\":\"d\"}}\n\n"))
+		w.Write([]byte("event: cancel\ndata: null\n\n"))
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	var buf bytes.Buffer
+	start := time.Now().Add(-time.Second)
+	assert.Equal(t, ErrWatchCanceled, fb.WriteNDJSON(context.Background(), &buf))
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		ts, err := time.Parse(time.RFC3339Nano, line["timestamp"].(string))
+		require.NoError(t, err)
+		assert.True(t, ts.After(start))
+		delete(line, "timestamp")
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"path": "/", "type": "put", "data": map[string]interface{}{"a": 1.0}},
+		{"path": "/b", "type": "patch", "data": map[string]interface{}{"c": "d"}},
+		{"path": "", "type": "cancel", "data": nil},
+	}, lines)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriteNDJSONWriteError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	err := fb.WriteNDJSON(context.Background(), failingWriter{})
+	assert.EqualError(t, err, "disk full")
+	assert.False(t, fb.isWatching())
+}
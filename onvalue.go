@@ -0,0 +1,219 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// valueListeners are the callbacks registered with OnValue that a
+// dispatcher goroutine calls. Callbacks registered after the first are
+// pending until the dispatcher gave them the current value.
+type valueListeners struct {
+	fns     []*valueListener
+	pending []*valueListener
+	added   chan struct{}
+	cancel  context.CancelFunc
+}
+
+// valueListener is a callback of OnValue, or of OnValueDebounced if it has
+// an interval. Only the dispatcher uses its state.
+type valueListener struct {
+	fn       func(*Snapshot)
+	interval time.Duration
+	// last is when fn was last called, due is set if the value changed
+	// since then, to latest
+	last   time.Time
+	due    bool
+	latest []byte
+}
+
+// OnValue calls fn with a snapshot of the location every time its value
+// changes, starting with the value it has when fn is registered, in the
+// style of the JavaScript SDK. The callbacks of a reference share a single
+// watch, they are called one at a time from a goroutine of their own and a
+// slow callback delays the ones after it.
+//
+// The first callback starts the watch, which fails like Watch does. The
+// callbacks are dropped when the watch ends, WatchErr tells why.
+func (fb *Firebase) OnValue(fn func(*Snapshot)) error {
+	return fb.listen(&valueListener{fn: fn})
+}
+
+// OnValueDebounced is like OnValue but fn is called at most once per
+// interval, with the latest value, so that the changes made in between are
+// coalesced into a single snapshot. The first value is delivered right
+// away.
+func (fb *Firebase) OnValueDebounced(interval time.Duration, fn func(*Snapshot)) error {
+	return fb.listen(&valueListener{fn: fn, interval: interval})
+}
+
+// listen registers a callback, starting the watch for the first one.
+func (fb *Firebase) listen(listener *valueListener) error {
+	fb.listenersMtx.Lock()
+	defer fb.listenersMtx.Unlock()
+	if l := fb.listeners; l != nil {
+		l.pending = append(l.pending, listener)
+		select {
+		case l.added <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications := make(chan Event)
+	if err := fb.WatchContext(ctx, notifications); err != nil {
+		cancel()
+		return err
+	}
+	l := &valueListeners{
+		pending: []*valueListener{listener},
+		added:   make(chan struct{}, 1),
+		cancel:  cancel,
+	}
+	fb.listeners = l
+	go fb.dispatch(l, notifications)
+	return nil
+}
+
+// Off removes the callbacks registered with OnValue and stops their
+// watch. A callback that is running when Off is called finishes, none is
+// called afterwards.
+func (fb *Firebase) Off() {
+	fb.listenersMtx.Lock()
+	l := fb.listeners
+	fb.listeners = nil
+	fb.listenersMtx.Unlock()
+	if l != nil {
+		l.cancel()
+	}
+}
+
+// dispatch calls the callbacks of l with the value of the location after
+// each of the notifications that changed it, and the pending callbacks
+// with the current value. Debounced callbacks are called once their
+// interval elapsed.
+func (fb *Firebase) dispatch(l *valueListeners, notifications chan Event) {
+	defer func() {
+		fb.listenersMtx.Lock()
+		if fb.listeners == l {
+			fb.listeners = nil
+		}
+		fb.listenersMtx.Unlock()
+		l.cancel()
+	}()
+
+	var (
+		value   interface{}
+		raw     []byte
+		started bool
+	)
+	for {
+		var (
+			timer *time.Timer
+			wake  <-chan time.Time
+		)
+		if d, ok := fb.nextDue(l); ok {
+			timer = time.NewTimer(d)
+			wake = timer.C
+		}
+
+		select {
+		case event, ok := <-notifications:
+			if !ok {
+				return
+			}
+			next, ok := withEvent(value, event)
+			if !ok || (started && reflect.DeepEqual(value, next)) {
+				continue
+			}
+			value, started = next, true
+			raw, _ = json.Marshal(value)
+			fb.callListeners(l, true, raw)
+		case <-l.added:
+			if started {
+				fb.callListeners(l, false, raw)
+			}
+		case <-wake:
+			fb.callDue(l)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// callListeners makes the pending callbacks of l regular ones and calls
+// them with raw, along with the regular callbacks if all is true. The
+// debounced callbacks whose interval did not elapse yet are only marked as
+// due.
+func (fb *Firebase) callListeners(l *valueListeners, all bool, raw []byte) {
+	fb.listenersMtx.Lock()
+	listeners := l.pending
+	if all {
+		listeners = append(l.fns[:len(l.fns):len(l.fns)], l.pending...)
+	}
+	l.fns = append(l.fns, l.pending...)
+	l.pending = nil
+	fb.listenersMtx.Unlock()
+
+	for _, listener := range listeners {
+		listener.due, listener.latest = true, raw
+	}
+	fb.call(l, listeners)
+}
+
+// callDue calls the debounced callbacks of l whose interval elapsed with
+// the latest value they missed.
+func (fb *Firebase) callDue(l *valueListeners) {
+	fb.listenersMtx.Lock()
+	listeners := l.fns
+	fb.listenersMtx.Unlock()
+	fb.call(l, listeners)
+}
+
+// call calls the due listeners whose interval elapsed. It stops as soon as
+// Off removed the callbacks of l.
+func (fb *Firebase) call(l *valueListeners, listeners []*valueListener) {
+	now := time.Now()
+	for _, listener := range listeners {
+		if !listener.due || now.Sub(listener.last) < listener.interval {
+			continue
+		}
+		if !fb.listening(l) {
+			return
+		}
+		listener.last, listener.due = now, false
+		listener.fn(&Snapshot{key: fb.Key(), raw: listener.latest})
+	}
+}
+
+// nextDue returns how long until the next debounced callback of l is due,
+// false if none is.
+func (fb *Firebase) nextDue(l *valueListeners) (time.Duration, bool) {
+	fb.listenersMtx.Lock()
+	defer fb.listenersMtx.Unlock()
+	var (
+		next  time.Duration
+		found bool
+	)
+	for _, listener := range l.fns {
+		if !listener.due {
+			continue
+		}
+		d := listener.interval - time.Since(listener.last)
+		if !found || d < next {
+			next, found = d, true
+		}
+	}
+	return next, found
+}
+
+// listening reports whether the callbacks of l were not removed by Off.
+func (fb *Firebase) listening(l *valueListeners) bool {
+	fb.listenersMtx.Lock()
+	defer fb.listenersMtx.Unlock()
+	return fb.listeners == l
+}
@@ -0,0 +1,88 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestOnValue(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", 1)
+	fb := New(server.URL + "/users")
+	first, second := make(chan *Snapshot, 10), make(chan *Snapshot, 10)
+	require.NoError(t, fb.OnValue(func(s *Snapshot) { first <- s }))
+	require.NoError(t, fb.OnValue(func(s *Snapshot) { second <- s }))
+
+	next := func(snapshots chan *Snapshot) map[string]interface{} {
+		select {
+		case s := <-snapshots:
+			assert.Equal(t, "users", s.Key())
+			var v map[string]interface{}
+			require.NoError(t, s.Val(&v))
+			return v
+		case <-time.After(time.Second):
+			t.Fatal("no snapshot")
+		}
+		return nil
+	}
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, next(first))
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, next(second))
+
+	server.Set("users/b", 2)
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": 2.0}, next(first))
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": 2.0}, next(second))
+
+	fb.Off()
+	server.Set("users/c", 3)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, first)
+
+	// the callbacks can be registered again once they are off
+	require.NoError(t, fb.OnValue(func(s *Snapshot) { first <- s }))
+	assert.Len(t, next(first), 3)
+	fb.Off()
+}
+
+func TestOnValueDebounced(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("counter", 0)
+	fb := New(server.URL + "/counter")
+	snapshots := make(chan *Snapshot, 10)
+	require.NoError(t, fb.OnValueDebounced(100*time.Millisecond, func(s *Snapshot) { snapshots <- s }))
+	defer fb.Off()
+
+	next := func() int {
+		select {
+		case s := <-snapshots:
+			var v int
+			require.NoError(t, s.Val(&v))
+			return v
+		case <-time.After(time.Second):
+			t.Fatal("no snapshot")
+		}
+		return 0
+	}
+	// the first value is not delayed
+	assert.Equal(t, 0, next())
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		server.Set("counter", i)
+	}
+	assert.Equal(t, 5, next())
+	assert.True(t, time.Since(start) >= 50*time.Millisecond, "the changes were coalesced")
+	time.Sleep(150 * time.Millisecond)
+	assert.Empty(t, snapshots)
+}
@@ -0,0 +1,94 @@
+//go:build go1.18
+// +build go1.18
+
+package firego
+
+import "encoding/json"
+
+type optionalState uint8
+
+const (
+	unset optionalState = iota
+	null
+	set
+)
+
+// Optional is a value that distinguishes between being left unchanged,
+// being deleted and being set to a value. Firebase deletes keys that are
+// set to null, so a missing field and a null field mean different things
+// to an Update. Use UpdateFrom or SetOptional to build the Update.
+//
+// The zero value of an Optional is unset.
+type Optional[T any] struct {
+	state optionalState
+	value T
+}
+
+// Some returns an Optional set to v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{state: set, value: v}
+}
+
+// Null returns an Optional that deletes the value it is written to.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: null}
+}
+
+// Get returns the value of the Optional and whether it is set to a value.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.state == set
+}
+
+// IsNull reports whether the Optional deletes the value it is written to.
+func (o Optional[T]) IsNull() bool {
+	return o.state == null
+}
+
+// IsUnset reports whether the Optional leaves the value unchanged.
+func (o Optional[T]) IsUnset() bool {
+	return o.state == unset
+}
+
+func (o Optional[T]) optionalValue() (interface{}, bool) {
+	switch o.state {
+	case set:
+		return o.value, true
+	case null:
+		return nil, true
+	}
+	return nil, false
+}
+
+// MarshalJSON encodes the value of the Optional, or null if it is not
+// set to a value.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.state != set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON decodes a value into the Optional, null decodes into an
+// Optional for which IsNull is true.
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*o = Null[T]()
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
+
+// SetOptional adds o to p at path: a write if o is set to a value, a
+// deletion if o is null and nothing if o is unset.
+func SetOptional[T any](p Patch, path string, o Optional[T]) Patch {
+	if v, present := o.optionalValue(); present {
+		p.Set(path, v)
+	}
+	return p
+}
@@ -0,0 +1,64 @@
+//go:build go1.18
+// +build go1.18
+
+package firego
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptional(t *testing.T) {
+	t.Parallel()
+	var o Optional[string]
+	assert.True(t, o.IsUnset())
+	_, ok := o.Get()
+	assert.False(t, ok)
+
+	o = Some("v")
+	v, ok := o.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "v", v)
+
+	assert.True(t, Null[int]().IsNull())
+}
+
+func TestOptionalJSON(t *testing.T) {
+	t.Parallel()
+	type doc struct {
+		A Optional[int] `json:"a"`
+		B Optional[int] `json:"b"`
+		C Optional[int] `json:"c"`
+	}
+
+	b, err := json.Marshal(doc{A: Some(1), B: Null[int]()})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1,"b":null,"c":null}`, string(b))
+
+	var d doc
+	require.NoError(t, json.Unmarshal([]byte(`{"a":2,"b":null}`), &d))
+	assert.Equal(t, Some(2), d.A)
+	assert.True(t, d.B.IsNull())
+	assert.True(t, d.C.IsUnset())
+}
+
+func TestUpdateFromOptional(t *testing.T) {
+	t.Parallel()
+	type profile struct {
+		Name  Optional[string] `json:"name"`
+		Email Optional[string] `json:"email"`
+		Age   Optional[int]    `json:"age"`
+	}
+
+	p, err := UpdateFrom(profile{Name: Some("ann"), Email: Null[string]()})
+	require.NoError(t, err)
+	assert.Equal(t, Patch{"name": "ann", "email": nil}, p)
+
+	p = SetOptional(Patch{}, "users/u1/age", Some(3))
+	p = SetOptional(p, "users/u1/name", Optional[string]{})
+	p = SetOptional(p, "users/u1/email", Null[string]())
+	assert.Equal(t, Patch{"users/u1/age": 3, "users/u1/email": nil}, p)
+}
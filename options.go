@@ -0,0 +1,112 @@
+package firego
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Logger receives the messages firego logs, such as the debug output of
+// security rules. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option configures a Firebase reference created by New.
+type Option func(*options)
+
+type options struct {
+	client    *http.Client
+	transport http.RoundTripper
+	timeout   time.Duration
+	auth      string
+	tokens    TokenSource
+	logger    Logger
+
+	maxEventSize int
+}
+
+// WithHTTPClient makes the reference send its requests with client. The
+// client is used as is, WithTimeout and WithTransport are ignored.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithTransport makes the reference send its requests through rt, e.g. a
+// Failover, instead of a default transport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithTimeout sets the time requests have to establish a connection, and
+// then to receive headers, from Firebase before returning an ErrTimeout
+// error. It defaults to TimeoutDuration and only applies to the default
+// transport.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithAuth authenticates the requests of the reference, and of the
+// references derived from it, with token.
+func WithAuth(token string) Option {
+	return func(o *options) {
+		o.auth = token
+	}
+}
+
+// WithTokenSource sets where the reference, and the references derived
+// from it, get a new auth token when Firebase revokes the current one, e.g.
+// because it expired, see WatchReconnect.
+func WithTokenSource(ts TokenSource) Option {
+	return func(o *options) {
+		o.tokens = ts
+	}
+}
+
+// WithLogger sets the Logger of the reference, it defaults to the standard
+// logger of the log package.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithMaxEventSize limits the size of the events the watches of the
+// reference accept to n bytes, the JSON of their data included. Watches
+// that receive a larger event end with ErrEventTooLarge instead of buffering
+// it. Events are not limited by default, since a put event carries the
+// whole value of the watched location.
+func WithMaxEventSize(n int) Option {
+	return func(o *options) {
+		o.maxEventSize = n
+	}
+}
+
+// httpClient returns the http.Client configured by the options.
+func (o *options) httpClient() *http.Client {
+	if o.client != nil {
+		return o.client
+	}
+	rt := o.transport
+	if rt == nil {
+		rt = defaultTransport(o.timeout)
+	}
+	return &http.Client{
+		Transport:     rt,
+		CheckRedirect: redirectPreserveHeaders,
+	}
+}
+
+// stdLogger logs through the standard logger, so that changes to its
+// output and flags apply.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
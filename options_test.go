@@ -0,0 +1,71 @@
+package firego
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	t.Parallel()
+	client := &http.Client{}
+	fb := New(URL, WithHTTPClient(client), WithTimeout(time.Second))
+	assert.Equal(t, client, fb.client)
+}
+
+func TestWithTransport(t *testing.T) {
+	t.Parallel()
+	var urls []string
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		return nil, fmt.Errorf("offline")
+	})
+
+	fb := New(URL, WithTransport(rt), WithAuth("token"))
+	assert.Error(t, fb.Child("users").Value(new(interface{})))
+	assert.Equal(t, []string{URL + "/users/.json?auth=token"}, urls)
+}
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithTimeout(time.Millisecond))
+	err := fb.Value(new(interface{}))
+	assert.IsType(t, ErrTimeout{}, err)
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: rules_debug\ndata: \"read allowed\"\n\n")
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	fb := New(server.URL, WithLogger(log.New(&buf, "", 0)))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+	for range notifications {
+	}
+	fb.StopWatching()
+	assert.Contains(t, buf.String(), "Rules-Debug")
+	assert.Contains(t, buf.String(), `"read allowed"`)
+}
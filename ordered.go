@@ -0,0 +1,100 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// KeyValue is a child of a location, as returned by ValueOrdered.
+type KeyValue struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// ValueOrdered gets the children of the Firebase reference in the order of
+// its query, which decoding into a map would lose: by the child, value or
+// priority the query was made with OrderBy, and by key otherwise. Firebase
+// does not sort the children of a response, they are sorted client-side
+// like Firebase orders them: null first, then false, true, numbers,
+// strings and objects, with ties broken by key. The children are sorted
+// after the codecs and transforms of the reference are applied. It returns
+// no children if the location does not hold an object.
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-ordered-data
+func (fb *Firebase) ValueOrdered(opts ...RequestOption) ([]KeyValue, error) {
+	return fb.ValueOrderedContext(context.Background(), opts...)
+}
+
+// ValueOrderedContext is like ValueOrdered but the request is canceled
+// when ctx is done.
+func (fb *Firebase) ValueOrderedContext(ctx context.Context, opts ...RequestOption) ([]KeyValue, error) {
+	ref, strip := fb.withPriorities()
+	raw, err := ref.ValueRawContext(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var children map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &children); err != nil || children == nil {
+		// not an object
+		return nil, nil
+	}
+	kvs, err := sortChildren(fb.ordering(), children)
+	if err != nil || !strip {
+		return kvs, err
+	}
+	for i := range kvs {
+		if kvs[i].Value, err = stripRawPriorities(kvs[i].Value); err != nil {
+			return nil, err
+		}
+	}
+	return kvs, nil
+}
+
+// withPriorities returns the reference to read the children of a query
+// ordered by priority from, which are only sent with the export format,
+// and whether the priorities have to be stripped from them.
+func (fb *Firebase) withPriorities() (*Firebase, bool) {
+	if fb.ordering() != "$priority" || fb.queryParams().Get(formatParam) != "" {
+		return fb, false
+	}
+	c := fb.copy()
+	c.params.Set(formatParam, formatVal)
+	return c, true
+}
+
+// stripRawPriorities removes the metadata of the export format from the
+// JSON value raw.
+func stripRawPriorities(raw json.RawMessage) (json.RawMessage, error) {
+	v, err := decodeNumbers(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(stripPriorities(v))
+}
+
+// sortChildren returns children sorted by orderBy, see ValueOrdered.
+func sortChildren(orderBy string, children map[string]json.RawMessage) ([]KeyValue, error) {
+	o := byOrder{keys: make([]string, 0, len(children)), orderBy: orderBy}
+	if orderBy != "$key" {
+		o.children = make(map[string]interface{}, len(children))
+	}
+	for k, v := range children {
+		o.keys = append(o.keys, k)
+		if o.children == nil {
+			continue
+		}
+		var child interface{}
+		if err := json.Unmarshal(v, &child); err != nil {
+			return nil, err
+		}
+		o.children[k] = child
+	}
+	sort.Sort(o)
+
+	kvs := make([]KeyValue, len(o.keys))
+	for i, k := range o.keys {
+		kvs[i] = KeyValue{Key: k, Value: children[k]}
+	}
+	return kvs, nil
+}
@@ -0,0 +1,96 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueOrdered(t *testing.T) {
+	t.Parallel()
+	body := `{"c": {"age": 1}, "a": {"age": 2}, "b": {"age": 3, "tags": {"z": 1, "y": 2}}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	kvs, err := fb.OrderByChild("age").ValueOrdered()
+	require.NoError(t, err)
+	require.Len(t, kvs, 3)
+	assert.Equal(t, "c", kvs[0].Key)
+	assert.JSONEq(t, `{"age": 1}`, string(kvs[0].Value))
+	assert.Equal(t, "a", kvs[1].Key)
+	assert.Equal(t, "b", kvs[2].Key)
+
+	// transforms keep the order
+	kvs, err = fb.OrderByChild("age").Transform("a", func(interface{}) interface{} { return nil }).ValueOrdered()
+	require.NoError(t, err)
+	keys := make([]string, len(kvs))
+	for i, kv := range kvs {
+		keys[i] = kv.Key
+	}
+	assert.Equal(t, []string{"c", "b"}, keys)
+}
+
+func TestValueOrderedSortsClientSide(t *testing.T) {
+	t.Parallel()
+	// Firebase does not sort the children of filtered responses
+	body := `{"n": null, "s2": "b", "f": false, "obj": {"x": 1}, "t": true, "10": 10, "2": 2, "s1": "a", "tie": 2}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	keys := func(ref *Firebase) []string {
+		kvs, err := ref.ValueOrdered()
+		require.NoError(t, err)
+		keys := make([]string, len(kvs))
+		for i, kv := range kvs {
+			keys[i] = kv.Key
+		}
+		return keys
+	}
+	assert.Equal(t, []string{"n", "f", "t", "2", "tie", "10", "s1", "s2", "obj"}, keys(fb.OrderByValue().StartAt("a")))
+	assert.Equal(t, []string{"2", "10", "f", "n", "obj", "s1", "s2", "t", "tie"}, keys(fb))
+	assert.Equal(t, []string{"2", "10", "f", "n", "obj", "s1", "s2", "t", "tie"}, keys(fb.OrderByKey().LimitToLast(9)))
+	assert.Equal(t, []string{"2", "10", "f", "n", "s1", "s2", "t", "tie", "obj"}, keys(fb.OrderByChild("x")))
+}
+
+func TestValueOrderedByPriority(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get(formatParam) != formatVal {
+			w.Write([]byte(`{"a": 1, "b": {"x": 2}, "c": 3}`))
+			return
+		}
+		w.Write([]byte(`{"a": {".value": 1, ".priority": 3}, "b": {"x": 2, ".priority": 1}, "c": {".value": 3, ".priority": 2}}`))
+	}))
+	defer server.Close()
+
+	kvs, err := New(server.URL, WithHTTPClient(&http.Client{})).OrderByPriority().ValueOrdered()
+	require.NoError(t, err)
+	require.Len(t, kvs, 3)
+	assert.Equal(t, "b", kvs[0].Key)
+	assert.JSONEq(t, `{"x": 2}`, string(kvs[0].Value))
+	assert.Equal(t, "c", kvs[1].Key)
+	assert.JSONEq(t, `3`, string(kvs[1].Value))
+	assert.Equal(t, "a", kvs[2].Key)
+}
+
+func TestValueOrderedNotObject(t *testing.T) {
+	t.Parallel()
+	for _, body := range []string{`null`, `"a"`, `[1,2]`, `3`} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(body))
+		}))
+		kvs, err := New(server.URL, WithHTTPClient(&http.Client{})).ValueOrdered()
+		assert.NoError(t, err, body)
+		assert.Empty(t, kvs, body)
+		server.Close()
+	}
+}
@@ -0,0 +1,300 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// PageIterator walks the children of a location page by page, in the order
+// of its query, see Pages.
+type PageIterator struct {
+	pager *pager
+	page  []KeyValue
+	err   error
+	done  bool
+}
+
+// Pages returns an iterator over the children of the Firebase reference,
+// fetching pageSize children per request, so that locations with millions
+// of children can be walked without loading them all at once. A pageSize
+// of 0 or less defaults to 1000.
+//
+// The children are returned in the order of the query of the reference,
+// by key if it is not ordered, and only the ones matching its filters and
+// limit are returned. Queries limited with LimitToLast or made Shallow
+// cannot be paged, Err then returns an error wrapping ErrInvalidQuery.
+//
+//	pages := ref.OrderByChild("age").StartAtValue(18).Pages(100)
+//	for pages.Next() {
+//		for _, kv := range pages.Page() {
+//			...
+//		}
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+func (fb *Firebase) Pages(pageSize int) *PageIterator {
+	if pageSize <= 0 {
+		pageSize = defaultExportPageSize
+	}
+	p, err := fb.pager(pageSize, false)
+	return &PageIterator{pager: p, err: err, done: err != nil}
+}
+
+// Next fetches the next page, it returns false once all the children were
+// returned or a request failed, see Err.
+func (p *PageIterator) Next() bool {
+	return p.NextContext(context.Background())
+}
+
+// NextContext is like Next but the request is canceled when ctx is done.
+func (p *PageIterator) NextContext(ctx context.Context) bool {
+	p.page = nil
+	if p.done {
+		return false
+	}
+	kvs, err := p.pager.next(ctx)
+	if err != nil {
+		p.err, p.done = err, true
+		return false
+	}
+	if len(kvs) == 0 {
+		p.done = true
+		return false
+	}
+	p.page = kvs
+	return true
+}
+
+// Page returns the children fetched by the last call to Next, in the order
+// of the query.
+func (p *PageIterator) Page() []KeyValue {
+	return p.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *PageIterator) Err() error {
+	return p.err
+}
+
+// pager fetches the children of a query page by page, in the order of the
+// query. The REST API only supports the value part of a cursor, so every
+// page starts at the ordering value of the last child of the previous page
+// and the children sharing that value which were returned already are
+// requested again and dropped.
+type pager struct {
+	ref      *Firebase
+	orderBy  string
+	pageSize int
+	// raw pagers return the children as stored, without applying the
+	// codecs and transforms of the reference.
+	raw bool
+	// strip removes the priorities requested to order the children by.
+	strip bool
+	// cursor points to the last child returned, or is the start cursor of
+	// the query before the first page.
+	cursor *keyCursor
+	endKey *keyCursor
+	// skip is the number of children sharing the value of the cursor which
+	// sort before it or are the child it points to.
+	skip int
+	// remaining is the number of children left within the limit of the
+	// query, -1 if it is not limited.
+	remaining int
+	done      bool
+}
+
+// pager returns a pager over the children of the query of the reference,
+// fetching pageSize children per request. The query must not be limited
+// with LimitToLast nor be shallow.
+func (fb *Firebase) pager(pageSize int, raw bool) (*pager, error) {
+	params := fb.queryParams()
+	for _, p := range []string{limitToLastParam, shallowParam} {
+		if params.Get(p) != "" {
+			return nil, queryError("children are paged from the start of the query, " + p + " cannot be set")
+		}
+	}
+	if err := fb.validateQuery(); err != nil {
+		return nil, err
+	}
+	remaining := -1
+	if limit := params.Get(limitToFirstParam); limit != "" {
+		remaining, _ = strconv.Atoi(limit)
+	}
+
+	ref, strip := fb.withPriorities()
+	ref = ref.copy()
+	ref.startKey, ref.endKey = nil, nil
+	ref.params.Del(limitToFirstParam)
+	ref.params.Set(orderByParam, quote(fb.ordering()))
+	if v := params.Get(equalToParam); v != "" {
+		// the pages need a start cursor of their own
+		ref.params.Del(equalToParam)
+		ref.params.Set(startAtParam, v)
+		ref.params.Set(endAtParam, v)
+	}
+	return &pager{
+		ref:       ref,
+		orderBy:   fb.ordering(),
+		pageSize:  pageSize,
+		raw:       raw,
+		strip:     strip,
+		cursor:    fb.startKey,
+		endKey:    fb.endKey,
+		remaining: remaining,
+	}, nil
+}
+
+// startAfterKey makes the pager of a query ordered by key start after the
+// child with the given key.
+func (p *pager) startAfterKey(key string) {
+	p.cursor = &keyCursor{value: quote(key), key: key, exclusive: true}
+	p.skip = 1
+}
+
+// next returns the next page, no children once all of them were returned.
+func (p *pager) next(ctx context.Context) ([]KeyValue, error) {
+	for !p.done {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		keys, children, err := p.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := p.decode(keys, children)
+		if err != nil || len(kvs) > 0 {
+			return kvs, err
+		}
+	}
+	return nil, nil
+}
+
+// fetch requests the children after the cursor and returns the keys of the
+// ones making up the next page, in order, along with the children.
+func (p *pager) fetch(ctx context.Context) ([]string, map[string]json.RawMessage, error) {
+	want := p.pageSize
+	if p.remaining >= 0 && p.remaining < want {
+		want = p.remaining
+	}
+	if want == 0 {
+		p.done = true
+		return nil, nil, nil
+	}
+
+	limit := want + p.skip
+	c := p.ref.copy()
+	if p.cursor != nil {
+		c.params.Set(startAtParam, p.cursor.value)
+	}
+	c.params.Set(limitToFirstParam, strconv.Itoa(limit))
+	body, err := c.doRequest(ctx, "GET", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		children map[string]json.RawMessage
+		stored   map[string]interface{}
+	)
+	if json.Unmarshal(body, &children) != nil || json.Unmarshal(body, &stored) != nil {
+		// not an object
+		p.done = true
+		return nil, nil, nil
+	}
+
+	keys := make([]string, 0, len(stored))
+	for k := range stored {
+		keys = append(keys, k)
+	}
+	sort.Sort(byOrder{keys: keys, orderBy: p.orderBy, children: stored})
+	value := func(k string) string { return jsonLiteral(orderValue(p.orderBy, k, stored[k])) }
+
+	p.done = len(keys) < limit
+	var page []string
+	last := -1
+	for i, k := range keys {
+		if p.passed(value(k), k) {
+			continue
+		}
+		if p.beyond(value(k), k) {
+			p.done = true
+			break
+		}
+		if len(page) == want {
+			p.done = false
+			break
+		}
+		page = append(page, k)
+		last = i
+	}
+	if len(page) == 0 {
+		if !p.done {
+			// a full page of children at or before the cursor, they all
+			// share its value
+			p.skip = limit
+		}
+		return nil, nil, nil
+	}
+
+	k := page[len(page)-1]
+	p.cursor = &keyCursor{value: value(k), key: k, exclusive: true}
+	p.skip = 0
+	for _, k := range keys[:last+1] {
+		if value(k) == p.cursor.value {
+			p.skip++
+		}
+	}
+	if p.remaining >= 0 {
+		p.remaining -= len(page)
+	}
+	return page, children, nil
+}
+
+// passed reports whether the child with the given key and ordering value
+// is at or before the cursor.
+func (p *pager) passed(value, key string) bool {
+	c := p.cursor
+	return c != nil && value == c.value && byKey{key, c.key}.Less(0, 1) || c.excludes(value, key)
+}
+
+// beyond reports whether the child with the given key and ordering value
+// is after the end cursor of the query.
+func (p *pager) beyond(value, key string) bool {
+	c := p.endKey
+	return c != nil && value == c.value && byKey{c.key, key}.Less(0, 1) || c.excludes(value, key)
+}
+
+// decode returns the children with the given keys, with the codecs and
+// transforms of the reference applied unless the pager is raw. Children
+// the codecs or transforms remove are left out.
+func (p *pager) decode(keys []string, children map[string]json.RawMessage) ([]KeyValue, error) {
+	kvs := make([]KeyValue, 0, len(keys))
+	for _, k := range keys {
+		raw := children[k]
+		var err error
+		if p.strip {
+			if raw, err = stripRawPriorities(raw); err != nil {
+				return nil, err
+			}
+		}
+		if !p.raw && (len(p.ref.codecs) > 0 || len(p.ref.transforms) > 0) {
+			v, err := decodeNumbers(raw)
+			if err != nil {
+				return nil, err
+			}
+			if v, err = p.ref.decodeValue([]string{k}, v); err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			if raw, err = json.Marshal(v); err != nil {
+				return nil, err
+			}
+		}
+		kvs = append(kvs, KeyValue{Key: k, Value: raw})
+	}
+	return kvs, nil
+}
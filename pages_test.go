@@ -0,0 +1,112 @@
+package firego
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPages(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	for i := 0; i < 25; i++ {
+		data[fmt.Sprintf("k%02d", i)] = float64(i)
+	}
+	data["7"] = "numeric keys come first"
+	server, requests := newPagingServer(t, data)
+	defer server.Close()
+
+	pages := New(server.URL, WithHTTPClient(&http.Client{})).Pages(10)
+	var (
+		keys  []string
+		sizes []int
+	)
+	for pages.Next() {
+		sizes = append(sizes, len(pages.Page()))
+		for _, kv := range pages.Page() {
+			keys = append(keys, kv.Key)
+		}
+	}
+	require.NoError(t, pages.Err())
+	assert.Equal(t, sortedKeys(data), keys)
+	assert.Equal(t, []int{10, 10, 6}, sizes)
+	assert.Equal(t, 3, *requests)
+	assert.False(t, pages.Next())
+}
+
+func TestPagesExactMultiple(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"a": 1.0, "b": 2.0, "c": 3.0, "d": 4.0}
+	server, _ := newPagingServer(t, data)
+	defer server.Close()
+
+	pages := New(server.URL, WithHTTPClient(&http.Client{})).Pages(2)
+	var n int
+	for pages.Next() {
+		n++
+		assert.Len(t, pages.Page(), 2)
+	}
+	require.NoError(t, pages.Err())
+	assert.Equal(t, 2, n)
+}
+
+func TestPagesQuery(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	for i := 0; i < 12; i++ {
+		// ties at every boundary of the pages of 3
+		data[fmt.Sprintf("u%02d", i)] = map[string]interface{}{"age": float64(20 + i/5)}
+	}
+	data["other"] = map[string]interface{}{"name": "no age"}
+	server, _ := newPagingServer(t, data)
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).OrderByChild("age")
+
+	for _, tt := range []struct {
+		ref  *Firebase
+		keys []string
+	}{
+		{fb, []string{"other", "u00", "u01", "u02", "u03", "u04", "u05", "u06", "u07", "u08", "u09", "u10", "u11"}},
+		{fb.EqualToValue(21), []string{"u05", "u06", "u07", "u08", "u09"}},
+		{fb.StartAfter(20, "u02").LimitToFirst(4), []string{"u03", "u04", "u05", "u06"}},
+		{fb.StartAtValue(21).EndBefore(21, "u08"), []string{"u05", "u06", "u07"}},
+	} {
+		pages := tt.ref.Pages(3)
+		var keys []string
+		for pages.Next() {
+			assert.True(t, len(pages.Page()) <= 3)
+			for _, kv := range pages.Page() {
+				keys = append(keys, kv.Key)
+			}
+		}
+		require.NoError(t, pages.Err())
+		assert.Equal(t, tt.keys, keys)
+	}
+
+	pages := fb.LimitToLast(2).Pages(3)
+	assert.False(t, pages.Next())
+	assert.Equal(t, ErrInvalidQuery, pages.Err().(queryError).Unwrap())
+}
+
+func TestPagesError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	pages := New(server.URL, WithHTTPClient(&http.Client{})).Pages(10)
+	assert.False(t, pages.Next())
+	assert.Error(t, pages.Err())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pages = New(server.URL, WithHTTPClient(&http.Client{})).Pages(10)
+	assert.False(t, pages.NextContext(ctx))
+	assert.Error(t, pages.Err())
+}
@@ -0,0 +1,100 @@
+package firego
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Patch builds the value of a multi-path Update, making explicit which
+// paths are set and which are deleted. Paths that are not part of the
+// Patch are left unchanged.
+//
+// Reference https://www.firebase.com/docs/web/guide/saving-data.html#section-update
+type Patch map[string]interface{}
+
+// Set adds a write of v to path.
+func (p Patch) Set(path string, v interface{}) Patch {
+	p[strings.Trim(path, "/")] = v
+	return p
+}
+
+// Delete adds a deletion of path. Firebase deletes keys that are set to
+// null.
+func (p Patch) Delete(path string) Patch {
+	p[strings.Trim(path, "/")] = nil
+	return p
+}
+
+// optional is implemented by Optional values.
+type optional interface {
+	// optionalValue returns the value to write and whether the
+	// value should be written at all.
+	optionalValue() (interface{}, bool)
+}
+
+// UpdateFrom builds a Patch from the exported fields of the struct v,
+// keyed by their JSON names. Fields holding an Optional are only included
+// when they are set, to a value or to null. Other fields are always
+// included unless they are tagged omitempty and hold their zero value.
+func UpdateFrom(v interface{}) (Patch, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("UpdateFrom requires a struct")
+	}
+
+	p := Patch{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, opts := f.Name, ""
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if idx := strings.Index(tag, ","); idx >= 0 {
+				tag, opts = tag[:idx], tag[idx:]
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+
+		fv := rv.Field(i)
+		if o, ok := fv.Interface().(optional); ok {
+			if val, present := o.optionalValue(); present {
+				p[name] = val
+			}
+			continue
+		}
+		if strings.Contains(opts, ",omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		p[name] = fv.Interface()
+	}
+	return p, nil
+}
+
+// isEmptyValue reports whether v is empty according to the rules that
+// encoding/json uses for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package firego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/u1", map[string]interface{}{"name": "ann", "nick": "a", "age": 30})
+	fb := New(server.URL)
+
+	p := Patch{}.Set("/users/u1/name", "bob").Delete("users/u1/nick/")
+	assert.Equal(t, Patch{"users/u1/name": "bob", "users/u1/nick": nil}, p)
+
+	require.NoError(t, fb.Update(p))
+	assert.Equal(t, map[string]interface{}{"name": "bob", "age": float64(30)}, server.Get("users/u1"))
+}
+
+func TestUpdateFrom(t *testing.T) {
+	t.Parallel()
+	type user struct {
+		Name    string `json:"name"`
+		Nick    string `json:"nick,omitempty"`
+		Age     int
+		Ignored string `json:"-"`
+		private string
+	}
+
+	p, err := UpdateFrom(&user{Name: "ann", Age: 3, Ignored: "x", private: "y"})
+	require.NoError(t, err)
+	assert.Equal(t, Patch{"name": "ann", "Age": 3}, p)
+
+	_, err = UpdateFrom("not a struct")
+	assert.Error(t, err)
+}
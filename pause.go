@@ -0,0 +1,238 @@
+package firego
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Pauser pauses and resumes the watch it is given to, see WatchPausable,
+// e.g. while its consumer cannot keep up. A Pauser controls a single watch
+// and is safe for concurrent use.
+type Pauser struct {
+	// DropConnection closes the connection of the watch while it is
+	// paused and establishes a new one when it is resumed. By default
+	// the connection is kept and the events received while paused are
+	// applied to a copy of the value of the location, which the watch
+	// then holds on to for as long as it runs.
+	DropConnection bool
+
+	mtx     sync.Mutex
+	paused  bool
+	changed chan struct{}
+}
+
+// Pause stops the watch from sending events until Resume is called. The
+// event being sent, if any, is dropped.
+func (p *Pauser) Pause() {
+	p.set(true)
+}
+
+// Resume makes the paused watch send events again, starting with an
+// EventTypeResynced event of the value of the location.
+func (p *Pauser) Resume() {
+	p.set(false)
+}
+
+// Paused reports whether the watch is paused.
+func (p *Pauser) Paused() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.paused
+}
+
+func (p *Pauser) set(paused bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.paused = paused
+	select {
+	case p.signal() <- struct{}{}:
+	default:
+	}
+}
+
+// signal returns the channel that is sent to when the watch is paused or
+// resumed, the caller holds mtx.
+func (p *Pauser) signal() chan struct{} {
+	if p.changed == nil {
+		p.changed = make(chan struct{}, 1)
+	}
+	return p.changed
+}
+
+// signaled returns the channel that is sent to when the watch is paused
+// or resumed.
+func (p *Pauser) signaled() <-chan struct{} {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.signal()
+}
+
+// WatchPausable is like WatchContext but the watch can be paused and
+// resumed with p. While paused, events are not sent to notifications and,
+// depending on p, the connection is closed. Once resumed, the watch sends
+// the value of the location as an EventTypeResynced event, in place of
+// the events it did not send.
+//
+// The watch ends, and notifications is closed, like the one of
+// WatchContext, but the errors of a watch that is paused are not sent,
+// WatchErr tells why it ended. A connection that fails to be
+// re-established when the watch is resumed ends it too.
+func (fb *Firebase) WatchPausable(ctx context.Context, notifications chan Event, p *Pauser, opts ...RequestOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w, err := fb.startWatching(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn := fb.copy()
+	events := make(chan Event)
+	if err := conn.WatchContext(w.ctx, events, opts...); err != nil {
+		fb.stoppedWatching(w, err)
+		return err
+	}
+
+	pw := &pausedWatch{fb: fb, w: w, p: p, opts: opts, conn: conn, events: events, notifications: notifications}
+	go func() {
+		err := pw.run()
+		fb.stoppedWatching(w, err)
+		close(notifications)
+	}()
+	return nil
+}
+
+// pausedWatch is the state of a WatchPausable.
+type pausedWatch struct {
+	fb   *Firebase
+	w    *watch
+	p    *Pauser
+	opts []RequestOption
+
+	// conn is the reference the connection is established on, events
+	// is nil while it is dropped
+	conn          *Firebase
+	events        chan Event
+	notifications chan Event
+
+	paused bool
+	// resync is set once a new connection is established, until its
+	// initial put event
+	resync bool
+	// value of the location, unless the connection is dropped on pause
+	value interface{}
+}
+
+// run sends the events of the watch to notifications until it ends, it
+// returns why.
+func (pw *pausedWatch) run() error {
+	defer pw.drop()
+	for {
+		select {
+		case event, ok := <-pw.events:
+			if !ok {
+				if pw.w.ctx.Err() != nil {
+					return pw.w.err()
+				}
+				return pw.conn.WatchErr()
+			}
+			if !pw.p.DropConnection {
+				pw.value, _ = withEvent(pw.value, event)
+			}
+			if pw.paused {
+				continue
+			}
+			if pw.resync && event.Type == EventTypePut {
+				event.Type, pw.resync = EventTypeResynced, false
+			}
+			if err := pw.send(event); err != nil {
+				return err
+			}
+		case <-pw.p.signaled():
+			if err := pw.update(); err != nil {
+				return err
+			}
+		case <-pw.w.ctx.Done():
+			return pw.w.err()
+		}
+	}
+}
+
+// send sends event to notifications, unless the watch is paused first.
+func (pw *pausedWatch) send(event Event) error {
+	// a pause that is already requested comes first
+	select {
+	case <-pw.p.signaled():
+		if changed, err := pw.changed(); changed {
+			return err
+		}
+	default:
+	}
+	for {
+		select {
+		case pw.notifications <- event:
+			return nil
+		case <-pw.p.signaled():
+			if changed, err := pw.changed(); changed {
+				return err
+			}
+		case <-pw.w.ctx.Done():
+			return pw.w.err()
+		}
+	}
+}
+
+// changed updates the watch like update, it reports whether the watch was
+// paused or resumed, or failed to.
+func (pw *pausedWatch) changed() (bool, error) {
+	paused := pw.paused
+	err := pw.update()
+	return err != nil || pw.paused != paused, err
+}
+
+// update pauses or resumes the watch to match the Pauser.
+func (pw *pausedWatch) update() error {
+	paused := pw.p.Paused()
+	if paused == pw.paused {
+		return nil
+	}
+	pw.paused = paused
+	if pw.p.DropConnection {
+		if paused {
+			pw.drop()
+			return nil
+		}
+		pw.conn, pw.events, pw.resync = pw.fb.copy(), make(chan Event), true
+		if err := pw.conn.WatchContext(pw.w.ctx, pw.events, pw.opts...); err != nil {
+			pw.events = nil
+			if pw.w.ctx.Err() != nil {
+				return pw.w.err()
+			}
+			pw.send(Event{Type: EventTypeError, Data: err})
+			return err
+		}
+		return nil
+	}
+	if paused {
+		return nil
+	}
+
+	raw, err := json.Marshal(pw.value)
+	if err != nil {
+		return err
+	}
+	return pw.send(Event{Type: EventTypeResynced, Path: "/", Data: pw.value, Raw: raw})
+}
+
+// drop tears down the connection and waits for it to end.
+func (pw *pausedWatch) drop() {
+	if pw.events == nil {
+		return
+	}
+	pw.conn.StopWatching()
+	for range pw.events {
+		// wait for the connection to be torn down
+	}
+	pw.events = nil
+}
@@ -0,0 +1,86 @@
+package firego
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestWatchPausable(t *testing.T) {
+	t.Parallel()
+	for _, drop := range []bool{false, true} {
+		server := firetest.New()
+		server.Start()
+		defer server.Close()
+
+		server.Set("users/a", 1)
+		fb := New(server.URL + "/users")
+		p := &Pauser{DropConnection: drop}
+		notifications := make(chan Event)
+		require.NoError(t, fb.WatchPausable(context.Background(), notifications, p))
+
+		event := <-notifications
+		assert.Equal(t, EventTypePut, event.Type)
+		assert.Equal(t, map[string]interface{}{"a": 1.0}, event.Data)
+
+		p.Pause()
+		assert.True(t, p.Paused())
+		server.Set("users/b", 2)
+		server.Set("users/c", 3)
+		select {
+		case event := <-notifications:
+			t.Fatalf("paused watch sent %v", event)
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.True(t, fb.isWatching())
+
+		p.Resume()
+		event = <-notifications
+		assert.Equal(t, EventTypeResynced, event.Type, "drop %v", drop)
+		assert.Equal(t, "/", event.Path)
+		assert.Equal(t, map[string]interface{}{"a": 1.0, "b": 2.0, "c": 3.0}, event.Data)
+		var v map[string]int
+		require.NoError(t, event.Value(&v))
+		assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 3}, v)
+
+		server.Set("users/d", 4)
+		event = <-notifications
+		assert.Equal(t, EventTypePut, event.Type)
+		assert.Equal(t, "/d", event.Path)
+
+		fb.StopWatching()
+		for range notifications {
+		}
+		assert.NoError(t, fb.WatchErr())
+		assert.False(t, fb.isWatching())
+	}
+}
+
+func TestWatchPausableContext(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pauser{DropConnection: true}
+	p.Pause()
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchPausable(ctx, notifications, p))
+
+	// the initial value is not sent while paused
+	select {
+	case event := <-notifications:
+		t.Fatalf("paused watch sent %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	_, ok := <-notifications
+	assert.False(t, ok)
+	assert.Equal(t, context.Canceled, fb.WatchErr())
+}
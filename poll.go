@@ -0,0 +1,180 @@
+package firego
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// ifNoneMatchHeader makes Firebase answer a read with 304 Not Modified if
+// the ETag of the value did not change.
+const ifNoneMatchHeader = "If-None-Match"
+
+// WithPolling makes the watch read the location every interval instead of
+// holding a streaming connection, for networks whose proxies drop
+// long-lived connections or buffer server-sent events. The reads ask for
+// the ETag of the value and are conditional, so an unchanged value is not
+// sent again, and the changes are diffed locally: a put event of the whole
+// value is sent first, then a patch event at "/" of the children that
+// changed, or a put event if the value is not an object, and a keep-alive
+// event when nothing changed.
+//
+// Changes made in between two reads are coalesced and a read that fails
+// ends the watch, like a dropped connection, with an EventTypeError event.
+func WithPolling(interval time.Duration) RequestOption {
+	return func(r *request) {
+		r.pollInterval = interval
+	}
+}
+
+// watchPolling runs the watch w of conn by polling, see WithPolling. It
+// fails like WatchContext if the first read does.
+func (fb *Firebase) watchPolling(w *watch, conn *Firebase, r *request, notifications chan Event) error {
+	raw, etag, err := conn.poll(w, r, "")
+	if err != nil {
+		if w.ctx.Err() != nil {
+			// torn down before the value was read
+			err = w.err()
+		}
+		fb.stoppedWatching(w, err)
+		if err == nil {
+			close(notifications)
+		}
+		return err
+	}
+	r.monitor.established()
+
+	events, end := fb.pipe(w, notifications, r)
+	go func() {
+		end(conn.polling(w, r, events, raw, etag))
+	}()
+	return nil
+}
+
+// polling sends the events of the value read every poll interval to
+// events, starting with raw, until the watch ends. It returns why.
+func (fb *Firebase) polling(w *watch, r *request, events chan Event, raw []byte, etag string) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fb.pollFailed(w, events, err)
+	}
+	// pointers since json.RawMessage only marshals through a pointer
+	// before Go 1.8
+	msg := json.RawMessage(raw)
+	typ, data := EventTypePut, interface{}(&msg)
+	for {
+		event, err := fb.polledEvent(typ, data)
+		if err != nil {
+			return fb.pollFailed(w, events, err)
+		}
+		r.monitor.received(event.Type)
+		select {
+		case events <- event:
+		case <-w.ctx.Done():
+			return w.err()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.ctx.Done():
+			return w.err()
+		}
+		if raw, etag, err = fb.poll(w, r, etag); err != nil {
+			return fb.pollFailed(w, events, err)
+		}
+		if typ, data, value, err = changes(value, raw); err != nil {
+			return fb.pollFailed(w, events, err)
+		}
+	}
+}
+
+// changes returns the type and data of the event that turns value into
+// the value encoded in raw, a keep-alive event if raw is nil, and the new
+// value.
+func changes(value interface{}, raw []byte) (string, interface{}, interface{}, error) {
+	if raw == nil {
+		return EventTypeKeepAlive, nil, value, nil
+	}
+	var next interface{}
+	if err := json.Unmarshal(raw, &next); err != nil {
+		return "", nil, value, err
+	}
+	if reflect.DeepEqual(value, next) {
+		return EventTypeKeepAlive, nil, value, nil
+	}
+	patch, err := Diff(value, next)
+	if err != nil || next == nil {
+		msg := json.RawMessage(raw)
+		return EventTypePut, &msg, next, nil
+	}
+	return EventTypePatch, patch, next, nil
+}
+
+// polledEvent builds the event of type typ at "/" the way newEvent does
+// for the events Firebase sends.
+func (fb *Firebase) polledEvent(typ string, data interface{}) (Event, error) {
+	if typ == EventTypeKeepAlive {
+		return fb.newEvent(typ, []byte("null"))
+	}
+	payload, err := json.Marshal(struct {
+		Path string      `json:"path"`
+		Data interface{} `json:"data"`
+	}{"/", data})
+	if err != nil {
+		return Event{}, err
+	}
+	return fb.newEvent(typ, payload)
+}
+
+// pollFailed sends err as an EventTypeError event, unless the watch was
+// torn down, and returns why the watch ended.
+func (fb *Firebase) pollFailed(w *watch, events chan Event, err error) error {
+	if w.ctx.Err() != nil {
+		return w.err()
+	}
+	select {
+	case events <- Event{Type: EventTypeError, Data: err}:
+	case <-w.ctx.Done():
+		return w.err()
+	}
+	return err
+}
+
+// poll reads the value of the location, it returns nil if its ETag is
+// still etag.
+func (fb *Firebase) poll(w *watch, r *request, etag string) ([]byte, string, error) {
+	header := http.Header{}
+	for k, v := range r.header {
+		header[k] = v
+	}
+	header.Set(etagHeader, "true")
+	if etag != "" {
+		header.Set(ifNoneMatchHeader, etag)
+	}
+
+	resp, err := fb.send(w.ctx, "GET", nil, header)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(r.monitor.reader(resp.Body))
+	if err != nil {
+		return nil, "", fb.closedErr(w.ctx, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, etag, nil
+	case resp.StatusCode/200 != 1:
+		return nil, "", fb.newError("GET", resp.StatusCode, b)
+	}
+	next := resp.Header.Get("ETag")
+	if next != "" && next == etag {
+		return nil, etag, nil
+	}
+	return b, next, nil
+}
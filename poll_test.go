@@ -0,0 +1,159 @@
+package firego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestWatchPolling(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx         sync.Mutex
+		value       = `{"a":1,"b":{"c":2}}`
+		version     = 1
+		notModified int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Equal(t, "true", req.Header.Get("X-Firebase-ETag"))
+		etag := string(rune('0' + version))
+		if req.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(value))
+	}))
+	defer server.Close()
+	set := func(v string) {
+		mtx.Lock()
+		value = v
+		version++
+		mtx.Unlock()
+	}
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications, WithPolling(10*time.Millisecond)))
+
+	event := <-notifications
+	assert.Equal(t, EventTypePut, event.Type)
+	assert.Equal(t, "/", event.Path)
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}}, event.Data)
+
+	event = <-notifications
+	assert.Equal(t, EventTypeKeepAlive, event.Type)
+
+	set(`{"a":1,"b":{"c":3},"d":4}`)
+	for event.Type == EventTypeKeepAlive {
+		event = <-notifications
+	}
+	assert.Equal(t, EventTypePatch, event.Type)
+	assert.Equal(t, "/", event.Path)
+	assert.Equal(t, map[string]interface{}{"b/c": 3.0, "d": 4.0}, event.Data)
+
+	set(`"gone"`)
+	event = <-notifications
+	for event.Type == EventTypeKeepAlive {
+		event = <-notifications
+	}
+	assert.Equal(t, EventTypePut, event.Type)
+	assert.Equal(t, "gone", event.Data)
+
+	fb.StopWatching()
+	for range notifications {
+	}
+	assert.NoError(t, fb.WatchErr())
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.NotZero(t, notModified)
+}
+
+func TestWatchPollingDiffs(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", 1)
+	fb := New(server.URL + "/users")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchContext(ctx, notifications, WithPolling(10*time.Millisecond)))
+
+	var value interface{}
+	next := func() Event {
+		for event := range notifications {
+			if event.Type != EventTypeKeepAlive {
+				require.NoError(t, ApplyEvent(&value, event))
+				return event
+			}
+		}
+		t.Fatal("watch ended")
+		return Event{}
+	}
+	next()
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, value)
+
+	server.Set("users/b", 2)
+	server.Delete("users/a")
+	// the changes are coalesced if they are made in between two reads
+	for i := 0; i < 2 && value.(map[string]interface{})["a"] != nil; i++ {
+		assert.Equal(t, EventTypePatch, next().Type)
+	}
+	assert.Equal(t, map[string]interface{}{"b": 2.0}, value)
+
+	cancel()
+	for range notifications {
+	}
+}
+
+func TestWatchPollingError(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx      sync.Mutex
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		requests++
+		n := requests
+		mtx.Unlock()
+		if n == 1 {
+			w.Write([]byte("1"))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"Permission denied"}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications, WithPolling(time.Millisecond)))
+	assert.Equal(t, 1.0, (<-notifications).Data)
+
+	event := <-notifications
+	assert.Equal(t, EventTypeError, event.Type)
+	require.IsType(t, (*Error)(nil), event.Data)
+	_, ok := <-notifications
+	assert.False(t, ok)
+	require.IsType(t, (*Error)(nil), fb.WatchErr())
+	assert.Equal(t, http.StatusUnauthorized, fb.WatchErr().(*Error).StatusCode)
+
+	// the first read fails like Watch
+	fb = New(server.URL, WithHTTPClient(&http.Client{}))
+	assert.Error(t, fb.Watch(make(chan Event), WithPolling(time.Millisecond)))
+	assert.False(t, fb.isWatching())
+}
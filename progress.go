@@ -0,0 +1,83 @@
+package firego
+
+import (
+	"strings"
+	"time"
+)
+
+// Progress receives updates from long running bulk operations such as
+// Export, Import and DeleteLarge, e.g. to render a progress bar.
+type Progress interface {
+	Report(u ProgressUpdate)
+}
+
+// ProgressFunc is an adapter to allow the use of ordinary functions as
+// Progress.
+type ProgressFunc func(u ProgressUpdate)
+
+// Report calls f(u).
+func (f ProgressFunc) Report(u ProgressUpdate) {
+	f(u)
+}
+
+// ProgressUpdate describes how far a bulk operation has come.
+type ProgressUpdate struct {
+	// Op names the operation, e.g. "export".
+	Op string
+	// Path of the location being worked on.
+	Path string
+	// Done is the number of children processed so far.
+	Done int64
+	// Total is the number of children to process, or -1 if unknown.
+	Total int64
+	// Bytes is the number of bytes transferred so far.
+	Bytes int64
+	// LastKey is the key of the last child processed.
+	LastKey string
+	// Elapsed is the time since the operation started.
+	Elapsed time.Duration
+}
+
+// ETA estimates the time left from the rate at which children were
+// processed so far, it returns zero if the total is unknown.
+func (u ProgressUpdate) ETA() time.Duration {
+	if u.Total < 0 || u.Done <= 0 {
+		return 0
+	}
+	left := u.Total - u.Done
+	if left <= 0 {
+		return 0
+	}
+	return time.Duration(float64(u.Elapsed) / float64(u.Done) * float64(left))
+}
+
+// tracker fills in the ProgressUpdates of a single operation.
+type tracker struct {
+	progress Progress
+	update   ProgressUpdate
+	start    time.Time
+}
+
+func (fb *Firebase) track(op string, p Progress) *tracker {
+	return &tracker{
+		progress: p,
+		update: ProgressUpdate{
+			Op:    op,
+			Path:  "/" + strings.Join(fb.segments(), "/"),
+			Total: -1,
+		},
+		start: time.Now(),
+	}
+}
+
+// report records n more children, the last one being lastKey, and sends
+// an update.
+func (t *tracker) report(n int, bytes int64, lastKey string) {
+	t.update.Done += int64(n)
+	t.update.Bytes += bytes
+	t.update.LastKey = lastKey
+	t.update.Elapsed = time.Since(t.start)
+	if t.progress != nil {
+		t.progress.Report(t.update)
+	}
+}
@@ -0,0 +1,23 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressUpdateETA(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		update   ProgressUpdate
+		expected time.Duration
+	}{
+		{ProgressUpdate{Done: 25, Total: 100, Elapsed: time.Minute}, 3 * time.Minute},
+		{ProgressUpdate{Done: 100, Total: 100, Elapsed: time.Minute}, 0},
+		{ProgressUpdate{Done: 0, Total: 100}, 0},
+		{ProgressUpdate{Done: 10, Total: -1, Elapsed: time.Minute}, 0},
+	} {
+		assert.Equal(t, tt.expected, tt.update.ETA(), "%+v", tt.update)
+	}
+}
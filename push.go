@@ -1,23 +1,137 @@
 package firego
 
-import "encoding/json"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
 
-// Push creates a reference to an auto-generated child location.
-func (fb *Firebase) Push(v interface{}) (*Firebase, error) {
-	bytes, err := json.Marshal(v)
+// ErrSilentPush is returned by Push when Firebase did not send the key of
+// the new child because the request was made with WithSilent. The value
+// was written nonetheless.
+var ErrSilentPush = errors.New("the key of a silent push is unknown")
+
+// Push creates a reference to an auto-generated child location. The child
+// reference has the same configuration as fb, see Child.
+//
+// If a codec of the reference has to know where the value is stored, see
+// LocationCodec, the key is generated like PushLocal does instead.
+func (fb *Firebase) Push(v interface{}, opts ...RequestOption) (*Firebase, error) {
+	return fb.PushContext(context.Background(), v, opts...)
+}
+
+// PushContext is like Push but the request is canceled when ctx is done.
+func (fb *Firebase) PushContext(ctx context.Context, v interface{}, opts ...RequestOption) (*Firebase, error) {
+	key, err := fb.PushKeyContext(ctx, v, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fb.Child(key), nil
+}
+
+// PushKey is like Push but returns the key of the auto-generated child
+// location instead of a reference to it.
+func (fb *Firebase) PushKey(v interface{}, opts ...RequestOption) (string, error) {
+	return fb.PushKeyContext(context.Background(), v, opts...)
+}
+
+// PushKeyContext is like PushKey but the request is canceled when ctx is
+// done.
+func (fb *Firebase) PushKeyContext(ctx context.Context, v interface{}, opts ...RequestOption) (string, error) {
+	if fb.locatesValues() {
+		key := GeneratePushID(time.Now())
+		return key, fb.Child(key).SetContext(ctx, v, opts...)
+	}
+	b, err := fb.encode(v)
+	if err != nil {
+		return "", err
+	}
+	return fb.push(ctx, bytes.NewReader(b), opts)
+}
+
+// PushReader is like Push but the JSON encoded value is read from r and
+// streamed to Firebase, see SetReader.
+func (fb *Firebase) PushReader(r io.Reader, opts ...RequestOption) (*Firebase, error) {
+	return fb.PushReaderContext(context.Background(), r, opts...)
+}
+
+// PushReaderContext is like PushReader but the request is canceled when
+// ctx is done.
+func (fb *Firebase) PushReaderContext(ctx context.Context, r io.Reader, opts ...RequestOption) (*Firebase, error) {
+	if fb.locatesValues() {
+		child := fb.Child(GeneratePushID(time.Now()))
+		if err := child.SetReaderContext(ctx, r, opts...); err != nil {
+			return nil, err
+		}
+		return child, nil
+	}
+	r, err := fb.encodeReader(r)
 	if err != nil {
 		return nil, err
 	}
-	bytes, err = fb.doRequest("POST", bytes)
+	key, err := fb.push(ctx, r, opts)
 	if err != nil {
 		return nil, err
 	}
+	return fb.Child(key), nil
+}
+
+// push posts body and returns the key Firebase generated for it.
+func (fb *Firebase) push(ctx context.Context, body io.Reader, opts []RequestOption) (string, error) {
+	b, err := fb.doRequest(ctx, "POST", body, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return "", ErrSilentPush
+	}
 	var m map[string]string
-	if err := json.Unmarshal(bytes, &m); err != nil {
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", err
+	}
+	return m["name"], nil
+}
+
+// PushLocal is like Push but generates the key of the child location with
+// GeneratePushID and writes the value with Set, so that the key is known,
+// and sorts in the order values were pushed, without waiting for
+// Firebase.
+func (fb *Firebase) PushLocal(v interface{}, opts ...RequestOption) (*Firebase, error) {
+	return fb.PushLocalContext(context.Background(), v, opts...)
+}
+
+// PushLocalContext is like PushLocal but the request is canceled when ctx
+// is done.
+func (fb *Firebase) PushLocalContext(ctx context.Context, v interface{}, opts ...RequestOption) (*Firebase, error) {
+	child := fb.Child(GeneratePushID(time.Now()))
+	if err := child.SetContext(ctx, v, opts...); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// PushMany is like PushLocal for every value of values, but writes them in
+// a single Update. It returns the generated keys in the order of values.
+func (fb *Firebase) PushMany(values []interface{}, opts ...RequestOption) ([]string, error) {
+	return fb.PushManyContext(context.Background(), values, opts...)
+}
+
+// PushManyContext is like PushMany but the request is canceled when ctx is
+// done.
+func (fb *Firebase) PushManyContext(ctx context.Context, values []interface{}, opts ...RequestOption) ([]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	keys := generatePushIDs(time.Now(), len(values))
+	update := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		update[keys[i]] = v
+	}
+	if err := fb.UpdateContext(ctx, update, opts...); err != nil {
 		return nil, err
 	}
-	return &Firebase{
-		url:    fb.url + "/" + m["name"],
-		client: fb.client,
-	}, err
+	return keys, nil
 }
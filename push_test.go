@@ -1,10 +1,14 @@
 package firego
 
 import (
+	"net/http"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zabawaba99/firetest"
 )
 
@@ -17,7 +21,7 @@ func TestPush(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 	childRef, err := fb.Push(payload)
 	assert.NoError(t, err)
 
@@ -25,3 +29,95 @@ func TestPush(t *testing.T) {
 	v := server.Get(path)
 	assert.Equal(t, payload, v)
 }
+
+func TestPushReader(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	childRef, err := fb.PushReader(strings.NewReader(`{"foo":"bar"}`))
+	assert.NoError(t, err)
+
+	path := strings.TrimPrefix(childRef.String(), server.URL+"/")
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, server.Get(path))
+}
+
+func TestPushKey(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).Child("items")
+	key, err := fb.PushKey("value")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, key)
+	assert.Equal(t, "value", server.Get("items/"+key))
+}
+
+func TestPushKeepsConfiguration(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.RequireAuth(true)
+	fb := New(server.URL, WithAuth(server.Secret)).WithHeader("X-Request-ID", "abc")
+	childRef, err := fb.Push("value")
+	require.NoError(t, err)
+	assert.Equal(t, server.Secret, childRef.params.Get(authParam))
+	assert.Equal(t, "abc", childRef.header.Get("X-Request-ID"))
+	assert.Equal(t, fb.client, childRef.client)
+
+	var v string
+	require.NoError(t, childRef.Value(&v), "the child is authenticated")
+	assert.Equal(t, "value", v)
+}
+
+func TestPushLocal(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).Child("items")
+	first, err := fb.PushLocal("first")
+	require.NoError(t, err)
+	second, err := fb.PushLocal("second")
+	require.NoError(t, err)
+
+	assert.Len(t, first.Key(), 20)
+	assert.True(t, first.Key() < second.Key())
+	assert.Equal(t, "first", server.Get("items/"+first.Key()))
+	assert.Equal(t, "second", server.Get("items/"+second.Key()))
+}
+
+func TestPushMany(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL, WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "PATCH", req.Method)
+		return http.DefaultTransport.RoundTrip(req)
+	}))).Child("items")
+
+	keys, err := fb.PushMany([]interface{}{"a", "b", map[string]interface{}{"c": true}})
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.True(t, sort.StringsAreSorted(keys))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, "a", server.Get("items/"+keys[0]))
+	assert.Equal(t, "b", server.Get("items/"+keys[1]))
+	assert.Equal(t, map[string]interface{}{"c": true}, server.Get("items/"+keys[2]))
+
+	keys, err = fb.PushMany(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
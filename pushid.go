@@ -0,0 +1,74 @@
+package firego
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// lastPushID holds the state of the previously generated push ID, so
+// that IDs generated within the same millisecond still sort in the order
+// they were generated.
+var lastPushID struct {
+	sync.Mutex
+	millis int64
+	rand   [12]byte
+}
+
+// GeneratePushID generates a key like the ones Push creates, using the
+// algorithm of the official SDKs: 8 characters encoding t in milliseconds
+// followed by 12 random characters. Keys generated by a process sort in
+// the order they were generated, keys generated with the same t by
+// different processes are unlikely to collide.
+//
+// Reference https://firebase.googleblog.com/2015/02/the-2120-ways-to-ensure-unique_68.html
+func GeneratePushID(t time.Time) string {
+	return generatePushIDs(t, 1)[0]
+}
+
+// generatePushIDs generates n push IDs for t that sort in the order they
+// are returned.
+func generatePushIDs(t time.Time, n int) []string {
+	lastPushID.Lock()
+	defer lastPushID.Unlock()
+
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = nextPushID(millis(t))
+	}
+	return ids
+}
+
+// nextPushID generates the push ID following the last one, it must be
+// called with lastPushID locked.
+func nextPushID(ms int64) string {
+	r := &lastPushID.rand
+	if ms == lastPushID.millis {
+		// increment the random characters by one
+		i := len(r) - 1
+		for ; i >= 0 && r[i] == 63; i-- {
+			r[i] = 0
+		}
+		if i >= 0 {
+			r[i]++
+		}
+	} else {
+		if _, err := rand.Read(r[:]); err != nil {
+			panic("firego: reading random bytes: " + err.Error())
+		}
+		for i := range r {
+			r[i] %= 64
+		}
+		lastPushID.millis = ms
+	}
+
+	var id [20]byte
+	for i := 7; i >= 0; i-- {
+		id[i] = pushChars[ms%64]
+		ms /= 64
+	}
+	for i, c := range r {
+		id[8+i] = pushChars[c]
+	}
+	return string(id[:])
+}
@@ -0,0 +1,50 @@
+package firego
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePushID(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1500000000, 123*int64(time.Millisecond))
+
+	id := GeneratePushID(now)
+	assert.Len(t, id, 20)
+	for _, c := range id {
+		assert.True(t, strings.ContainsRune(pushChars, c), "unexpected character %q", c)
+	}
+
+	// the first 8 characters encode the time
+	var ms int64
+	for _, c := range id[:8] {
+		ms = ms*64 + int64(strings.IndexRune(pushChars, c))
+	}
+	assert.Equal(t, millis(now), ms)
+
+	later := GeneratePushID(now.Add(time.Millisecond))
+	earlier := GeneratePushID(now.Add(-time.Hour))
+	assert.True(t, earlier < id)
+	assert.True(t, id < later)
+}
+
+// not parallel, IDs generated for other times in between would reset the
+// random characters
+func TestGeneratePushIDSameMillisecond(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = GeneratePushID(now)
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+	seen := map[string]bool{}
+	for _, id := range ids {
+		assert.False(t, seen[id], "duplicate id %s", id)
+		seen[id] = true
+	}
+}
@@ -1,8 +1,13 @@
 package firego
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf16"
 )
 
 // StartAt creates a new Firebase reference with the
@@ -16,6 +21,7 @@ import (
 // Reference https://www.firebase.com/docs/rest/guide/retrieving-data.html#section-rest-filtering
 func (fb *Firebase) StartAt(value string) *Firebase {
 	c := fb.copy()
+	c.startKey = nil
 	if value != "" {
 		c.params.Set(startAtParam, escapeString(value))
 	} else {
@@ -35,6 +41,7 @@ func (fb *Firebase) StartAt(value string) *Firebase {
 // Reference https://www.firebase.com/docs/rest/guide/retrieving-data.html#section-rest-filtering
 func (fb *Firebase) EndAt(value string) *Firebase {
 	c := fb.copy()
+	c.endKey = nil
 	if value != "" {
 		c.params.Set(endAtParam, escapeString(value))
 	} else {
@@ -43,6 +50,71 @@ func (fb *Firebase) EndAt(value string) *Firebase {
 	return c
 }
 
+// EqualTo creates a new Firebase reference with the
+// requested EqualTo configuration, it is used together with OrderBy.
+// The value that is passed in is automatically escape if it is a
+// string value.
+//
+//    EqualTo(7)        // -> equalTo=7
+//    EqualTo("foo")    // -> equalTo="foo"
+//    EqualTo(`"foo"`)  // -> equalTo="foo"
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#filtering-by-a-specified-child-key
+func (fb *Firebase) EqualTo(value string) *Firebase {
+	c := fb.copy()
+	if value != "" {
+		c.params.Set(equalToParam, escapeString(value))
+	} else {
+		c.params.Del(equalToParam)
+	}
+	return c
+}
+
+// StartAtValue is like StartAt but v is encoded as a JSON literal, so that
+// numbers, booleans and nil can be used as well as strings. Unlike with
+// StartAt, a string is always quoted.
+//
+//    StartAtValue(7)     // -> startAt=7
+//    StartAtValue("7")   // -> startAt="7"
+//    StartAtValue(true)  // -> startAt=true
+//    StartAtValue(nil)   // -> startAt=null
+func (fb *Firebase) StartAtValue(v interface{}) *Firebase {
+	c := fb.copy()
+	c.startKey = nil
+	c.params.Set(startAtParam, jsonLiteral(v))
+	return c
+}
+
+// EndAtValue is like EndAt but v is encoded as a JSON literal, see
+// StartAtValue.
+func (fb *Firebase) EndAtValue(v interface{}) *Firebase {
+	c := fb.copy()
+	c.endKey = nil
+	c.params.Set(endAtParam, jsonLiteral(v))
+	return c
+}
+
+// EqualToValue is like EqualTo but v is encoded as a JSON literal, see
+// StartAtValue.
+func (fb *Firebase) EqualToValue(v interface{}) *Firebase {
+	c := fb.copy()
+	c.params.Set(equalToParam, jsonLiteral(v))
+	return c
+}
+
+// jsonLiteral encodes v, which should be nil, a bool, a number or a
+// string, as JSON.
+func jsonLiteral(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return quote(s)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return quote(fmt.Sprint(v))
+	}
+	return string(b)
+}
+
 // OrderBy creates a new Firebase reference with the
 // requested OrderBy configuration. The value that is passed in
 // is automatically escape if it is a string value.
@@ -62,21 +134,123 @@ func (fb *Firebase) OrderBy(value string) *Firebase {
 	return c
 }
 
+// OrderByKey creates a new Firebase reference that orders
+// children by their keys.
+//
+//    OrderByKey() // -> orderBy="$key"
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-ordered-data
+func (fb *Firebase) OrderByKey() *Firebase {
+	return fb.orderBy("$key")
+}
+
+// OrderByValue creates a new Firebase reference that orders
+// children by their values.
+//
+//    OrderByValue() // -> orderBy="$value"
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-ordered-data
+func (fb *Firebase) OrderByValue() *Firebase {
+	return fb.orderBy("$value")
+}
+
+// OrderByPriority creates a new Firebase reference that orders
+// children by their priorities.
+//
+//    OrderByPriority() // -> orderBy="$priority"
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-ordered-data
+func (fb *Firebase) OrderByPriority() *Firebase {
+	return fb.orderBy("$priority")
+}
+
+// OrderByChild creates a new Firebase reference that orders
+// children by the value of their child at path, which may be
+// nested. Unlike OrderBy, the path is always quoted, even if it is
+// a number, and empty segments are dropped. Requests made with a
+// path that is empty or has a segment containing one of . # $ [ ]
+// or a control character fail with an ErrInvalidQuery error.
+//
+//    OrderByChild("age")           // -> orderBy="age"
+//    OrderByChild("address/zip")   // -> orderBy="address/zip"
+//    OrderByChild("/address//zip") // -> orderBy="address/zip"
+//    OrderByChild("7")             // -> orderBy="7"
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-ordered-data
+func (fb *Firebase) OrderByChild(path string) *Firebase {
+	return fb.orderBy(strings.Join(splitPath(path), "/"))
+}
+
+func (fb *Firebase) orderBy(value string) *Firebase {
+	c := fb.copy()
+	c.params.Set(orderByParam, quote(value))
+	return c
+}
+
+// escapeString encodes the value of a query parameter: integers are
+// left bare, strings that are already JSON encoded are normalized and
+// any other string is quoted.
 func escapeString(s string) string {
-	_, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		return `"` + strings.Trim(s, `"`) + `"`
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if unquoted, err := unquote(s); err == nil {
+			return quote(unquoted)
+		}
 	}
-	return s
+	return quote(s)
+}
+
+// quote encodes s as a JSON string with its quotes, backslashes, control
+// and non-ASCII characters escaped. Invalid UTF-8 is replaced by U+FFFD,
+// like encoding/json does.
+func quote(s string) string {
+	const hex = "0123456789abcdef"
+	b := make([]byte, 0, len(s)+2)
+	escape := func(r rune) {
+		b = append(b, '\\', 'u', hex[r>>12&0xf], hex[r>>8&0xf], hex[r>>4&0xf], hex[r&0xf])
+	}
+	b = append(b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r >= 0x20 && r < 0x7f:
+			b = append(b, byte(r))
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			escape(r1)
+			escape(r2)
+		default:
+			escape(r)
+		}
+	}
+	return string(append(b, '"'))
+}
+
+// unquote decodes the JSON string s.
+func unquote(s string) (string, error) {
+	var unquoted string
+	err := json.Unmarshal([]byte(s), &unquoted)
+	return unquoted, err
 }
 
 // LimitToFirst creates a new Firebase reference with the
-// requested limitToFirst configuration.
+// requested limitToFirst configuration. A value of 0 removes the
+// limit, requests made with a negative value or together with
+// LimitToLast fail with an ErrInvalidQuery error.
 //
 // Reference https://www.firebase.com/docs/rest/api/#section-param-query
 func (fb *Firebase) LimitToFirst(value int64) *Firebase {
 	c := fb.copy()
-	if value > 0 {
+	if value != 0 {
 		c.params.Set(limitToFirstParam, strconv.FormatInt(value, 10))
 	} else {
 		c.params.Del(limitToFirstParam)
@@ -85,12 +259,14 @@ func (fb *Firebase) LimitToFirst(value int64) *Firebase {
 }
 
 // LimitToLast creates a new Firebase reference with the
-// requested limitToLast configuration.
+// requested limitToLast configuration. A value of 0 removes the
+// limit, requests made with a negative value or together with
+// LimitToFirst fail with an ErrInvalidQuery error.
 //
 // Reference https://www.firebase.com/docs/rest/api/#section-param-query
 func (fb *Firebase) LimitToLast(value int64) *Firebase {
 	c := fb.copy()
-	if value > 0 {
+	if value != 0 {
 		c.params.Set(limitToLastParam, strconv.FormatInt(value, 10))
 	} else {
 		c.params.Del(limitToLastParam)
@@ -103,23 +279,121 @@ func (fb *Firebase) LimitToLast(value int64) *Firebase {
 // its value will be returned. If the data is a JSON object, the values
 // for each key will be truncated to true.
 //
+// Unlike the other query methods, Shallow changes the reference it is
+// called on, it returns it so that calls can be chained. Use WithShallow
+// to make a single call shallow, or Keys to list the keys of the children.
+//
 // Reference https://www.firebase.com/docs/rest/api/#section-param-shallow
-func (fb *Firebase) Shallow(v bool) {
+func (fb *Firebase) Shallow(v bool) *Firebase {
 	if v {
-		fb.params.Set(shallowParam, "true")
+		fb.setParam(shallowParam, "true")
 	} else {
-		fb.params.Del(shallowParam)
+		fb.setParam(shallowParam, "")
 	}
+	return fb
 }
 
 // IncludePriority determines whether or not to ask Firebase
 // for the values priority. By default, the priority is not returned.
+// Values are then returned in the export format, where objects have a
+// ".priority" child and primitives with a priority are wrapped in an
+// object with ".value" and ".priority" children, see Snapshot.Priority.
+//
+// Like Shallow, IncludePriority changes the reference it is called on and
+// returns it.
 //
 // Reference https://www.firebase.com/docs/rest/api/#section-param-format
-func (fb *Firebase) IncludePriority(v bool) {
+func (fb *Firebase) IncludePriority(v bool) *Firebase {
 	if v {
-		fb.params.Set(formatParam, formatVal)
+		fb.setParam(formatParam, formatVal)
 	} else {
-		fb.params.Del(formatParam)
+		fb.setParam(formatParam, "")
+	}
+	return fb
+}
+
+// ErrInvalidQuery is wrapped by the errors of requests whose query
+// parameters Firebase would reject, e.g. a filter without OrderBy, so that
+// they fail with a descriptive error without a round trip.
+var ErrInvalidQuery = errors.New("invalid query")
+
+type queryError string
+
+func (e queryError) Error() string { return "invalid query: " + string(e) }
+
+// Unwrap returns ErrInvalidQuery.
+func (e queryError) Unwrap() error { return ErrInvalidQuery }
+
+// validateQuery checks the query parameters of the reference before they
+// are sent to Firebase.
+func (fb *Firebase) validateQuery() error {
+	params := fb.queryParams()
+	for _, p := range []string{limitToFirstParam, limitToLastParam} {
+		if v := params.Get(p); v != "" {
+			if n, err := strconv.ParseInt(v, 10, 64); err != nil || n < 0 {
+				return queryError(p + " must not be negative, got " + v)
+			}
+		}
+	}
+	if params.Get(limitToFirstParam) != "" && params.Get(limitToLastParam) != "" {
+		return queryError(limitToFirstParam + " and " + limitToLastParam + " cannot be combined")
+	}
+
+	orderBy := params.Get(orderByParam)
+	if orderBy != "" && !strings.HasPrefix(orderBy, `"`) {
+		return queryError(orderByParam + " must be a quoted string, got " + orderBy)
+	}
+	if orderBy != "" {
+		if err := validateChildPath(orderBy); err != nil {
+			return err
+		}
+	}
+	filters := []string{startAtParam, endAtParam, equalToParam, limitToFirstParam, limitToLastParam}
+	for _, p := range filters {
+		if params.Get(p) == "" {
+			continue
+		}
+		if params.Get(shallowParam) != "" {
+			return queryError(shallowParam + " cannot be combined with " + p)
+		}
+		if orderBy == "" {
+			return queryError(orderByParam + " must be set to use " + p)
+		}
+	}
+	if params.Get(equalToParam) != "" {
+		for _, p := range []string{startAtParam, endAtParam} {
+			if params.Get(p) != "" {
+				return queryError(equalToParam + " cannot be combined with " + p)
+			}
+		}
+	}
+	if params.Get(shallowParam) != "" && orderBy != "" {
+		return queryError(shallowParam + " cannot be combined with " + orderByParam)
+	}
+	return nil
+}
+
+// validateChildPath checks the child path a quoted orderBy value orders
+// by, if it is not one of $key, $value or $priority.
+func validateChildPath(orderBy string) error {
+	path, err := unquote(orderBy)
+	if err != nil {
+		// left for Firebase to reject
+		return nil
+	}
+	switch path {
+	case "$key", "$value", "$priority":
+		return nil
+	case "":
+		return queryError(orderByParam + " must not be an empty child path")
+	}
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" {
+			return queryError(orderByParam + " child path " + orderBy + " has an empty segment")
+		}
+		if strings.ContainsAny(seg, ".#$[]") || strings.IndexFunc(seg, unicode.IsControl) >= 0 {
+			return queryError(orderByParam + " child path " + orderBy + " contains one of . # $ [ ] or a control character")
+		}
 	}
+	return nil
 }
@@ -1,6 +1,10 @@
 package firego
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,7 +15,7 @@ func TestShallow(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL)
 	)
 	defer server.Close()
 
@@ -22,7 +26,7 @@ func TestShallow(t *testing.T) {
 	req := server.receivedReqs[0]
 	assert.Equal(t, shallowParam+"=true", req.URL.Query().Encode())
 
-	fb.Shallow(false)
+	assert.Equal(t, fb, fb.Shallow(false))
 	fb.Value("")
 	require.Len(t, server.receivedReqs, 2)
 
@@ -34,7 +38,7 @@ func TestOrderBy(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL)
 	)
 	defer server.Close()
 
@@ -45,11 +49,33 @@ func TestOrderBy(t *testing.T) {
 	assert.Equal(t, orderByParam+"=%22user_id%22", req.URL.Query().Encode())
 }
 
+func TestOrderByHelpers(t *testing.T) {
+	t.Parallel()
+	fb := New(URL)
+	tests := []struct {
+		ref  *Firebase
+		want string
+	}{
+		{fb.OrderByKey(), `"$key"`},
+		{fb.OrderByValue(), `"$value"`},
+		{fb.OrderByPriority(), `"$priority"`},
+		{fb.OrderByChild("age"), `"age"`},
+		{fb.OrderByChild("/address/zip/"), `"address/zip"`},
+		{fb.OrderByChild("address//geo/lat"), `"address/geo/lat"`},
+		{fb.OrderByChild("7"), `"7"`},
+		{fb.OrderByChild(`say "hi"`), `"say \"hi\""`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.ref.params.Get(orderByParam))
+	}
+	assert.Empty(t, fb.params.Get(orderByParam))
+}
+
 func TestLimitToFirst(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL).OrderByKey()
 	)
 	defer server.Close()
 
@@ -57,14 +83,14 @@ func TestLimitToFirst(t *testing.T) {
 	require.Len(t, server.receivedReqs, 1)
 
 	req := server.receivedReqs[0]
-	assert.Equal(t, limitToFirstParam+"=2", req.URL.Query().Encode())
+	assert.Equal(t, limitToFirstParam+"=2&"+orderByParam+"=%22%24key%22", req.URL.Query().Encode())
 }
 
 func TestLimitToLast(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL).OrderByKey()
 	)
 	defer server.Close()
 
@@ -72,14 +98,89 @@ func TestLimitToLast(t *testing.T) {
 	require.Len(t, server.receivedReqs, 1)
 
 	req := server.receivedReqs[0]
-	assert.Equal(t, limitToLastParam+"=2", req.URL.Query().Encode())
+	assert.Equal(t, limitToLastParam+"=2&"+orderByParam+"=%22%24key%22", req.URL.Query().Encode())
+}
+
+func TestLimitValidation(t *testing.T) {
+	t.Parallel()
+	var (
+		server = newTestServer("")
+		fb     = New(server.URL)
+	)
+	defer server.Close()
+
+	assert.Empty(t, fb.LimitToFirst(5).LimitToFirst(0).params.Get(limitToFirstParam))
+
+	tests := []struct {
+		ref  *Firebase
+		opts []RequestOption
+		want string
+	}{
+		{fb.LimitToFirst(-1), nil, "invalid query: limitToFirst must not be negative, got -1"},
+		{fb.LimitToLast(-2), nil, "invalid query: limitToLast must not be negative, got -2"},
+		{fb.LimitToFirst(1).LimitToLast(1), nil, "invalid query: limitToFirst and limitToLast cannot be combined"},
+		{fb.LimitToFirst(1), []RequestOption{WithLimitToLast(1)}, "invalid query: limitToFirst and limitToLast cannot be combined"},
+	}
+	for _, test := range tests {
+		var v interface{}
+		err := test.ref.Value(&v, test.opts...)
+		assert.EqualError(t, err, test.want)
+		require.IsType(t, queryError(""), err)
+		assert.Equal(t, ErrInvalidQuery, err.(queryError).Unwrap())
+		if test.opts == nil {
+			assert.Equal(t, ErrInvalidQuery, test.ref.Watch(make(chan Event)).(queryError).Unwrap())
+		}
+	}
+	assert.Empty(t, server.receivedReqs)
+}
+
+func TestQueryValidation(t *testing.T) {
+	t.Parallel()
+	var (
+		server = newTestServer("null")
+		fb     = New(server.URL)
+	)
+	defer server.Close()
+
+	tests := []struct {
+		ref  *Firebase
+		want string
+	}{
+		{fb.StartAt("a"), "orderBy must be set to use startAt"},
+		{fb.EndAt("a"), "orderBy must be set to use endAt"},
+		{fb.EqualTo("a"), "orderBy must be set to use equalTo"},
+		{fb.LimitToLast(1), "orderBy must be set to use limitToLast"},
+		{fb.OrderBy("7").LimitToLast(1), "orderBy must be a quoted string, got 7"},
+		{fb.OrderByKey().EqualTo("a").StartAt("a"), "equalTo cannot be combined with startAt"},
+		{fb.OrderByKey().EqualTo("a").EndAt("a"), "equalTo cannot be combined with endAt"},
+		{fb.OrderByKey().LimitToFirst(1).Shallow(true), "shallow cannot be combined with limitToFirst"},
+		{fb.OrderByKey().Shallow(true), "shallow cannot be combined with orderBy"},
+		{fb.OrderByChild("/"), "orderBy must not be an empty child path"},
+		{fb.OrderByChild("address/zip.code"), `orderBy child path "address/zip.code" contains one of . # $ [ ] or a control character`},
+		{fb.OrderByChild("tags[0]"), `orderBy child path "tags[0]" contains one of . # $ [ ] or a control character`},
+		{fb.OrderByChild("a\nb"), `orderBy child path "a\nb" contains one of . # $ [ ] or a control character`},
+		{fb.OrderBy("a//b"), `orderBy child path "a//b" has an empty segment`},
+	}
+	for _, test := range tests {
+		var v interface{}
+		err := test.ref.Value(&v)
+		assert.EqualError(t, err, "invalid query: "+test.want)
+	}
+	assert.Empty(t, server.receivedReqs)
+
+	var v interface{}
+	assert.NoError(t, fb.OrderByChild("7").StartAt("a").EndAt("b").LimitToFirst(1).Value(&v))
+	assert.NoError(t, fb.OrderByKey().EqualTo("a").LimitToFirst(1).Value(&v))
+	assert.NoError(t, fb.Child("x").Shallow(true).Value(&v))
+	assert.NoError(t, fb.OrderByChild(`address/say "hi"`).Value(&v))
+	assert.Len(t, server.receivedReqs, 4)
 }
 
 func TestStartAt(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL).OrderByKey()
 	)
 	defer server.Close()
 
@@ -88,17 +189,17 @@ func TestStartAt(t *testing.T) {
 	require.Len(t, server.receivedReqs, 2)
 
 	req := server.receivedReqs[0]
-	assert.Equal(t, startAtParam+"=3", req.URL.Query().Encode())
+	assert.Equal(t, orderByParam+"=%22%24key%22&"+startAtParam+"=3", req.URL.Query().Encode())
 
 	req = server.receivedReqs[1]
-	assert.Equal(t, startAtParam+"=%22foo%22", req.URL.Query().Encode())
+	assert.Equal(t, orderByParam+"=%22%24key%22&"+startAtParam+"=%22foo%22", req.URL.Query().Encode())
 }
 
 func TestEndAt(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL).OrderByKey()
 	)
 	defer server.Close()
 
@@ -107,17 +208,96 @@ func TestEndAt(t *testing.T) {
 	require.Len(t, server.receivedReqs, 2)
 
 	req := server.receivedReqs[0]
-	assert.Equal(t, endAtParam+"=4", req.URL.Query().Encode())
+	assert.Equal(t, endAtParam+"=4&"+orderByParam+"=%22%24key%22", req.URL.Query().Encode())
 
 	req = server.receivedReqs[1]
-	assert.Equal(t, endAtParam+"=%22theend%22", req.URL.Query().Encode())
+	assert.Equal(t, endAtParam+"=%22theend%22&"+orderByParam+"=%22%24key%22", req.URL.Query().Encode())
+}
+
+func TestEqualTo(t *testing.T) {
+	t.Parallel()
+	var (
+		server = newTestServer("")
+		fb     = New(server.URL)
+	)
+	defer server.Close()
+
+	fb.OrderBy("age").EqualTo("25").Value("")
+	fb.EqualTo("bob").EqualTo("").Value("")
+	require.Len(t, server.receivedReqs, 2)
+
+	req := server.receivedReqs[0]
+	assert.Equal(t, equalToParam+"=25&"+orderByParam+"=%22age%22", req.URL.Query().Encode())
+
+	req = server.receivedReqs[1]
+	assert.Equal(t, "", req.URL.Query().Encode())
+}
+
+func TestTypedCursors(t *testing.T) {
+	t.Parallel()
+	fb := New(URL)
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{7, "7"},
+		{2.5, "2.5"},
+		{"7", `"7"`},
+		{"foo", `"foo"`},
+		{true, "true"},
+		{false, "false"},
+		{nil, "null"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, fb.StartAtValue(test.v).params.Get(startAtParam), "%v", test.v)
+		assert.Equal(t, test.want, fb.EndAtValue(test.v).params.Get(endAtParam), "%v", test.v)
+		assert.Equal(t, test.want, fb.EqualToValue(test.v).params.Get(equalToParam), "%v", test.v)
+	}
+
+	c, _ := fb.prepare([]RequestOption{WithStartAtValue(1), WithEndAtValue(10), WithEqualToValue(nil)})
+	assert.Equal(t, "1", c.params.Get(startAtParam))
+	assert.Equal(t, "10", c.params.Get(endAtParam))
+	assert.Equal(t, "null", c.params.Get(equalToParam))
+}
+
+func TestEscapeString(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in, want string
+	}{
+		{"7", `7`},
+		{"-7", `-7`},
+		{"foo", `"foo"`},
+		{`"foo"`, `"foo"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`"say \"hi\""`, `"say \"hi\""`},
+		{`"foo`, `"\"foo"`},
+		{`C:\temp`, `"C:\\temp"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"\x01", `"\u0001"`},
+		{"caf\u00e9", `"caf\u00e9"`},
+		{"\uf8ff", `"\uf8ff"`},
+		{"\U0001f600", `"\ud83d\ude00"`},
+		{"\xff", `"\ufffd"`},
+	}
+	for _, test := range tests {
+		got := escapeString(test.in)
+		assert.Equal(t, test.want, got, test.in)
+
+		var decoded interface{}
+		require.NoError(t, json.Unmarshal([]byte(got), &decoded), test.in)
+	}
+
+	// the key cursors compare values encoded the same way
+	assert.Equal(t, `"caf\u00e9"`, jsonLiteral("caf\u00e9"))
+	assert.Equal(t, `1.5`, jsonLiteral(1.5))
 }
 
 func TestIncludePriority(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL)
 	)
 	defer server.Close()
 
@@ -140,7 +320,7 @@ func TestQueryMultipleParams(t *testing.T) {
 	t.Parallel()
 	var (
 		server = newTestServer("")
-		fb     = New(server.URL, nil)
+		fb     = New(server.URL)
 	)
 	defer server.Close()
 
@@ -150,3 +330,32 @@ func TestQueryMultipleParams(t *testing.T) {
 	req := server.receivedReqs[0]
 	assert.Equal(t, orderByParam+"=%22user_id%22&startAt=7", req.URL.Query().Encode())
 }
+
+func TestQueryConcurrent(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("null"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			fb.Shallow(i%2 == 0)
+			fb.IncludePriority(i%2 == 1)
+		}(i)
+		go func() {
+			defer wg.Done()
+			var v interface{}
+			assert.NoError(t, fb.Value(&v))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NotNil(t, fb.OrderBy("$key").LimitToFirst(1))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,505 @@
+/*
+Package queue implements a job queue on top of a Firebase location, for
+running background work without another datastore.
+
+Tasks have a priority and a time before which they do not run. Firebase
+can only order a query by a single field, so workers read the due tasks
+that became due first, BatchSize at a time, and run the ones with the
+highest priority among them: priorities are best-effort, a high priority
+task can wait behind more than BatchSize lower priority tasks that became
+due before it. Workers claim tasks with a lease that they keep extending while the task runs,
+tasks whose worker died are claimed again once the lease expires. Tasks
+that fail are retried with a backoff and moved to a dead letter location
+after too many attempts.
+
+The queue keeps its data under three children of its location: "tasks"
+holds the queued tasks, "dead" the tasks that failed too often and
+"workers" the last heartbeat of every worker.
+*/
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zabawaba99/firego"
+)
+
+const (
+	// DefaultLease is the default time a claimed task is reserved for its
+	// worker without a heartbeat.
+	DefaultLease = 30 * time.Second
+	// DefaultMaxAttempts is the default number of times a task is run
+	// before it is moved to the dead letter location.
+	DefaultMaxAttempts = 5
+	// DefaultPollInterval is the default time a worker waits before
+	// looking for due tasks again when there were none.
+	DefaultPollInterval = time.Second
+	// DefaultBatchSize is the default number of due tasks read when
+	// looking for a task to claim.
+	DefaultBatchSize = 50
+)
+
+// ErrLeaseLost is returned when a worker's lease on a task expired and the
+// task was claimed by another worker, or removed.
+var ErrLeaseLost = errors.New("task lease was lost")
+
+var errNotDue = errors.New("task is not due")
+
+// Task is a unit of work in a Queue.
+type Task struct {
+	// ID is the key of the task.
+	ID string
+	// Data is the JSON encoded payload of the task.
+	Data json.RawMessage
+	// Priority orders due tasks, higher priorities run first among the
+	// tasks read in the same batch, see BatchSize.
+	Priority int
+	// RunAfter is the earliest time the task runs. While the task is
+	// claimed it is the time its lease expires.
+	RunAfter time.Time
+	// Attempts is the number of times the task was claimed.
+	Attempts int
+	// LastError is the error of the last failed attempt.
+	LastError string
+
+	worker string
+}
+
+// record is how a task is stored.
+type record struct {
+	Data      *json.RawMessage `json:"data"`
+	Priority  int              `json:"priority,omitempty"`
+	RunAfter  int64            `json:"runAfter"`
+	Attempts  int              `json:"attempts,omitempty"`
+	LastError string           `json:"error,omitempty"`
+	Worker    string           `json:"worker,omitempty"`
+}
+
+// PushOptions configures a task pushed to a Queue.
+type PushOptions struct {
+	// Priority of the task, higher priorities run first.
+	Priority int
+	// Delay before the task runs.
+	Delay time.Duration
+	// RunAfter is the earliest time the task runs, it takes precedence
+	// over Delay.
+	RunAfter time.Time
+}
+
+// Handler runs a task. The context is canceled if the worker loses the
+// task's lease.
+type Handler func(ctx context.Context, t *Task) error
+
+// Queue is a job queue kept at a Firebase location.
+type Queue struct {
+	// Lease is the time a claimed task is reserved for its worker, it
+	// defaults to DefaultLease. Workers send heartbeats every third of
+	// the lease.
+	Lease time.Duration
+	// MaxAttempts is the number of times a task is run before it is moved
+	// to the dead letter location, it defaults to DefaultMaxAttempts.
+	MaxAttempts int
+	// Backoff returns the time to wait before retrying a task that failed
+	// for the given number of attempts. It defaults to an exponential
+	// backoff starting at one second.
+	Backoff func(attempts int) time.Duration
+	// PollInterval is the time a worker waits when there are no due tasks,
+	// it defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// BatchSize is the number of due tasks read when claiming, in the
+	// order they became due, it defaults to DefaultBatchSize. Priorities
+	// only order the tasks within a batch, a larger BatchSize lets high
+	// priority tasks overtake more of the tasks that became due before
+	// them.
+	BatchSize int
+	// OnError, if set, is called with the errors workers encounter in the
+	// background, including the errors returned by handlers.
+	OnError func(error)
+
+	ref *firego.Firebase
+	now func() time.Time
+}
+
+// New creates a Queue kept at the location of ref.
+func New(ref *firego.Firebase) *Queue {
+	return &Queue{ref: ref, now: time.Now}
+}
+
+// Push adds a task with data as its payload and returns its ID.
+func (q *Queue) Push(data interface{}, opts PushOptions) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	runAfter := opts.RunAfter
+	if runAfter.IsZero() {
+		runAfter = q.now().Add(opts.Delay)
+	}
+
+	raw := json.RawMessage(b)
+	ref, err := q.ref.Child("tasks").Push(record{
+		Data:     &raw,
+		Priority: opts.Priority,
+		RunAfter: millis(runAfter),
+	})
+	if err != nil {
+		return "", err
+	}
+	return ref.Key(), nil
+}
+
+// Claim reserves the due task with the highest priority among the
+// BatchSize tasks that became due first for worker, it returns nil if no
+// task is due. A task that was claimed more than
+// MaxAttempts times, because its workers died, is moved to the dead
+// letter location instead.
+func (q *Queue) Claim(worker string) (*Task, error) {
+	for {
+		t, err := q.claim(worker)
+		if err != nil || t == nil || t.Attempts <= q.maxAttempts() {
+			return t, err
+		}
+		if err := q.bury(t, "lease expired"); err != nil && err != ErrLeaseLost {
+			return nil, err
+		}
+	}
+}
+
+func (q *Queue) claim(worker string) (*Task, error) {
+	now := millis(q.now())
+	var due map[string]record
+	query := q.ref.Child("tasks").OrderBy("runAfter").EndAt(strconv.FormatInt(now, 10)).LimitToFirst(int64(q.batchSize()))
+	if err := query.Value(&due); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*Task, 0, len(due))
+	for id, r := range due {
+		if r.RunAfter <= now {
+			tasks = append(tasks, r.task(id))
+		}
+	}
+	sort.Sort(byPriority(tasks))
+
+	for _, t := range tasks {
+		var claimed record
+		err := q.task(t.ID).Transaction(func(current interface{}) (interface{}, error) {
+			r, ok := decode(current)
+			if !ok || r.RunAfter > millis(q.now()) {
+				return nil, errNotDue
+			}
+			r.Worker = worker
+			r.RunAfter = millis(q.now().Add(q.lease()))
+			r.Attempts++
+			claimed = r
+			return r, nil
+		})
+		switch err {
+		case nil:
+			return claimed.task(t.ID), nil
+		case errNotDue:
+			// another worker was faster
+			continue
+		default:
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// Heartbeat extends the lease of a claimed task.
+func (q *Queue) Heartbeat(t *Task) error {
+	return q.update(t, func(r *record) (interface{}, error) {
+		r.RunAfter = millis(q.now().Add(q.lease()))
+		return r, nil
+	})
+}
+
+// Complete removes a claimed task from the queue.
+func (q *Queue) Complete(t *Task) error {
+	return q.update(t, func(*record) (interface{}, error) {
+		return nil, nil
+	})
+}
+
+// Fail records that running a claimed task failed with cause. The task is
+// retried after the Backoff, unless it ran MaxAttempts times, in which
+// case it is moved to the dead letter location.
+func (q *Queue) Fail(t *Task, cause error) error {
+	msg := "unknown error"
+	if cause != nil {
+		msg = cause.Error()
+	}
+	if t.Attempts >= q.maxAttempts() {
+		return q.bury(t, msg)
+	}
+	return q.update(t, func(r *record) (interface{}, error) {
+		r.Worker = ""
+		r.LastError = msg
+		r.RunAfter = millis(q.now().Add(q.backoff(r.Attempts)))
+		return r, nil
+	})
+}
+
+// Dead returns the tasks in the dead letter location.
+func (q *Queue) Dead() ([]*Task, error) {
+	var dead map[string]record
+	if err := q.ref.Child("dead").Value(&dead); err != nil {
+		return nil, err
+	}
+	tasks := make([]*Task, 0, len(dead))
+	for id, r := range dead {
+		tasks = append(tasks, r.task(id))
+	}
+	sort.Sort(byPriority(tasks))
+	return tasks, nil
+}
+
+// Requeue moves a task from the dead letter location back to the queue,
+// with its attempts reset.
+func (q *Queue) Requeue(id string) error {
+	var r record
+	if err := q.ref.Child("dead/" + id).Value(&r); err != nil {
+		return err
+	}
+	if r.Data == nil {
+		return errors.New("dead task " + id + " does not exist")
+	}
+	r.Attempts, r.Worker, r.RunAfter = 0, "", millis(q.now())
+	return q.ref.Update(map[string]interface{}{
+		"tasks/" + id: r,
+		"dead/" + id:  nil,
+	})
+}
+
+// Workers returns the time of the last heartbeat of every worker.
+func (q *Queue) Workers() (map[string]time.Time, error) {
+	var workers map[string]struct {
+		Heartbeat int64 `json:"heartbeat"`
+	}
+	if err := q.ref.Child("workers").Value(&workers); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]time.Time, len(workers))
+	for id, w := range workers {
+		seen[id] = at(w.Heartbeat)
+	}
+	return seen, nil
+}
+
+// Work claims and runs tasks with h, one at a time, until ctx is done. It
+// always returns the context's error. While a task runs its lease is
+// extended and the worker's heartbeat recorded every third of the lease.
+// Tasks for which h returns nil are completed, the others failed.
+func (q *Queue) Work(ctx context.Context, worker string, h Handler) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.beat(worker, "")
+
+		t, err := q.Claim(worker)
+		if err != nil {
+			q.report(err)
+		}
+		if t == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(q.pollInterval()):
+			}
+			continue
+		}
+
+		if err := q.run(ctx, worker, t, h); err != nil {
+			q.report(err)
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, worker string, t *Task, h Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(q.lease() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			q.beat(worker, t.ID)
+			if err := q.Heartbeat(t); err == ErrLeaseLost {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	q.beat(worker, t.ID)
+	err := h(ctx, t)
+	close(stop)
+	<-done
+
+	if err != nil {
+		q.report(err)
+		return q.Fail(t, err)
+	}
+	return q.Complete(t)
+}
+
+// update changes a claimed task with fn, if it is still claimed by the
+// task's worker.
+func (q *Queue) update(t *Task, fn func(r *record) (interface{}, error)) error {
+	return q.task(t.ID).Transaction(func(current interface{}) (interface{}, error) {
+		r, ok := decode(current)
+		if !ok || r.Worker != t.worker {
+			return nil, ErrLeaseLost
+		}
+		return fn(&r)
+	})
+}
+
+// bury moves a claimed task to the dead letter location.
+func (q *Queue) bury(t *Task, msg string) error {
+	data := t.Data
+	r := record{
+		Data:      &data,
+		Priority:  t.Priority,
+		RunAfter:  millis(t.RunAfter),
+		Attempts:  t.Attempts,
+		LastError: msg,
+	}
+	// written first so that the task is never lost
+	if err := q.ref.Child("dead/" + t.ID).Set(r); err != nil {
+		return err
+	}
+	if err := q.Complete(t); err != nil {
+		if err == ErrLeaseLost {
+			// another worker owns the task now
+			q.ref.Child("dead/" + t.ID).Remove()
+		}
+		return err
+	}
+	return nil
+}
+
+func (q *Queue) beat(worker, task string) {
+	err := q.ref.Child("workers/" + worker).Set(map[string]interface{}{
+		"heartbeat": firego.ServerTimestamp,
+		"task":      task,
+	})
+	if err != nil {
+		q.report(err)
+	}
+}
+
+func (q *Queue) task(id string) *firego.Firebase {
+	return q.ref.Child("tasks/" + id)
+}
+
+func (q *Queue) report(err error) {
+	if q.OnError != nil {
+		q.OnError(err)
+	}
+}
+
+func (q *Queue) lease() time.Duration {
+	if q.Lease <= 0 {
+		return DefaultLease
+	}
+	return q.Lease
+}
+
+func (q *Queue) maxAttempts() int {
+	if q.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return q.MaxAttempts
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	if q.Backoff != nil {
+		return q.Backoff(attempts)
+	}
+	if attempts > 20 {
+		attempts = 20
+	}
+	return time.Second << uint(attempts-1)
+}
+
+func (q *Queue) pollInterval() time.Duration {
+	if q.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return q.PollInterval
+}
+
+func (q *Queue) batchSize() int {
+	if q.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return q.BatchSize
+}
+
+func (r record) task(id string) *Task {
+	t := &Task{
+		ID:        id,
+		Priority:  r.Priority,
+		RunAfter:  at(r.RunAfter),
+		Attempts:  r.Attempts,
+		LastError: r.LastError,
+		worker:    r.Worker,
+	}
+	if r.Data != nil {
+		t.Data = *r.Data
+	}
+	return t
+}
+
+// decode converts the generic value of a task to a record, ok is false if
+// the task does not exist.
+func decode(v interface{}) (r record, ok bool) {
+	if v == nil {
+		return r, false
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return r, false
+	}
+	return r, json.Unmarshal(b, &r) == nil
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func at(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// byPriority orders tasks by descending priority, then by when they are
+// due.
+type byPriority []*Task
+
+func (s byPriority) Len() int      { return len(s) }
+func (s byPriority) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPriority) Less(i, j int) bool {
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority > s[j].Priority
+	}
+	if !s[i].RunAfter.Equal(s[j].RunAfter) {
+		return s[i].RunAfter.Before(s[j].RunAfter)
+	}
+	return s[i].ID < s[j].ID
+}
@@ -0,0 +1,244 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firego/internal/treeserver"
+)
+
+// clock is a fake time source for a Queue.
+type clock struct {
+	mtx sync.Mutex
+	t   time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newQueue(server *treeserver.Server) (*Queue, *clock) {
+	c := &clock{t: time.Unix(1000, 0)}
+	q := New(firego.New(server.URL))
+	q.now = c.now
+	return q, c
+}
+
+func TestPushClaim(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+	q, c := newQueue(server)
+
+	low, err := q.Push("low", PushOptions{})
+	require.NoError(t, err)
+	high, err := q.Push("high", PushOptions{Priority: 10})
+	require.NoError(t, err)
+	delayed, err := q.Push("delayed", PushOptions{Priority: 20, Delay: time.Minute})
+	require.NoError(t, err)
+
+	task, err := q.Claim("w1")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, high, task.ID)
+	assert.Equal(t, `"high"`, string(task.Data))
+	assert.Equal(t, 1, task.Attempts)
+	assert.Equal(t, "w1", server.Get("tasks/"+high+"/worker"))
+
+	task, err = q.Claim("w2")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, low, task.ID)
+
+	// the claimed tasks are leased and the delayed one is not due yet
+	task, err = q.Claim("w3")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+
+	c.advance(time.Minute)
+	task, err = q.Claim("w3")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, delayed, task.ID)
+	require.NoError(t, q.Complete(task))
+	assert.Nil(t, server.Get("tasks/"+delayed))
+}
+
+func TestLeaseExpires(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+	q, c := newQueue(server)
+	q.Lease = time.Minute
+
+	_, err := q.Push("job", PushOptions{})
+	require.NoError(t, err)
+
+	first, err := q.Claim("w1")
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	c.advance(30 * time.Second)
+	require.NoError(t, q.Heartbeat(first))
+	c.advance(45 * time.Second)
+	task, err := q.Claim("w2")
+	require.NoError(t, err)
+	assert.Nil(t, task, "the heartbeat extended the lease")
+
+	c.advance(time.Minute)
+	second, err := q.Claim("w2")
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, 2, second.Attempts)
+
+	assert.Equal(t, ErrLeaseLost, q.Complete(first))
+	assert.Equal(t, ErrLeaseLost, q.Heartbeat(first))
+	require.NoError(t, q.Complete(second))
+}
+
+func TestFailDeadLetter(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+	q, c := newQueue(server)
+	q.MaxAttempts = 2
+
+	id, err := q.Push(map[string]int{"n": 1}, PushOptions{Priority: 3})
+	require.NoError(t, err)
+
+	task, err := q.Claim("w1")
+	require.NoError(t, err)
+	require.NoError(t, q.Fail(task, errors.New("boom")))
+	assert.Equal(t, "boom", server.Get("tasks/"+id+"/error"))
+
+	// retried after the backoff
+	task, err = q.Claim("w1")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+	c.advance(time.Second)
+	task, err = q.Claim("w1")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, "boom", task.LastError)
+
+	require.NoError(t, q.Fail(task, errors.New("boom again")))
+	assert.Nil(t, server.Get("tasks/"+id))
+
+	dead, err := q.Dead()
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, id, dead[0].ID)
+	assert.Equal(t, `{"n":1}`, string(dead[0].Data))
+	assert.Equal(t, 3, dead[0].Priority)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Equal(t, "boom again", dead[0].LastError)
+
+	require.NoError(t, q.Requeue(id))
+	assert.Nil(t, server.Get("dead/"+id))
+	task, err = q.Claim("w1")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, 1, task.Attempts)
+}
+
+func TestClaimBuriesAbandonedTasks(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+	q, c := newQueue(server)
+	q.MaxAttempts = 1
+
+	id, err := q.Push("crashes its workers", PushOptions{})
+	require.NoError(t, err)
+	task, err := q.Claim("w1")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	// w1 died
+	c.advance(DefaultLease)
+	task, err = q.Claim("w2")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+	assert.Equal(t, "lease expired", server.Get("dead/"+id+"/error"))
+}
+
+func TestWork(t *testing.T) {
+	t.Parallel()
+	server := treeserver.New(`null`)
+	defer server.Close()
+	q := New(firego.New(server.URL))
+	q.PollInterval = 10 * time.Millisecond
+	q.Lease = 30 * time.Millisecond
+	q.Backoff = func(int) time.Duration { return 0 }
+
+	var errs []error
+	var mtx sync.Mutex
+	q.OnError = func(err error) {
+		mtx.Lock()
+		errs = append(errs, err)
+		mtx.Unlock()
+	}
+
+	for _, v := range []string{"ok", "flaky", "slow"} {
+		_, err := q.Push(v, PushOptions{})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	ran := map[string]int{}
+	go func() {
+		done <- q.Work(ctx, "w1", func(ctx context.Context, task *Task) error {
+			var v string
+			json.Unmarshal(task.Data, &v)
+			ran[v]++
+			switch {
+			case v == "flaky" && task.Attempts == 1:
+				return errors.New("flaky")
+			case v == "slow":
+				// outlives the lease, the heartbeats keep it
+				time.Sleep(100 * time.Millisecond)
+			}
+			return nil
+		})
+	}()
+
+	require.True(t, waitFor(func() bool { return server.Get("tasks") == nil }))
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	assert.Equal(t, map[string]int{"ok": 1, "flaky": 2, "slow": 1}, ran)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.NotEmpty(t, errs)
+	assert.Equal(t, "flaky", errs[0].Error())
+
+	workers, err := q.Workers()
+	require.NoError(t, err)
+	assert.Contains(t, workers, "w1")
+}
+
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package firego
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// EventTypeResynced is the type of the event WatchReconnect sends once a
+// dropped watch is re-established. Its Data is the value of the location
+// at that time, which replaces whatever was built from the events before.
+const EventTypeResynced = "resynced"
+
+// DefaultKeepAliveTimeout is how long WatchReconnect waits for an event by
+// default before it considers the connection dead. Firebase sends a
+// keep-alive event every 30 seconds when nothing changes.
+const DefaultKeepAliveTimeout = time.Minute
+
+// ErrKeepAliveTimeout is passed to the OnError of the ReconnectOptions of a
+// watch that was re-established because no event arrived within its
+// KeepAliveTimeout.
+var ErrKeepAliveTimeout = errors.New("no keep-alive received from Firebase")
+
+// ErrAuthRevoked is passed to the OnError of the ReconnectOptions of a
+// watch that was re-established with a new auth token because Firebase
+// revoked the previous one.
+var ErrAuthRevoked = errors.New("auth token revoked by Firebase")
+
+// ReconnectOptions configures WatchReconnect.
+type ReconnectOptions struct {
+	// MinBackoff is the delay before the first attempt to re-establish a
+	// dropped watch, it defaults to 1 second. The delay doubles with
+	// every failed attempt.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest delay between two attempts, it defaults
+	// to 1 minute.
+	MaxBackoff time.Duration
+	// KeepAliveTimeout is how long to wait for an event, keep-alive events
+	// included, before tearing down the connection and re-establishing
+	// the watch, so that a connection that went silent, e.g. a half-open
+	// one, does not leave the watch waiting forever. It defaults to
+	// DefaultKeepAliveTimeout, a negative value disables the check.
+	KeepAliveTimeout time.Duration
+	// OnError, if set, is called with the errors that made the watch
+	// reconnect.
+	OnError func(error)
+	// Monitor, if set, collects the stats of the connections of the
+	// watch and counts the times it was re-established.
+	Monitor *WatchMonitor
+}
+
+// backoff returns how long to wait before the given attempt, starting at
+// 0, to re-establish the watch: an exponentially growing delay of which
+// up to half is random, so that clients dropped at the same time do not
+// reconnect at the same time.
+func (o ReconnectOptions) backoff(attempt int) time.Duration {
+	min, max := o.MinBackoff, o.MaxBackoff
+	if min <= 0 {
+		min = time.Second
+	}
+	if max <= 0 {
+		max = time.Minute
+	}
+	d := min
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// keepAliveTimeout returns the KeepAliveTimeout to use, 0 if the check is
+// disabled.
+func (o ReconnectOptions) keepAliveTimeout() time.Duration {
+	switch {
+	case o.KeepAliveTimeout < 0:
+		return 0
+	case o.KeepAliveTimeout == 0:
+		return DefaultKeepAliveTimeout
+	}
+	return o.KeepAliveTimeout
+}
+
+// WatchReconnect is like Watch but the watch is re-established when the
+// connection drops, Firebase closes it or answers with a server error,
+// after a backoff described by opts. The value of the location when the
+// watch is back is sent as an EventTypeResynced event, in place of the
+// initial put event of the new connection.
+//
+// If the reference has a TokenSource, see WithTokenSource, an auth token
+// Firebase revokes is replaced by a new one from the source, which the
+// reference is then authenticated with, and the watch is re-established
+// instead of receiving an EventTypeAuthRevoked event. If the source fails,
+// the watch ends with its error.
+//
+// The watch ends, and notifications is closed, once StopWatching is
+// called or Firebase refuses the watch for good: with a cancel event, or a
+// client error such as a permission denied, which is sent as an
+// EventTypeError event, as is ErrEventTooLarge. WatchErr then returns the
+// reason, the errors the watch recovered from are only passed to
+// opts.OnError.
+func (fb *Firebase) WatchReconnect(notifications chan Event, opts ReconnectOptions) error {
+	w, err := fb.startWatching(context.Background())
+	if err != nil {
+		return err
+	}
+	stop := w.ctx.Done()
+
+	conn := fb.copy()
+	events := make(chan Event)
+	if err := conn.Watch(events, WithWatchMonitor(opts.Monitor)); err != nil && !retryable(err) {
+		fb.stoppedWatching(w, err)
+		return err
+	} else if err != nil {
+		events = nil
+		report(opts, err)
+	}
+
+	go func() {
+		var err error
+		defer func() {
+			fb.stoppedWatching(w, err)
+			close(notifications)
+		}()
+
+		attempt := 0
+		resync := false
+		for {
+			if events != nil {
+				received, ok, fwdErr := fb.forward(conn, events, notifications, stop, resync, opts)
+				if !ok {
+					select {
+					case <-stop:
+					default:
+						// canceled by Firebase
+						err = conn.WatchErr()
+					}
+					if fwdErr != nil {
+						select {
+						case notifications <- Event{Type: EventTypeError, Data: fwdErr}:
+							err = fwdErr
+						case <-stop:
+						}
+					}
+					return
+				}
+				if received {
+					attempt, resync = 0, true
+				}
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(opts.backoff(attempt)):
+			}
+			attempt++
+
+			conn = fb.copy()
+			events = make(chan Event)
+			if watchErr := conn.Watch(events, WithWatchMonitor(opts.Monitor)); watchErr != nil {
+				if !retryable(watchErr) {
+					select {
+					case notifications <- Event{Type: EventTypeError, Data: watchErr}:
+						err = watchErr
+					case <-stop:
+					}
+					return
+				}
+				events = nil
+				report(opts, watchErr)
+				continue
+			}
+			opts.Monitor.reconnected()
+		}
+	}()
+	return nil
+}
+
+// forward sends the events of the connection to notifications until it
+// drops, goes silent for longer than the keep-alive timeout or, if the
+// reference has a TokenSource, Firebase revokes its auth token. It reports
+// whether any event was received, whether the watch should be
+// re-established and, if not, the error that ended it when it is not the
+// one of the connection.
+func (fb *Firebase) forward(conn *Firebase, events, notifications chan Event, stop <-chan struct{}, resync bool, opts ReconnectOptions) (received, ok bool, err error) {
+	teardown := func(reconnect bool) (bool, bool, error) {
+		conn.StopWatching()
+		for range events {
+			// wait for the connection to be torn down
+		}
+		return received, reconnect, err
+	}
+
+	timeout := opts.keepAliveTimeout()
+	var silent *time.Timer
+	if timeout > 0 {
+		silent = time.NewTimer(timeout)
+		defer silent.Stop()
+	}
+	for {
+		var event Event
+		select {
+		case event, ok = <-events:
+			if !ok {
+				return received, true, nil
+			}
+		case <-timerC(silent):
+			report(opts, ErrKeepAliveTimeout)
+			return teardown(true)
+		case <-stop:
+			return teardown(false)
+		}
+
+		switch {
+		case event.Type == EventTypeError:
+			connErr, _ := event.Data.(error)
+			if connErr == ErrEventTooLarge {
+				// the new connection would receive it again
+				err = connErr
+				return teardown(false)
+			}
+			report(opts, connErr)
+			continue
+		case event.Type == EventTypePut && resync && !received:
+			event.Type = EventTypeResynced
+		case event.Type == EventTypeAuthRevoked && fb.tokens != nil:
+			// resumed with a new token instead
+			report(opts, ErrAuthRevoked)
+			if err = fb.refreshAuth(); err != nil {
+				return teardown(false)
+			}
+			return teardown(true)
+		}
+		received = true
+
+		select {
+		case notifications <- event:
+		case <-stop:
+			return teardown(false)
+		}
+		if event.Type == EventTypeCancel {
+			// the connection ends on its own
+			for range events {
+			}
+			return received, false, nil
+		}
+		if silent != nil {
+			if !silent.Stop() {
+				select {
+				case <-silent.C:
+				default:
+				}
+			}
+			silent.Reset(timeout)
+		}
+	}
+}
+
+// timerC returns the channel of t, nil if there is no timer.
+func timerC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
+
+// retryable reports whether establishing a watch that failed with err may
+// succeed later on.
+func retryable(err error) bool {
+	if err == ErrClosed {
+		return false
+	}
+	if e, ok := err.(*Error); ok {
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	if _, ok := err.(queryError); ok {
+		return false
+	}
+	return true
+}
+
+func report(opts ReconnectOptions, err error) {
+	if opts.OnError != nil && err != nil {
+		opts.OnError(err)
+	}
+}
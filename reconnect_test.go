@@ -0,0 +1,306 @@
+package firego
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchReconnect(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx         sync.Mutex
+		connections int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		connections++
+		n := connections
+		mtx.Unlock()
+
+		switch n {
+		case 1:
+			// dropped after the initial snapshot
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"a\":1}}\n\n"))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"a\":1,\"b\":2}}\n\n"))
+			w.Write([]byte("event: put\ndata: {\"path\":\"/c\",\"data\":3}\n\n"))
+			w.(http.Flusher).Flush()
+			<-req.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	var (
+		errsMtx sync.Mutex
+		errs    []error
+	)
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		OnError: func(err error) {
+			errsMtx.Lock()
+			errs = append(errs, err)
+			errsMtx.Unlock()
+		},
+	}))
+
+	event := <-notifications
+	assert.Equal(t, EventTypePut, event.Type)
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, event.Data)
+
+	event = <-notifications
+	assert.Equal(t, EventTypeResynced, event.Type)
+	assert.Equal(t, "/", event.Path)
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": 2.0}, event.Data)
+
+	event = <-notifications
+	assert.Equal(t, EventTypePut, event.Type)
+	assert.Equal(t, "/c", event.Path)
+
+	fb.StopWatching()
+	_, ok := <-notifications
+	assert.False(t, ok, "notifications should be closed")
+	assert.NoError(t, fb.WatchErr())
+
+	errsMtx.Lock()
+	defer errsMtx.Unlock()
+	require.Len(t, errs, 2)
+	assert.Equal(t, http.StatusServiceUnavailable, errs[1].(*Error).StatusCode)
+}
+
+func TestWatchReconnectPermanentError(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx         sync.Mutex
+		connections int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		connections++
+		n := connections
+		mtx.Unlock()
+
+		if n > 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Permission denied"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":null}\n\n"))
+		w.Write([]byte("event: auth_revoked\ndata: \"credential is no longer valid\"\n\n"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{MinBackoff: time.Millisecond}))
+
+	var types []string
+	var last Event
+	for event := range notifications {
+		types = append(types, event.Type)
+		last = event
+	}
+	assert.Equal(t, []string{EventTypePut, EventTypeAuthRevoked, EventTypeError}, types)
+	assert.Equal(t, ErrPermissionDenied, last.Data.(*Error).Unwrap())
+	assert.Equal(t, last.Data, fb.WatchErr())
+	assert.False(t, fb.isWatching())
+
+	// the first connection is made synchronously
+	err := fb.WatchReconnect(make(chan Event), ReconnectOptions{})
+	require.IsType(t, &Error{}, err)
+	assert.Equal(t, http.StatusUnauthorized, err.(*Error).StatusCode)
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	t.Parallel()
+	opts := ReconnectOptions{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, max := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		d := opts.backoff(attempt)
+		assert.True(t, d >= max/2 && d <= max, "attempt %d waited %s", attempt, d)
+	}
+	assert.True(t, ReconnectOptions{}.backoff(0) <= time.Second)
+}
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+	assert.True(t, retryable(errors.New("connection reset")))
+	assert.True(t, retryable(&Error{StatusCode: http.StatusBadGateway}))
+	assert.False(t, retryable(&Error{StatusCode: http.StatusBadRequest}))
+	assert.False(t, retryable(ErrClosed))
+	assert.False(t, retryable(queryError("bad")))
+}
+
+func TestWatchReconnectKeepAliveTimeout(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx         sync.Mutex
+		connections int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		connections++
+		n := connections
+		mtx.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":" + strconv.Itoa(n) + "}\n\n"))
+		w.(http.Flusher).Flush()
+		if n == 1 {
+			// the connection goes silent without being closed
+			<-req.Context().Done()
+			return
+		}
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+				w.Write([]byte("event: keep-alive\ndata: null\n\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 10)
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{
+		MinBackoff:       time.Millisecond,
+		KeepAliveTimeout: 50 * time.Millisecond,
+		OnError:          func(err error) { errs <- err },
+	}))
+
+	event := <-notifications
+	assert.Equal(t, 1.0, event.Data)
+	event = <-notifications
+	assert.Equal(t, EventTypeResynced, event.Type)
+	assert.Equal(t, 2.0, event.Data)
+	assert.Equal(t, ErrKeepAliveTimeout, <-errs)
+
+	// keep-alive events keep the connection
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, EventTypeKeepAlive, (<-notifications).Type)
+	}
+	fb.StopWatching()
+	for range notifications {
+	}
+	assert.Empty(t, errs)
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, 2, connections)
+}
+
+func TestWatchReconnectAuthRevoked(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth := req.URL.Query().Get("auth")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":\"" + auth + "\"}\n\n"))
+		if auth == "old" {
+			w.Write([]byte("event: auth_revoked\ndata: \"token expired\"\n\n"))
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	tokens := make(chan string, 1)
+	tokens <- "new"
+	errs := make(chan error, 10)
+	fb := New(server.URL, WithAuth("old"), WithHTTPClient(&http.Client{}), WithTokenSource(TokenFunc(func() (string, error) {
+		select {
+		case token := <-tokens:
+			return token, nil
+		default:
+			return "", errors.New("no token")
+		}
+	})))
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{
+		MinBackoff: time.Millisecond,
+		OnError:    func(err error) { errs <- err },
+	}))
+
+	event := <-notifications
+	assert.Equal(t, "old", event.Data)
+	event = <-notifications
+	assert.Equal(t, EventTypeResynced, event.Type)
+	assert.Equal(t, "new", event.Data)
+	assert.Equal(t, ErrAuthRevoked, <-errs)
+	assert.Equal(t, "new", fb.queryParams().Get(authParam))
+
+	fb.StopWatching()
+	for range notifications {
+	}
+	assert.NoError(t, fb.WatchErr())
+}
+
+func TestWatchReconnectTokenSourceFails(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":null}\n\n"))
+		w.Write([]byte("event: auth_revoked\ndata: \"token expired\"\n\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	failed := errors.New("no token")
+	fb := New(server.URL, WithHTTPClient(&http.Client{}), WithTokenSource(TokenFunc(func() (string, error) {
+		return "", failed
+	})))
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{MinBackoff: time.Millisecond}))
+
+	var types []string
+	for event := range notifications {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{EventTypePut, EventTypeError}, types)
+	assert.Equal(t, failed, fb.WatchErr())
+}
+
+func TestWatchReconnectEventTooLarge(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":\"too large\"}\n\n"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}), WithMaxEventSize(10))
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{MinBackoff: time.Millisecond}))
+
+	var types []string
+	for event := range notifications {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{EventTypeError}, types)
+	assert.Equal(t, ErrEventTooLarge, fb.WatchErr())
+}
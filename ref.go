@@ -0,0 +1,88 @@
+package firego
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// resolveConcurrency is the number of values ResolveAll fetches at once.
+const resolveConcurrency = 8
+
+// Ref is a value that points at another location in the database, stored
+// as the path of that location relative to the root. It formalizes the
+// common pattern of storing foreign keys.
+type Ref string
+
+// NewRef returns a Ref pointing at the given path segments.
+func NewRef(segments ...string) Ref {
+	return Ref(strings.Join(segments, "/"))
+}
+
+// Resolve gets the value of the location the Ref points at, relative to
+// root.
+func (r Ref) Resolve(ctx context.Context, root *Firebase, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return root.Child(strings.Trim(string(r), "/")).ValueContext(ctx, v)
+}
+
+// ResolveAll gets the values of the locations refs point at, relative to
+// root, into v which must be a pointer to a slice. The slice is replaced
+// by one with an element for every ref, in the same order. Duplicate refs
+// are only fetched once and empty refs leave their element as the zero
+// value. Values are fetched concurrently and the first error cancels the
+// remaining fetches.
+func ResolveAll(ctx context.Context, root *Firebase, refs []Ref, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("ResolveAll requires a pointer to a slice")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = reflect.MakeSlice(rv.Elem().Type(), len(refs), len(refs))
+		first   = map[Ref]int{}
+		sem     = make(chan struct{}, resolveConcurrency)
+		wg      sync.WaitGroup
+		once    sync.Once
+		err     error
+	)
+	for i, ref := range refs {
+		if _, ok := first[ref]; ok || ref == "" {
+			continue
+		}
+		first[ref] = i
+
+		wg.Add(1)
+		go func(ref Ref, elem reflect.Value) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if e := ref.Resolve(ctx, root, elem.Addr().Interface()); e != nil {
+				once.Do(func() {
+					err = e
+					cancel()
+				})
+			}
+		}(ref, results.Index(i))
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	for i, ref := range refs {
+		if j, ok := first[ref]; ok && j != i {
+			results.Index(i).Set(results.Index(j))
+		}
+	}
+	rv.Elem().Set(results)
+	return nil
+}
@@ -0,0 +1,74 @@
+package firego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+}
+
+func TestRefResolve(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/u1", testUser{Name: "ann"})
+	root := New(server.URL)
+
+	var u testUser
+	require.NoError(t, NewRef("users", "u1").Resolve(context.Background(), root, &u))
+	assert.Equal(t, "ann", u.Name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, Ref("users/u1").Resolve(ctx, root, &u))
+}
+
+func TestResolveAll(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users", map[string]testUser{"u1": {"ann"}, "u2": {"bob"}})
+
+	// count the requests that reach the database
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Redirect(w, req, server.URL+req.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer counting.Close()
+	root := New(counting.URL)
+
+	var users []testUser
+	refs := []Ref{"users/u2", "", "/users/u1/", "users/u2"}
+	require.NoError(t, ResolveAll(context.Background(), root, refs, &users))
+	assert.Equal(t, []testUser{{"bob"}, {}, {"ann"}, {"bob"}}, users)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	assert.Error(t, ResolveAll(context.Background(), root, refs, users))
+}
+
+func TestResolveAllError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, `{"error":"Permission denied"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	var users []testUser
+	err := ResolveAll(context.Background(), New(server.URL), []Ref{"a", "b"}, &users)
+	assert.Error(t, err)
+	assert.Nil(t, users)
+}
@@ -1,8 +1,16 @@
 package firego
 
+import "context"
+
 // Remove the Firebase reference from the cloud.
-func (fb *Firebase) Remove() error {
-	_, err := fb.doRequest("DELETE", nil)
+func (fb *Firebase) Remove(opts ...RequestOption) error {
+	return fb.RemoveContext(context.Background(), opts...)
+}
+
+// RemoveContext is like Remove but the request is canceled when ctx is
+// done.
+func (fb *Firebase) RemoveContext(ctx context.Context, opts ...RequestOption) error {
+	_, err := fb.doRequest(ctx, "DELETE", nil, opts)
 	if err != nil {
 		return err
 	}
@@ -15,7 +15,7 @@ func TestRemove(t *testing.T) {
 
 	server.Set("", true)
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 	err := fb.Remove()
 	assert.NoError(t, err)
 
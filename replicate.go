@@ -0,0 +1,321 @@
+package firego
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// replicatePageSize is the number of children compared per page by
+// Reconcile.
+const replicatePageSize = 500
+
+// ReplicationStats describes the work done by a Replicator.
+type ReplicationStats struct {
+	// Events is the number of source events applied to the destination.
+	Events int64
+	// Errors is the number of events that failed to apply.
+	Errors int64
+	// Reconciled is the number of destination children rewritten or
+	// removed by reconciliation passes.
+	Reconciled int64
+	// LastApplied is when the last event was applied.
+	LastApplied time.Time
+	// Lag is the time between receiving and applying the last event.
+	Lag time.Duration
+}
+
+// Replicator keeps a destination reference, possibly in another project,
+// eventually consistent with a source reference. Replication is one-way:
+// changes made directly to the destination are overwritten by the next
+// reconciliation pass.
+type Replicator struct {
+	// ReconcileInterval between reconciliation passes, zero only
+	// reconciles on Start and after an event failed to apply, before
+	// the next event is applied.
+	ReconcileInterval time.Duration
+	// RetryInterval between attempts to re-establish the watch, it
+	// defaults to 5 seconds.
+	RetryInterval time.Duration
+	// OnError, if set, is called with the errors encountered in the
+	// background.
+	OnError func(error)
+
+	src, dst *Firebase
+
+	mtx   sync.Mutex
+	stats ReplicationStats
+	// dirty is set when an event failed to apply
+	dirty bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReplicator creates a Replicator from src to dst.
+func NewReplicator(src, dst *Firebase) *Replicator {
+	return &Replicator{src: src.location(), dst: dst.location()}
+}
+
+// Stats returns the work done so far.
+func (r *Replicator) Stats() ReplicationStats {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.stats
+}
+
+// Reconcile compares the children of the source and destination page by
+// page, rewriting the destination children that differ and removing the
+// ones that do not exist in the source. It returns the number of
+// children that were rewritten or removed.
+func (r *Replicator) Reconcile(ctx context.Context) (int, error) {
+	var (
+		fixed  int
+		lower  *string
+		update = map[string]interface{}{}
+		page   = map[string]interface{}{}
+	)
+	flush := func(upper *string) error {
+		// compare the collected source children to the destination
+		// children in the same key range
+		dst, err := r.dst.keyRange(lower, upper)
+		if err != nil {
+			return err
+		}
+		for k, v := range page {
+			if !reflect.DeepEqual(dst[k], v) {
+				update[k] = v
+			}
+		}
+		for k := range dst {
+			if _, ok := page[k]; !ok {
+				update[k] = nil
+			}
+		}
+		if len(update) > 0 {
+			if err := r.dst.Update(update); err != nil {
+				return err
+			}
+		}
+		fixed += len(update)
+		update = map[string]interface{}{}
+		page = map[string]interface{}{}
+		return nil
+	}
+
+	err := r.src.forEachChild(ctx, replicatePageSize, func(key string, v interface{}) error {
+		page[key] = v
+		if len(page) < replicatePageSize {
+			return nil
+		}
+		upper := key
+		if err := flush(&upper); err != nil {
+			return err
+		}
+		lower = &upper
+		return nil
+	})
+	if err == nil {
+		// the remaining children, and everything after the last source key
+		err = flush(nil)
+	}
+
+	r.mtx.Lock()
+	r.stats.Reconciled += int64(fixed)
+	if err == nil {
+		r.dirty = false
+	}
+	r.mtx.Unlock()
+	return fixed, err
+}
+
+// keyRange returns the children with keys after lower, up to and
+// including upper, nil bounds being open.
+func (fb *Firebase) keyRange(lower, upper *string) (map[string]interface{}, error) {
+	c := fb.copy()
+	c.params.Set(orderByParam, `"$key"`)
+	if lower != nil {
+		c.params.Set(startAtParam, quote(*lower))
+	}
+	if upper != nil {
+		c.params.Set(endAtParam, quote(*upper))
+	}
+
+	var v map[string]interface{}
+	if err := c.Value(&v); err != nil {
+		return nil, err
+	}
+	// startAt is inclusive, and the bounds are enforced here as well in
+	// case the server ignored them
+	for k := range v {
+		if (lower != nil && !(byKey{*lower, k}).Less(0, 1)) || (upper != nil && (byKey{*upper, k}).Less(0, 1)) {
+			delete(v, k)
+		}
+	}
+	return v, nil
+}
+
+// Start reconciles the destination and then replicates the changes made
+// to the source in the background.
+func (r *Replicator) Start(ctx context.Context) error {
+	if _, err := r.Reconcile(ctx); err != nil {
+		return err
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run()
+	return nil
+}
+
+// Stop tears down the watch.
+func (r *Replicator) Stop() {
+	close(r.stop)
+	r.src.StopWatching()
+	<-r.done
+}
+
+func (r *Replicator) run() {
+	defer close(r.done)
+
+	retry := r.RetryInterval
+	if retry <= 0 {
+		retry = 5 * time.Second
+	}
+	var reconcile <-chan time.Time
+	if r.ReconcileInterval > 0 {
+		ticker := time.NewTicker(r.ReconcileInterval)
+		defer ticker.Stop()
+		reconcile = ticker.C
+	}
+
+	for {
+		notifications := make(chan Event)
+		if err := r.src.Watch(notifications); err != nil {
+			r.report(err)
+		} else {
+			select {
+			case <-r.stop:
+				// Stop may have run before the watch was established
+				r.src.StopWatching()
+			default:
+			}
+			r.consume(notifications, reconcile)
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+func (r *Replicator) consume(notifications chan Event, reconcile <-chan time.Time) {
+	for {
+		select {
+		case event, ok := <-notifications:
+			if !ok {
+				return
+			}
+			r.apply(event)
+		case <-reconcile:
+			_, err := r.Reconcile(context.Background())
+			r.report(err)
+		}
+	}
+}
+
+// apply writes a source event to the destination.
+func (r *Replicator) apply(event Event) {
+	received := time.Now()
+
+	r.mtx.Lock()
+	dirty := r.dirty
+	r.mtx.Unlock()
+	if dirty {
+		if _, err := r.Reconcile(context.Background()); err != nil {
+			r.report(err)
+		}
+	}
+
+	var err error
+	switch event.Type {
+	case EventTypePut:
+		if event.Path == "/" {
+			err = r.replaceChildren(event.Data)
+		} else {
+			err = r.dst.Child(event.Path[1:]).Set(event.Data)
+		}
+	case EventTypePatch:
+		if event.Path == "/" {
+			err = r.dst.Update(event.Data)
+		} else {
+			err = r.dst.Child(event.Path[1:]).Update(event.Data)
+		}
+	case EventTypeError:
+		r.report(event.Data.(error))
+		return
+	default:
+		return
+	}
+
+	r.mtx.Lock()
+	if err != nil {
+		r.stats.Errors++
+		r.dirty = true
+	} else {
+		r.stats.Events++
+		r.stats.LastApplied = time.Now()
+		r.stats.Lag = r.stats.LastApplied.Sub(received)
+	}
+	r.mtx.Unlock()
+	r.report(err)
+}
+
+// replaceChildren makes the children of the destination equal to the
+// snapshot of the source, in batches so that large snapshots do not end
+// up in a single request.
+func (r *Replicator) replaceChildren(v interface{}) error {
+	children, ok := v.(map[string]interface{})
+	if !ok {
+		return r.dst.Set(v)
+	}
+
+	existing, err := r.dst.Keys()
+	if err != nil {
+		return err
+	}
+	update := map[string]interface{}{}
+	for _, k := range existing {
+		if _, ok := children[k]; !ok {
+			update[k] = nil
+		}
+	}
+
+	keys := make([]string, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		update[k] = children[k]
+		if len(update) >= replicatePageSize {
+			if err := r.dst.Update(update); err != nil {
+				return err
+			}
+			update = map[string]interface{}{}
+		}
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	return r.dst.Update(update)
+}
+
+func (r *Replicator) report(err error) {
+	if err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+}
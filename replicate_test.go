@@ -0,0 +1,109 @@
+package firego
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+	src := firetest.New()
+	src.Start()
+	defer src.Close()
+	dst := firetest.New()
+	dst.Start()
+	defer dst.Close()
+
+	src.Set("posts", map[string]interface{}{
+		"a": "same",
+		"b": map[string]interface{}{"title": "new"},
+		"c": "missing",
+	})
+	dst.Set("posts", map[string]interface{}{
+		"a": "same",
+		"b": map[string]interface{}{"title": "old"},
+		"z": "extra",
+	})
+
+	r := NewReplicator(New(src.URL+"/posts"), New(dst.URL+"/posts"))
+	fixed, err := r.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, fixed)
+	assert.Equal(t, src.Get("posts"), dst.Get("posts"))
+
+	fixed, err = r.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, fixed)
+	assert.Equal(t, int64(3), r.Stats().Reconciled)
+}
+
+func TestKeyRange(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("", map[string]interface{}{"1": 1, "5": 5, "a": "a", "b": "b"})
+	fb := New(server.URL)
+
+	lower, upper := "1", "a"
+	v, err := fb.keyRange(&lower, &upper)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"5": 5.0, "a": "a"}, v)
+
+	v, err = fb.keyRange(nil, &lower)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"1": 1.0}, v)
+
+	v, err = fb.keyRange(&upper, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"b": "b"}, v)
+}
+
+func TestReplicatorStart(t *testing.T) {
+	t.Parallel()
+	src := firetest.New()
+	src.Start()
+	defer src.Close()
+	dst := firetest.New()
+	dst.Start()
+	defer dst.Close()
+
+	src.Set("posts/a", "A")
+	dst.Set("posts/z", "extra")
+
+	r := NewReplicator(New(src.URL+"/posts"), New(dst.URL+"/posts"))
+	r.RetryInterval = 50 * time.Millisecond
+	require.NoError(t, r.Start(context.Background()))
+	defer r.Stop()
+	assert.Equal(t, map[string]interface{}{"a": "A"}, dst.Get("posts"))
+
+	converged := func(expected interface{}) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !assert.ObjectsAreEqual(expected, dst.Get("posts")) {
+			if time.Now().After(deadline) {
+				require.Equal(t, expected, dst.Get("posts"))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	src.Set("posts/b", map[string]interface{}{"title": "B"})
+	converged(map[string]interface{}{"a": "A", "b": map[string]interface{}{"title": "B"}})
+
+	src.Update("posts/b", map[string]interface{}{"body": "text"})
+	converged(map[string]interface{}{"a": "A", "b": map[string]interface{}{"title": "B", "body": "text"}})
+
+	src.Delete("posts/a")
+	converged(map[string]interface{}{"b": map[string]interface{}{"title": "B", "body": "text"}})
+
+	stats := r.Stats()
+	assert.True(t, stats.Events >= 3)
+	assert.Equal(t, int64(0), stats.Errors)
+	assert.False(t, stats.LastApplied.IsZero())
+}
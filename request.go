@@ -0,0 +1,185 @@
+package firego
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestOption configures a single call, such as Value or Set, without
+// changing the reference it is made on. Unlike the query methods, which
+// share the reference's parameters, request options are safe to use when
+// the same reference is used concurrently with different settings.
+type RequestOption func(*request)
+
+// request holds what the RequestOptions of a call configured.
+type request struct {
+	params map[string]string
+	header http.Header
+	etag   *string
+
+	// backpressure and pending configure watches, see WithBackpressure
+	backpressure Backpressure
+	pending      int
+	// monitor collects the stats of watches, see WithWatchMonitor
+	monitor *WatchMonitor
+	// pollInterval makes watches poll the location, see WithPolling
+	pollInterval time.Duration
+}
+
+// WithParam sets the query parameter key to value for the call.
+func WithParam(key, value string) RequestOption {
+	return func(r *request) {
+		r.params[key] = value
+	}
+}
+
+// WithShallow makes the call only return the keys of the children of an
+// object, see Shallow.
+func WithShallow() RequestOption {
+	return WithParam(shallowParam, "true")
+}
+
+// WithOrderBy orders the children returned by the call, see OrderBy.
+func WithOrderBy(value string) RequestOption {
+	return WithParam(orderByParam, escapeString(value))
+}
+
+// WithStartAt only returns the children starting at value, see StartAt.
+func WithStartAt(value string) RequestOption {
+	return WithParam(startAtParam, escapeString(value))
+}
+
+// WithEndAt only returns the children ending at value, see EndAt.
+func WithEndAt(value string) RequestOption {
+	return WithParam(endAtParam, escapeString(value))
+}
+
+// WithEqualTo only returns the children equal to value, see EqualTo.
+func WithEqualTo(value string) RequestOption {
+	return WithParam(equalToParam, escapeString(value))
+}
+
+// WithStartAtValue is like WithStartAt but v is encoded as a JSON literal,
+// see StartAtValue.
+func WithStartAtValue(v interface{}) RequestOption {
+	return WithParam(startAtParam, jsonLiteral(v))
+}
+
+// WithEndAtValue is like WithEndAt but v is encoded as a JSON literal, see
+// StartAtValue.
+func WithEndAtValue(v interface{}) RequestOption {
+	return WithParam(endAtParam, jsonLiteral(v))
+}
+
+// WithEqualToValue is like WithEqualTo but v is encoded as a JSON literal,
+// see StartAtValue.
+func WithEqualToValue(v interface{}) RequestOption {
+	return WithParam(equalToParam, jsonLiteral(v))
+}
+
+// WithLimitToFirst only returns the first n children, see LimitToFirst.
+func WithLimitToFirst(n int64) RequestOption {
+	return WithParam(limitToFirstParam, strconv.FormatInt(n, 10))
+}
+
+// WithLimitToLast only returns the last n children, see LimitToLast.
+func WithLimitToLast(n int64) RequestOption {
+	return WithParam(limitToLastParam, strconv.FormatInt(n, 10))
+}
+
+// WithDownload makes Firebase send the value as an attachment named
+// filename, like a browser download.
+//
+// Reference https://firebase.google.com/docs/reference/rest/database#section-param-download
+func WithDownload(filename string) RequestOption {
+	return WithParam(downloadParam, filename)
+}
+
+// WithSilent makes Firebase answer a write, such as Set, Update or Remove,
+// with an empty response instead of echoing the written value, which saves
+// bandwidth for writers that do not need it. Since the response of a push
+// is empty too, the key of the new child is unknown and Push fails with
+// ErrSilentPush, use PushLocal instead.
+//
+// Reference https://firebase.google.com/docs/reference/rest/database#section-param-print
+func WithSilent() RequestOption {
+	return WithParam(printParam, silentVal)
+}
+
+// WithServerTimeout sets how long Firebase may spend on a read before
+// giving up on it, e.g. to give reads of very large locations more time
+// than the default. Firebase allows at most 15 minutes, d is rounded down
+// to the millisecond and a d of 0 or less keeps the default.
+//
+// Reference https://firebase.google.com/docs/reference/rest/database#section-param-timeout
+func WithServerTimeout(d time.Duration) RequestOption {
+	if d < time.Millisecond {
+		return nil
+	}
+	return WithParam(timeoutParam, serverTimeout(d))
+}
+
+// serverTimeout formats d the way the timeout parameter expects it.
+func serverTimeout(d time.Duration) string {
+	switch {
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "min"
+	case d%time.Second == 0:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+	return strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+}
+
+// WriteSize is the size limit of a write, see WithWriteSizeLimit.
+type WriteSize string
+
+// The write size limits Firebase supports, from the smallest to unlimited.
+// Writes are limited to large by default.
+const (
+	WriteSizeTiny      WriteSize = "tiny"
+	WriteSizeSmall     WriteSize = "small"
+	WriteSizeMedium    WriteSize = "medium"
+	WriteSizeLarge     WriteSize = "large"
+	WriteSizeUnlimited WriteSize = "unlimited"
+)
+
+// WithWriteSizeLimit sets the size of the writes, or deletes, Firebase
+// accepts for the call. Larger writes take longer, and block the database
+// while they run, so Firebase rejects the ones over the limit instead of
+// running them. WriteSizeUnlimited lets admin tools make writes the
+// default limit rejects.
+//
+// Reference https://firebase.google.com/docs/reference/rest/database#section-param-writesizelimit
+func WithWriteSizeLimit(size WriteSize) RequestOption {
+	return WithParam(writeSizeParam, string(size))
+}
+
+// WithETag asks Firebase for the ETag of the value at the location and
+// stores it in etag once the call succeeds, e.g. to detect later changes.
+func WithETag(etag *string) RequestOption {
+	return func(r *request) {
+		r.header.Set(etagHeader, "true")
+		r.etag = etag
+	}
+}
+
+// prepare applies opts, it returns the reference to send the request to
+// and the remaining request configuration. The reference is only copied
+// if opts change its parameters.
+func (fb *Firebase) prepare(opts []RequestOption) (*Firebase, *request) {
+	r := &request{params: map[string]string{}, header: http.Header{}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	if len(r.params) == 0 {
+		return fb, r
+	}
+	c := fb.copy()
+	for k, v := range r.params {
+		c.params.Set(k, v)
+	}
+	return c, r
+}
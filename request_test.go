@@ -0,0 +1,148 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestOptions(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(`{"a":true}`)
+	defer server.Close()
+
+	fb := New(server.URL)
+	var v map[string]interface{}
+	require.NoError(t, fb.Value(&v,
+		WithOrderBy("$key"),
+		WithStartAt("a"),
+		WithEndAt("7"),
+		WithLimitToFirst(2),
+		WithParam("print", "pretty"),
+	))
+	require.Len(t, server.receivedReqs, 1)
+	q := server.receivedReqs[0].URL.Query()
+	assert.Equal(t, `"$key"`, q.Get(orderByParam))
+	assert.Equal(t, `"a"`, q.Get(startAtParam))
+	assert.Equal(t, "7", q.Get(endAtParam))
+	assert.Equal(t, "2", q.Get(limitToFirstParam))
+	assert.Equal(t, "pretty", q.Get("print"))
+
+	require.NoError(t, fb.Value(&v, WithOrderBy("$key"), WithEqualTo("b"), WithLimitToLast(3)))
+	q = server.receivedReqs[1].URL.Query()
+	assert.Equal(t, `"b"`, q.Get(equalToParam))
+	assert.Equal(t, "3", q.Get(limitToLastParam))
+
+	require.NoError(t, fb.Value(&v, WithShallow()))
+	assert.Equal(t, "true", server.receivedReqs[2].URL.Query().Get(shallowParam))
+
+	// the reference itself is left alone
+	assert.Len(t, fb.params, 0)
+	require.NoError(t, fb.Set(1))
+	assert.Empty(t, server.receivedReqs[3].URL.RawQuery)
+}
+
+func TestWithETag(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get(etagHeader) == "true" {
+			w.Header().Set("ETag", "some-etag")
+		}
+		w.Write([]byte(`1`))
+	}))
+	defer server.Close()
+
+	var (
+		etag string
+		v    int
+	)
+	require.NoError(t, New(server.URL).Value(&v, WithETag(&etag)))
+	assert.Equal(t, "some-etag", etag)
+	assert.Equal(t, 1, v)
+}
+
+func TestWithSilent(t *testing.T) {
+	t.Parallel()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		queries = append(queries, req.URL.RawQuery)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	require.NoError(t, fb.Set(1, WithSilent()))
+	require.NoError(t, fb.Update(map[string]int{"a": 1}, WithSilent()))
+	require.NoError(t, fb.Remove(WithSilent()))
+	_, err := fb.Push(1, WithSilent())
+	assert.Equal(t, ErrSilentPush, err)
+	_, err = fb.PushLocal(1, WithSilent())
+	require.NoError(t, err)
+
+	require.Len(t, queries, 5)
+	for _, q := range queries {
+		assert.Equal(t, "print=silent", q)
+	}
+}
+
+func TestWithServerTimeout(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(`1`)
+	defer server.Close()
+
+	fb := New(server.URL)
+	var v int
+	require.NoError(t, fb.Value(&v, WithServerTimeout(15*time.Second)))
+	require.NoError(t, fb.Value(&v, WithServerTimeout(0)))
+	require.Len(t, server.receivedReqs, 2)
+	assert.Equal(t, "15s", server.receivedReqs[0].URL.Query().Get(timeoutParam))
+	assert.Empty(t, server.receivedReqs[1].URL.RawQuery)
+
+	for d, want := range map[time.Duration]string{
+		3 * time.Minute:         "3min",
+		90 * time.Second:        "90s",
+		1500 * time.Millisecond: "1500ms",
+		time.Millisecond + 1:    "1ms",
+	} {
+		assert.Equal(t, want, serverTimeout(d), d.String())
+	}
+}
+
+func TestWithWriteSizeLimit(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(`null`)
+	defer server.Close()
+
+	fb := New(server.URL)
+	require.NoError(t, fb.Remove(WithWriteSizeLimit(WriteSizeUnlimited)))
+	require.NoError(t, fb.Set(1, WithWriteSizeLimit(WriteSizeTiny)))
+	require.Len(t, server.receivedReqs, 2)
+	assert.Equal(t, "unlimited", server.receivedReqs[0].URL.Query().Get(writeSizeParam))
+	assert.Equal(t, "tiny", server.receivedReqs[1].URL.Query().Get(writeSizeParam))
+}
+
+func TestRequestOptionsConcurrent(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`null`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	var wg sync.WaitGroup
+	for i := int64(1); i <= 10; i++ {
+		wg.Add(1)
+		go func(n int64) {
+			defer wg.Done()
+			var v interface{}
+			assert.NoError(t, fb.Value(&v, WithOrderBy("$key"), WithLimitToFirst(n)))
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, fb.params, 0)
+}
@@ -0,0 +1,294 @@
+/*
+Package retention enforces declarative retention rules on Firebase
+locations, e.g. deleting chat messages older than 30 days or keeping only
+the 1000 most recent log entries.
+*/
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zabawaba99/firego"
+)
+
+const (
+	// DefaultInterval is the default time between pruning passes.
+	DefaultInterval = time.Hour
+	// DefaultBatchSize is the default number of children deleted per
+	// request.
+	DefaultBatchSize = 100
+)
+
+// Rule declares which children of the locations matching Path are
+// deleted.
+type Rule struct {
+	// Path of the locations whose children are pruned, relative to the
+	// Pruner's root. A "*" segment matches any key, but the first
+	// segment must be a key.
+	Path string
+	// Field holds the time a child was created in milliseconds since
+	// the epoch, such as a Firebase server timestamp. When empty
+	// children are ordered by key, which is chronological for pushed
+	// children.
+	Field string
+	// MaxAge deletes the children whose Field is older, it requires
+	// Field to be set. Children without the field are kept.
+	MaxAge time.Duration
+	// MaxChildren deletes the oldest children exceeding it. Children
+	// without Field are considered the oldest.
+	MaxChildren int
+}
+
+// Stats describes the work done by a Pruner.
+type Stats struct {
+	// Passes is the number of completed pruning passes.
+	Passes int64
+	// Deleted is the number of deleted children.
+	Deleted int64
+	// Errors is the number of failed pruning passes.
+	Errors int64
+	// LastPass is when the last pass completed.
+	LastPass time.Time
+}
+
+// Pruner enforces retention rules.
+type Pruner struct {
+	// Root is the reference the rule paths are relative to.
+	Root *firego.Firebase
+	// Rules to enforce.
+	Rules []Rule
+	// Interval between pruning passes, it defaults to DefaultInterval.
+	Interval time.Duration
+	// BatchSize is the number of children deleted per request, it
+	// defaults to DefaultBatchSize.
+	BatchSize int
+	// Rate limits the number of deleted children per second, zero does
+	// not limit it.
+	Rate float64
+	// OnError, if set, is called whenever a pass fails.
+	OnError func(error)
+
+	mtx   sync.Mutex
+	stats Stats
+	now   func() time.Time
+}
+
+// Stats returns the work done so far.
+func (p *Pruner) Stats() Stats {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.stats
+}
+
+// Run prunes every Interval until ctx is done, it always returns the
+// context's error.
+func (p *Pruner) Run(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Prune(ctx); err != nil && ctx.Err() == nil && p.OnError != nil {
+			p.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune enforces every rule once.
+func (p *Pruner) Prune(ctx context.Context) error {
+	err := p.prune(ctx)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if err != nil {
+		p.stats.Errors++
+		return err
+	}
+	p.stats.Passes++
+	p.stats.LastPass = p.clock()
+	return nil
+}
+
+func (p *Pruner) prune(ctx context.Context) error {
+	for _, r := range p.Rules {
+		if r.MaxAge > 0 && r.Field == "" {
+			return errors.New("retention rule with a max age needs a field")
+		}
+		locations, err := p.expand(strings.Trim(r.Path, "/"))
+		if err != nil {
+			return err
+		}
+		for _, loc := range locations {
+			if err := p.enforce(ctx, r, loc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expand returns the locations matching the path pattern.
+func (p *Pruner) expand(pattern string) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+	if segments[0] == "" || segments[0] == "*" {
+		return nil, errors.New("retention rule path must start with a key")
+	}
+
+	locations := []string{segments[0]}
+	for _, seg := range segments[1:] {
+		if seg != "*" {
+			for i := range locations {
+				locations[i] += "/" + seg
+			}
+			continue
+		}
+
+		var expanded []string
+		for _, loc := range locations {
+			keys, err := p.keys(p.Root.Child(loc))
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range keys {
+				expanded = append(expanded, loc+"/"+k)
+			}
+		}
+		locations = expanded
+	}
+	return locations, nil
+}
+
+// keys returns the keys of the children of ref, which is made shallow.
+func (p *Pruner) keys(ref *firego.Firebase) ([]string, error) {
+	ref.Shallow(true)
+	var v map[string]interface{}
+	if err := ref.Value(&v); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (p *Pruner) enforce(ctx context.Context, r Rule, loc string) error {
+	ref := p.Root.Child(loc)
+	orderBy := r.Field
+	if orderBy == "" {
+		orderBy = "$key"
+	}
+
+	if r.MaxAge > 0 {
+		cutoff := p.clock().Add(-r.MaxAge).UnixNano() / int64(time.Millisecond)
+		for {
+			// starting at 0 leaves out the children without the field
+			expired, err := childKeys(ref.OrderBy(orderBy).StartAt("0").EndAt(strconv.FormatInt(cutoff, 10)).LimitToFirst(int64(p.batchSize())))
+			if err != nil {
+				return err
+			}
+			if err := p.delete(ctx, ref, expired); err != nil {
+				return err
+			}
+			if len(expired) < p.batchSize() {
+				break
+			}
+		}
+	}
+
+	if r.MaxChildren > 0 {
+		keys, err := p.keys(p.Root.Child(loc))
+		if err != nil {
+			return err
+		}
+		if excess := len(keys) - r.MaxChildren; excess > 0 {
+			oldest, err := childKeys(ref.OrderBy(orderBy).LimitToFirst(int64(excess)))
+			if err != nil {
+				return err
+			}
+			if err := p.delete(ctx, ref, oldest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// delete removes the children of ref in rate limited batches.
+func (p *Pruner) delete(ctx context.Context, ref *firego.Firebase, keys []string) error {
+	for len(keys) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := p.batchSize()
+		if n > len(keys) {
+			n = len(keys)
+		}
+		update := make(map[string]interface{}, n)
+		for _, k := range keys[:n] {
+			update[k] = nil
+		}
+		if err := ref.Update(update); err != nil {
+			return err
+		}
+		keys = keys[n:]
+
+		p.mtx.Lock()
+		p.stats.Deleted += int64(n)
+		p.mtx.Unlock()
+
+		if p.Rate > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(float64(n) / p.Rate * float64(time.Second))):
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Pruner) batchSize() int {
+	if p.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return p.BatchSize
+}
+
+func (p *Pruner) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+// childKeys returns the keys of the children returned by the query.
+func childKeys(query *firego.Firebase) ([]string, error) {
+	var v map[string]json.RawMessage
+	if err := query.Value(&v); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
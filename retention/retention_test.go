@@ -0,0 +1,253 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+)
+
+// queryServer is a fake Firebase that understands the queries and
+// deletes made by a Pruner.
+type queryServer struct {
+	*httptest.Server
+
+	mtx     sync.Mutex
+	data    map[string]interface{}
+	patches int
+}
+
+func newQueryServer(t *testing.T, data map[string]interface{}) *queryServer {
+	s := &queryServer{data: data}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		node := s.data
+		for _, seg := range strings.Split(strings.Trim(strings.TrimSuffix(req.URL.Path, ".json"), "/"), "/") {
+			if seg == "" {
+				continue
+			}
+			node, _ = node[seg].(map[string]interface{})
+		}
+
+		if req.Method == "PATCH" {
+			s.patches++
+			var update map[string]interface{}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&update))
+			for k, v := range update {
+				assert.Nil(t, v)
+				delete(node, k)
+			}
+			w.Write([]byte(`{}`))
+			return
+		}
+
+		q := req.URL.Query()
+		if q.Get("shallow") == "true" {
+			shallow := map[string]bool{}
+			for k := range node {
+				shallow[k] = true
+			}
+			json.NewEncoder(w).Encode(shallow)
+			return
+		}
+
+		var orderBy string
+		require.NoError(t, json.Unmarshal([]byte(q.Get("orderBy")), &orderBy))
+		value := func(k string) float64 {
+			if orderBy == "$key" {
+				return 0
+			}
+			if f, ok := node[k].(map[string]interface{})[orderBy].(float64); ok {
+				return f
+			}
+			return -1
+		}
+
+		var keys []string
+		for k := range node {
+			if s := q.Get("startAt"); s != "" {
+				start, _ := strconv.ParseFloat(s, 64)
+				if value(k) < start {
+					continue
+				}
+			}
+			if e := q.Get("endAt"); e != "" {
+				end, _ := strconv.ParseFloat(e, 64)
+				if value(k) > end {
+					continue
+				}
+			}
+			keys = append(keys, k)
+		}
+		sort.Sort(byValue{keys, value})
+		if l := q.Get("limitToFirst"); l != "" {
+			n, _ := strconv.Atoi(l)
+			if len(keys) > n {
+				keys = keys[:n]
+			}
+		}
+
+		result := map[string]interface{}{}
+		for _, k := range keys {
+			result[k] = node[k]
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	return s
+}
+
+type byValue struct {
+	keys  []string
+	value func(string) float64
+}
+
+func (b byValue) Len() int      { return len(b.keys) }
+func (b byValue) Swap(i, j int) { b.keys[i], b.keys[j] = b.keys[j], b.keys[i] }
+func (b byValue) Less(i, j int) bool {
+	if vi, vj := b.value(b.keys[i]), b.value(b.keys[j]); vi != vj {
+		return vi < vj
+	}
+	return b.keys[i] < b.keys[j]
+}
+
+func (s *queryServer) keys(path ...string) []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	node := s.data
+	for _, seg := range path {
+		node, _ = node[seg].(map[string]interface{})
+	}
+	var keys []string
+	for k := range node {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var now = time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func ms(d time.Duration) float64 {
+	return float64(now.Add(-d).UnixNano() / int64(time.Millisecond))
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	t.Parallel()
+	server := newQueryServer(t, map[string]interface{}{
+		"rooms": map[string]interface{}{
+			"r1": map[string]interface{}{"messages": map[string]interface{}{
+				"m1": map[string]interface{}{"createdAt": ms(48 * time.Hour)},
+				"m2": map[string]interface{}{"createdAt": ms(25 * time.Hour)},
+				"m3": map[string]interface{}{"createdAt": ms(time.Hour)},
+				"m4": map[string]interface{}{"text": "no timestamp"},
+			}},
+			"r2": map[string]interface{}{"messages": map[string]interface{}{
+				"m1": map[string]interface{}{"createdAt": ms(72 * time.Hour)},
+			}},
+		},
+	})
+	defer server.Close()
+
+	p := &Pruner{
+		Root:      firego.New(server.URL),
+		Rules:     []Rule{{Path: "rooms/*/messages", Field: "createdAt", MaxAge: 24 * time.Hour}},
+		BatchSize: 1,
+		now:       func() time.Time { return now },
+	}
+	require.NoError(t, p.Prune(context.Background()))
+
+	assert.Equal(t, []string{"m3", "m4"}, server.keys("rooms", "r1", "messages"))
+	assert.Empty(t, server.keys("rooms", "r2", "messages"))
+	assert.Equal(t, 3, server.patches)
+
+	stats := p.Stats()
+	assert.Equal(t, int64(1), stats.Passes)
+	assert.Equal(t, int64(3), stats.Deleted)
+	assert.Equal(t, now, stats.LastPass)
+}
+
+func TestPruneMaxChildren(t *testing.T) {
+	t.Parallel()
+	logs := map[string]interface{}{}
+	for i := 0; i < 10; i++ {
+		logs["-K"+strconv.Itoa(i)] = "entry"
+	}
+	server := newQueryServer(t, map[string]interface{}{"logs": logs})
+	defer server.Close()
+
+	p := &Pruner{
+		Root:  firego.New(server.URL),
+		Rules: []Rule{{Path: "/logs/", MaxChildren: 3}},
+	}
+	require.NoError(t, p.Prune(context.Background()))
+
+	assert.Equal(t, []string{"-K7", "-K8", "-K9"}, server.keys("logs"))
+	assert.Equal(t, 1, server.patches)
+}
+
+func TestPruneRate(t *testing.T) {
+	t.Parallel()
+	logs := map[string]interface{}{}
+	for i := 0; i < 4; i++ {
+		logs["-K"+strconv.Itoa(i)] = "entry"
+	}
+	server := newQueryServer(t, map[string]interface{}{"logs": logs})
+	defer server.Close()
+
+	p := &Pruner{
+		Root:      firego.New(server.URL),
+		Rules:     []Rule{{Path: "logs", MaxChildren: 1}},
+		BatchSize: 1,
+		Rate:      100,
+	}
+	start := time.Now()
+	require.NoError(t, p.Prune(context.Background()))
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+	assert.Equal(t, 3, server.patches)
+}
+
+func TestPruneInvalidRules(t *testing.T) {
+	t.Parallel()
+	for _, r := range []Rule{
+		{Path: "*/messages", MaxChildren: 1},
+		{Path: "", MaxChildren: 1},
+		{Path: "messages", MaxAge: time.Hour},
+	} {
+		p := &Pruner{Root: firego.New("https://somefirebaseapp.firebaseio.com"), Rules: []Rule{r}}
+		assert.Error(t, p.Prune(context.Background()), "%#v", r)
+		assert.Equal(t, int64(1), p.Stats().Errors)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	server := newQueryServer(t, map[string]interface{}{})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pruner{
+		Root:     firego.New(server.URL),
+		Rules:    []Rule{{Path: "logs", MaxChildren: 1}},
+		Interval: 5 * time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+	for p.Stats().Passes < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
@@ -0,0 +1,342 @@
+package firego
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route event types.
+const (
+	// RouteCreated is the type of a RouteEvent for a location that did
+	// not exist before.
+	RouteCreated = "created"
+	// RouteUpdated is the type of a RouteEvent for a location whose value
+	// changed.
+	RouteUpdated = "updated"
+	// RouteDeleted is the type of a RouteEvent for a location that was
+	// removed.
+	RouteDeleted = "deleted"
+)
+
+// RouteEvent describes a change to a location matching the pattern of a
+// route.
+type RouteEvent struct {
+	// Type is one of RouteCreated, RouteUpdated or RouteDeleted.
+	Type string
+	// Path of the location, relative to the Router's reference.
+	Path string
+	// Vars holds the keys matched by the variables of the pattern.
+	Vars map[string]string
+	// Before is the value of the location before the change, nil if it
+	// did not exist.
+	Before interface{}
+	// After is the value of the location after the change, nil if it was
+	// removed.
+	After interface{}
+}
+
+// RouteHandler handles the changes to the locations matching a route.
+type RouteHandler func(e RouteEvent)
+
+type route struct {
+	pattern []string
+	handler RouteHandler
+	// values holds the value of every matching location that exists
+	values map[string]interface{}
+}
+
+// Router watches a reference and dispatches the changes to the locations
+// matching the registered patterns to their handlers, like database
+// triggers running inside the process. The Router owns the watch on the
+// reference.
+//
+// Patterns are relative to the reference and segments in braces are
+// variables matching any key, e.g. "orders/{orderID}/items/{itemID}".
+type Router struct {
+	// SkipExisting records the locations that exist when the Router starts
+	// without calling their handlers, so that only later changes are
+	// dispatched.
+	SkipExisting bool
+	// RetryInterval between attempts to re-establish the watch, it
+	// defaults to 5 seconds.
+	RetryInterval time.Duration
+	// OnError, if set, is called with the errors encountered in the
+	// background.
+	OnError func(error)
+
+	ref *Firebase
+
+	mtx    sync.Mutex
+	routes []*route
+	// started is set once the first snapshot was applied
+	started bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRouter creates a Router for ref.
+func NewRouter(ref *Firebase) *Router {
+	return &Router{ref: ref.location()}
+}
+
+// Handle registers h for the locations matching pattern. Handlers should be
+// registered before the Router is started, as the locations that already
+// exist are otherwise reported as created.
+func (r *Router) Handle(pattern string, h RouteHandler) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.routes = append(r.routes, &route{
+		pattern: splitPath(pattern),
+		handler: h,
+		values:  map[string]interface{}{},
+	})
+}
+
+// Start starts watching the reference in the background.
+func (r *Router) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run()
+}
+
+// Stop tears down the watch.
+func (r *Router) Stop() {
+	close(r.stop)
+	r.ref.StopWatching()
+	<-r.done
+}
+
+func (r *Router) run() {
+	defer close(r.done)
+
+	retry := r.RetryInterval
+	if retry <= 0 {
+		retry = 5 * time.Second
+	}
+
+	for {
+		notifications := make(chan Event)
+		if err := r.ref.Watch(notifications); err != nil {
+			r.report(err)
+		} else {
+			select {
+			case <-r.stop:
+				// Stop may have run before the watch was established
+				r.ref.StopWatching()
+			default:
+			}
+			for event := range notifications {
+				r.apply(event)
+			}
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+func (r *Router) apply(event Event) {
+	path := splitPath(event.Path)
+	switch event.Type {
+	case EventTypePut:
+		r.write(path, event.Data)
+	case EventTypePatch:
+		data, _ := event.Data.(map[string]interface{})
+		for _, k := range sortedKeys(data) {
+			r.write(append(path[:len(path):len(path)], splitPath(k)...), data[k])
+		}
+	case EventTypeError:
+		r.report(event.Data.(error))
+	}
+}
+
+// write dispatches the changes caused by a write of v at path.
+func (r *Router) write(path []string, v interface{}) {
+	r.mtx.Lock()
+	skip := r.SkipExisting && !r.started
+	r.started = true
+	var calls []func()
+	for _, rt := range r.routes {
+		for _, e := range rt.changes(path, v) {
+			if !skip {
+				h, e := rt.handler, e
+				calls = append(calls, func() { h(e) })
+			}
+		}
+	}
+	r.mtx.Unlock()
+
+	// handlers run without the lock so they can register more routes
+	for _, call := range calls {
+		call()
+	}
+}
+
+// changes returns the events for the matching locations affected by a
+// write of v at path, in order, and records the new values.
+func (rt *route) changes(path []string, v interface{}) []RouteEvent {
+	n := len(path)
+	if n > len(rt.pattern) {
+		n = len(rt.pattern)
+	}
+	if !matchRoute(rt.pattern[:n], path[:n]) {
+		return nil
+	}
+
+	if len(path) >= len(rt.pattern) {
+		// written at or below a matching location
+		loc := path[:len(rt.pattern)]
+		key := strings.Join(loc, "/")
+		before := rt.values[key]
+		after := withPath(before, path[len(rt.pattern):], v)
+		if e, ok := rt.change(loc, before, after); ok {
+			return []RouteEvent{e}
+		}
+		return nil
+	}
+
+	// written above the matching locations, which are all replaced
+	affected := map[string][]string{}
+	prefix := strings.Join(path, "/")
+	for key := range rt.values {
+		if prefix == "" || strings.HasPrefix(key, prefix+"/") {
+			affected[key] = splitPath(key)
+		}
+	}
+	for _, loc := range expandRoute(path, rt.pattern[len(path):], v) {
+		affected[strings.Join(loc, "/")] = loc
+	}
+
+	keys := make([]string, 0, len(affected))
+	for key := range affected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var events []RouteEvent
+	for _, key := range keys {
+		loc := affected[key]
+		after := valueAt(v, loc[len(path):])
+		if e, ok := rt.change(loc, rt.values[key], after); ok {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// change records the new value of a matching location and returns the
+// event for it, ok is false if the value did not change.
+func (rt *route) change(loc []string, before, after interface{}) (e RouteEvent, ok bool) {
+	if reflect.DeepEqual(before, after) {
+		return e, false
+	}
+	e = RouteEvent{
+		Type:   RouteUpdated,
+		Path:   strings.Join(loc, "/"),
+		Vars:   map[string]string{},
+		Before: before,
+		After:  after,
+	}
+	switch {
+	case before == nil:
+		e.Type = RouteCreated
+	case after == nil:
+		e.Type = RouteDeleted
+	}
+	for i, seg := range rt.pattern {
+		if name, ok := routeVar(seg); ok {
+			e.Vars[name] = loc[i]
+		}
+	}
+
+	if after == nil {
+		delete(rt.values, e.Path)
+	} else {
+		rt.values[e.Path] = after
+	}
+	return e, true
+}
+
+// expandRoute returns the locations matching the rest of a pattern that
+// exist in v, the value at prefix.
+func expandRoute(prefix, rest []string, v interface{}) [][]string {
+	if v == nil {
+		return nil
+	}
+	if len(rest) == 0 {
+		return [][]string{prefix}
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var locs [][]string
+	for k, child := range m {
+		if _, isVar := routeVar(rest[0]); isVar || rest[0] == k {
+			locs = append(locs, expandRoute(append(prefix[:len(prefix):len(prefix)], k), rest[1:], child)...)
+		}
+	}
+	return locs
+}
+
+func matchRoute(pattern, path []string) bool {
+	for i, seg := range pattern {
+		if _, isVar := routeVar(seg); !isVar && seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// routeVar returns the name of a variable pattern segment.
+func routeVar(seg string) (string, bool) {
+	if len(seg) < 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+		return "", false
+	}
+	return seg[1 : len(seg)-1], true
+}
+
+// valueAt returns the value at path below v.
+func valueAt(v interface{}, path []string) interface{} {
+	for _, seg := range path {
+		m, _ := v.(map[string]interface{})
+		v = m[seg]
+	}
+	return v
+}
+
+// withPath returns a copy of v with the value at path replaced by child,
+// v itself is left untouched since it was handed to handlers before.
+func withPath(v interface{}, path []string, child interface{}) interface{} {
+	if len(path) == 0 {
+		return child
+	}
+	m, _ := v.(map[string]interface{})
+	c := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	if next := withPath(m[path[0]], path[1:], child); next != nil {
+		c[path[0]] = next
+	} else {
+		delete(c, path[0])
+	}
+	if len(c) == 0 {
+		return nil
+	}
+	return c
+}
+
+func (r *Router) report(err error) {
+	if err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+}
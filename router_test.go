@@ -0,0 +1,147 @@
+package firego
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestRouterDispatch(t *testing.T) {
+	t.Parallel()
+	r := NewRouter(New("https://example.firebaseio.com"))
+	var events []RouteEvent
+	r.Handle("orders/{orderID}/items/{itemID}", func(e RouteEvent) {
+		events = append(events, e)
+	})
+
+	// the snapshot sent when the watch is established
+	r.apply(Event{Type: "put", Path: "/", Data: map[string]interface{}{
+		"orders": map[string]interface{}{
+			"o1": map[string]interface{}{
+				"items":  map[string]interface{}{"i1": map[string]interface{}{"qty": 1.0}},
+				"status": "new",
+			},
+		},
+	}})
+	require.Len(t, events, 1)
+	assert.Equal(t, RouteEvent{
+		Type:  RouteCreated,
+		Path:  "orders/o1/items/i1",
+		Vars:  map[string]string{"orderID": "o1", "itemID": "i1"},
+		After: map[string]interface{}{"qty": 1.0},
+	}, events[0])
+
+	// writes below a location carry its whole value
+	events = nil
+	r.apply(Event{Type: "put", Path: "/orders/o1/items/i1/qty", Data: 2.0})
+	require.Len(t, events, 1)
+	assert.Equal(t, RouteUpdated, events[0].Type)
+	assert.Equal(t, map[string]interface{}{"qty": 1.0}, events[0].Before)
+	assert.Equal(t, map[string]interface{}{"qty": 2.0}, events[0].After)
+
+	// writes elsewhere are not dispatched
+	events = nil
+	r.apply(Event{Type: "put", Path: "/orders/o1/status", Data: "paid"})
+	assert.Empty(t, events)
+
+	events = nil
+	r.apply(Event{Type: "patch", Path: "/orders", Data: map[string]interface{}{
+		"o1/items/i1": nil,
+		"o2/items/i9": "gift",
+	}})
+	require.Len(t, events, 2)
+	assert.Equal(t, RouteDeleted, events[0].Type)
+	assert.Equal(t, "orders/o1/items/i1", events[0].Path)
+	assert.Nil(t, events[0].After)
+	assert.Equal(t, RouteCreated, events[1].Type)
+	assert.Equal(t, map[string]string{"orderID": "o2", "itemID": "i9"}, events[1].Vars)
+
+	// a snapshot after reconnecting only dispatches what changed
+	events = nil
+	r.apply(Event{Type: "put", Path: "/", Data: map[string]interface{}{
+		"orders": map[string]interface{}{
+			"o2": map[string]interface{}{"items": map[string]interface{}{"i9": "gift", "i10": "card"}},
+		},
+	}})
+	require.Len(t, events, 1)
+	assert.Equal(t, RouteCreated, events[0].Type)
+	assert.Equal(t, "orders/o2/items/i10", events[0].Path)
+
+	// removing a parent deletes every location below it
+	events = nil
+	r.apply(Event{Type: "put", Path: "/orders/o2", Data: nil})
+	require.Len(t, events, 2)
+	assert.Equal(t, "orders/o2/items/i10", events[0].Path)
+	assert.Equal(t, "orders/o2/items/i9", events[1].Path)
+	assert.Equal(t, RouteDeleted, events[1].Type)
+	assert.Equal(t, "gift", events[1].Before)
+}
+
+func TestRouterSkipExisting(t *testing.T) {
+	t.Parallel()
+	r := NewRouter(New("https://example.firebaseio.com"))
+	r.SkipExisting = true
+	var paths []string
+	r.Handle("users/{uid}", func(e RouteEvent) {
+		paths = append(paths, e.Type+" "+e.Path)
+	})
+
+	r.apply(Event{Type: "put", Path: "/", Data: map[string]interface{}{
+		"users": map[string]interface{}{"a": 1.0},
+	}})
+	assert.Empty(t, paths)
+
+	r.apply(Event{Type: "put", Path: "/users/a", Data: 2.0})
+	r.apply(Event{Type: "put", Path: "/users/b", Data: 1.0})
+	assert.Equal(t, []string{"updated users/a", "created users/b"}, paths)
+}
+
+func TestRouterWatch(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("orders/o1/total", 10)
+
+	r := NewRouter(New(server.URL))
+	var (
+		mtx    sync.Mutex
+		events []RouteEvent
+	)
+	r.Handle("orders/{id}", func(e RouteEvent) {
+		mtx.Lock()
+		events = append(events, e)
+		mtx.Unlock()
+	})
+	r.Start()
+	defer r.Stop()
+
+	received := func(n int) bool {
+		for i := 0; i < 100; i++ {
+			mtx.Lock()
+			got := len(events)
+			mtx.Unlock()
+			if got >= n {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	require.True(t, received(1))
+
+	server.Set("orders/o2/total", 20)
+	require.True(t, received(2))
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, "o1", events[0].Vars["id"])
+	assert.Equal(t, RouteCreated, events[1].Type)
+	assert.Equal(t, map[string]string{"id": "o2"}, events[1].Vars)
+	assert.Equal(t, map[string]interface{}{"total": 20.0}, events[1].After)
+}
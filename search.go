@@ -0,0 +1,122 @@
+package firego
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchIndex maintains an inverted index of the words found in chosen
+// string fields of records, stored as <index>/<term>/<key> = true, and
+// answers prefix-term lookups against it.
+type SearchIndex struct {
+	ref    *Firebase
+	fields []string
+}
+
+// NewSearchIndex creates a SearchIndex stored at ref that indexes the
+// given fields. Nested fields are separated by a "/".
+func NewSearchIndex(ref *Firebase, fields ...string) *SearchIndex {
+	return &SearchIndex{ref: ref, fields: fields}
+}
+
+// Add indexes the terms of the record identified by key.
+func (s *SearchIndex) Add(key string, v interface{}) error {
+	return s.Update(key, nil, v)
+}
+
+// Remove deletes the terms of the record identified by key from the index.
+func (s *SearchIndex) Remove(key string, v interface{}) error {
+	return s.Update(key, v, nil)
+}
+
+// Update re-indexes the record identified by key after it changed from
+// prev to next, removing terms that no longer appear in it. Either value
+// may be nil.
+func (s *SearchIndex) Update(key string, prev, next interface{}) error {
+	prevTerms, err := s.terms(prev)
+	if err != nil {
+		return err
+	}
+	nextTerms, err := s.terms(next)
+	if err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{}
+	for term := range prevTerms {
+		if !nextTerms[term] {
+			update[EscapeKey(term)+"/"+key] = nil
+		}
+	}
+	for term := range nextTerms {
+		update[EscapeKey(term)+"/"+key] = true
+	}
+
+	if len(update) == 0 {
+		return nil
+	}
+	return s.ref.Update(update)
+}
+
+// Search returns the keys of the records that contain, for every word in
+// query, a term starting with that word. Keys are returned sorted.
+func (s *SearchIndex) Search(query string) ([]string, error) {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var matches map[string]bool
+	for _, word := range words {
+		prefix := EscapeKey(word)
+		var terms map[string]map[string]bool
+		err := s.ref.OrderByKey().StartAtValue(prefix).EndAtValue(prefix + "\uf8ff").Value(&terms)
+		if err != nil {
+			return nil, err
+		}
+
+		found := map[string]bool{}
+		for _, keys := range terms {
+			for k := range keys {
+				if matches == nil || matches[k] {
+					found[k] = true
+				}
+			}
+		}
+		matches = found
+	}
+
+	keys := make([]string, 0, len(matches))
+	for k := range matches {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *SearchIndex) terms(v interface{}) (map[string]bool, error) {
+	terms := map[string]bool{}
+	if v == nil {
+		return terms, nil
+	}
+
+	n, err := normalize(v)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range s.fields {
+		str, _ := lookup(n, field).(string)
+		for _, term := range Tokenize(str) {
+			terms[term] = true
+		}
+	}
+	return terms, nil
+}
+
+// Tokenize splits s into the lower cased words used as search terms.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
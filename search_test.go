@@ -0,0 +1,80 @@
+package firego
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestSearchIndexUpdate(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	idx := NewSearchIndex(New(server.URL).Child("search/users"), "name", "profile/city")
+	require.NoError(t, idx.Add("u1", map[string]interface{}{
+		"name":    "Ada Lovelace",
+		"profile": map[string]string{"city": "London"},
+	}))
+	assert.Equal(t, map[string]interface{}{
+		"ada":      map[string]interface{}{"u1": true},
+		"lovelace": map[string]interface{}{"u1": true},
+		"london":   map[string]interface{}{"u1": true},
+	}, server.Get("search/users"))
+
+	require.NoError(t, idx.Update("u1",
+		map[string]interface{}{"name": "Ada Lovelace"},
+		map[string]interface{}{"name": "Ada King"},
+	))
+	v := server.Get("search/users")
+	assert.Contains(t, v, "king")
+	assert.NotContains(t, v, "lovelace")
+
+	require.NoError(t, idx.Remove("u1", map[string]interface{}{
+		"name":    "Ada King",
+		"profile": map[string]string{"city": "London"},
+	}))
+	assert.Nil(t, server.Get("search/users"))
+}
+
+func TestSearchIndexSearch(t *testing.T) {
+	t.Parallel()
+	responses := map[string]string{
+		`"ad"`: `{"ada":{"u1":true,"u2":true},"adam":{"u3":true}}`,
+		`"lo"`: `{"lovelace":{"u1":true},"london":{"u3":true}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		assert.Equal(t, `"$key"`, q.Get(orderByParam))
+		start, err := unquote(q.Get(startAtParam))
+		require.NoError(t, err)
+		end, err := unquote(q.Get(endAtParam))
+		require.NoError(t, err)
+		assert.Equal(t, start+"\uf8ff", end)
+		var resp interface{}
+		require.NoError(t, json.Unmarshal([]byte(responses[q.Get(startAtParam)]), &resp))
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	idx := NewSearchIndex(New(server.URL), "name")
+	keys, err := idx.Search("Ad")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1", "u2", "u3"}, keys)
+
+	keys, err = idx.Search("ad lo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1", "u3"}, keys)
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"hello", "wörld", "42"}, Tokenize("Hello, Wörld! 42"))
+	assert.Empty(t, Tokenize(" .,- "))
+}
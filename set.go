@@ -1,13 +1,53 @@
 package firego
 
-import "encoding/json"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+)
 
 // Set the value of the Firebase reference.
-func (fb *Firebase) Set(v interface{}) error {
-	bytes, err := json.Marshal(v)
+func (fb *Firebase) Set(v interface{}, opts ...RequestOption) error {
+	return fb.SetContext(context.Background(), v, opts...)
+}
+
+// SetContext is like Set but the request is canceled when ctx is done.
+func (fb *Firebase) SetContext(ctx context.Context, v interface{}, opts ...RequestOption) error {
+	b, err := fb.encode(v)
+	if err != nil {
+		return err
+	}
+	_, err = fb.doRequest(ctx, "PUT", bytes.NewReader(b), opts)
+	return err
+}
+
+// SetReader is like Set but the JSON encoded value is read from r and
+// streamed to Firebase, instead of being held in memory. If the reference
+// has codecs, the value has to be decoded for them to be applied and is
+// buffered.
+func (fb *Firebase) SetReader(r io.Reader, opts ...RequestOption) error {
+	return fb.SetReaderContext(context.Background(), r, opts...)
+}
+
+// SetReaderContext is like SetReader but the request is canceled when ctx
+// is done.
+func (fb *Firebase) SetReaderContext(ctx context.Context, r io.Reader, opts ...RequestOption) error {
+	r, err := fb.encodeReader(r)
 	if err != nil {
 		return err
 	}
-	_, err = fb.doRequest("PUT", bytes)
+	_, err = fb.doRequest(ctx, "PUT", r, opts)
 	return err
 }
+
+// SetRaw is like Set but the value is already JSON encoded.
+func (fb *Firebase) SetRaw(raw json.RawMessage, opts ...RequestOption) error {
+	return fb.SetRawContext(context.Background(), raw, opts...)
+}
+
+// SetRawContext is like SetRaw but the request is canceled when ctx is
+// done.
+func (fb *Firebase) SetRawContext(ctx context.Context, raw json.RawMessage, opts ...RequestOption) error {
+	return fb.SetReaderContext(ctx, bytes.NewReader(raw), opts...)
+}
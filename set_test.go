@@ -1,6 +1,7 @@
 package firego
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,10 +17,30 @@ func TestSet(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 	err := fb.Set(payload)
 	assert.NoError(t, err)
 
 	v := server.Get("")
 	assert.Equal(t, payload, v)
 }
+
+func TestSetReader(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	err := fb.Child("streamed").SetReader(strings.NewReader(`{"foo":"bar"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, server.Get("streamed"))
+
+	// codecs are still applied
+	err = fb.Child("encoded").WithCodec(suffixCodec("-a")).SetReader(strings.NewReader(`"hello"`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello-a", server.Get("encoded"))
+
+	err = fb.Child("invalid").WithCodec(suffixCodec("-a")).SetReader(strings.NewReader(`{`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package firego
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// Snapshot is the value of a location at the time it was read. Unlike
+// the value given to Value, it can be traversed without deciding on a Go
+// type for the whole tree up front.
+type Snapshot struct {
+	key string
+	raw json.RawMessage
+}
+
+// Snapshot reads the value of the reference.
+func (fb *Firebase) Snapshot(opts ...RequestOption) (*Snapshot, error) {
+	return fb.SnapshotContext(context.Background(), opts...)
+}
+
+// SnapshotContext is like Snapshot but the request is canceled when ctx is
+// done.
+func (fb *Firebase) SnapshotContext(ctx context.Context, opts ...RequestOption) (*Snapshot, error) {
+	var raw json.RawMessage
+	if err := fb.ValueContext(ctx, &raw, opts...); err != nil {
+		return nil, err
+	}
+	return &Snapshot{key: fb.Key(), raw: raw}, nil
+}
+
+// Key returns the key of the location the snapshot was taken of.
+func (s *Snapshot) Key() string {
+	return s.key
+}
+
+// Exists reports whether the location holds a value.
+func (s *Snapshot) Exists() bool {
+	return len(s.raw) > 0 && string(s.raw) != "null"
+}
+
+// Raw returns the JSON encoding of the value, "null" if the location does
+// not exist.
+func (s *Snapshot) Raw() json.RawMessage {
+	if !s.Exists() {
+		return json.RawMessage("null")
+	}
+	return s.raw
+}
+
+// Val decodes the value into v. If the snapshot was read with
+// IncludePriority, the priorities are left out.
+func (s *Snapshot) Val(v interface{}) error {
+	raw := s.Raw()
+	if bytes.Contains(raw, []byte(`".priority"`)) {
+		var exported interface{}
+		if err := json.Unmarshal(raw, &exported); err != nil {
+			return err
+		}
+		b, err := json.Marshal(stripPriorities(exported))
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Priority returns the priority of the value, nil if it has none or the
+// snapshot was not read with IncludePriority.
+func (s *Snapshot) Priority() interface{} {
+	var p interface{}
+	if raw, ok := s.object(s.raw)[".priority"]; ok {
+		json.Unmarshal(raw, &p)
+	}
+	return p
+}
+
+// Child returns the snapshot of the location at path relative to the
+// snapshot's location. The snapshot of a location that does not exist is
+// returned if there is no value at path.
+func (s *Snapshot) Child(path string) *Snapshot {
+	child := &Snapshot{key: s.key}
+	raw := s.raw
+	for _, seg := range splitPath(path) {
+		raw = s.children(raw)[seg]
+		child.key = seg
+	}
+	child.raw = raw
+	return child
+}
+
+// NumChildren returns the number of children of the value, zero if it is
+// not an object.
+func (s *Snapshot) NumChildren() int {
+	return len(s.children(s.raw))
+}
+
+// ForEach calls fn with the snapshot of every child, in key order, until fn
+// returns an error. It returns the error returned by fn.
+func (s *Snapshot) ForEach(fn func(child *Snapshot) error) error {
+	children := s.children(s.raw)
+	keys := make([]string, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+	sort.Sort(byKey(keys))
+
+	for _, k := range keys {
+		if err := fn(&Snapshot{key: k, raw: children[k]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// object decodes raw as an object, it returns nil if raw is not one.
+func (s *Snapshot) object(raw json.RawMessage) map[string]json.RawMessage {
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil
+	}
+	var children map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &children); err != nil {
+		return nil
+	}
+	return children
+}
+
+// children is like object but leaves out the metadata of the export
+// format, the children of a primitive with a priority.
+func (s *Snapshot) children(raw json.RawMessage) map[string]json.RawMessage {
+	children := s.object(raw)
+	if _, ok := children[".value"]; ok {
+		return nil
+	}
+	delete(children, ".priority")
+	return children
+}
+
+// stripPriorities removes the metadata of the export format from v.
+func stripPriorities(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if value, ok := m[".value"]; ok {
+		return value
+	}
+	delete(m, ".priority")
+	for k, child := range m {
+		m[k] = stripPriorities(child)
+	}
+	return m
+}
@@ -0,0 +1,119 @@
+package firego
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users", map[string]interface{}{
+		"b": map[string]interface{}{"name": "Bob", "tags": map[string]interface{}{"x": true}},
+		"a": map[string]interface{}{"name": "Ann"},
+	})
+
+	s, err := New(server.URL).Child("users").Snapshot()
+	require.NoError(t, err)
+	assert.True(t, s.Exists())
+	assert.Equal(t, "users", s.Key())
+	assert.Equal(t, 2, s.NumChildren())
+
+	name := s.Child("b/name")
+	assert.Equal(t, "name", name.Key())
+	var v string
+	require.NoError(t, name.Val(&v))
+	assert.Equal(t, "Bob", v)
+	assert.Equal(t, `"Bob"`, string(name.Raw()))
+
+	missing := s.Child("c/name")
+	assert.False(t, missing.Exists())
+	assert.Equal(t, "null", string(missing.Raw()))
+	assert.False(t, name.Child("first").Exists())
+
+	var keys []string
+	require.NoError(t, s.ForEach(func(child *Snapshot) error {
+		keys = append(keys, child.Key())
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	stop := errors.New("stop")
+	keys = nil
+	assert.Equal(t, stop, s.ForEach(func(child *Snapshot) error {
+		keys = append(keys, child.Key())
+		return stop
+	}))
+	assert.Equal(t, []string{"a"}, keys)
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	s, err := New(server.URL).Child("nothing").Snapshot()
+	require.NoError(t, err)
+	assert.False(t, s.Exists())
+	assert.Equal(t, 0, s.NumChildren())
+
+	var v map[string]interface{}
+	require.NoError(t, s.Val(&v))
+	assert.Nil(t, v)
+}
+
+func TestSnapshotPriority(t *testing.T) {
+	t.Parallel()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.RawQuery
+		w.Write([]byte(`{
+			".priority": 1,
+			"a": {".value": "x", ".priority": 2},
+			"b": {"name": "Bob", ".priority": "high"},
+			"c": 3
+		}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	s, err := fb.IncludePriority(true).Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, "format=export", query)
+
+	assert.Equal(t, 1.0, s.Priority())
+	assert.Equal(t, 3, s.NumChildren())
+	assert.Equal(t, 2.0, s.Child("a").Priority())
+	assert.Equal(t, 0, s.Child("a").NumChildren())
+	assert.Equal(t, "high", s.Child("b").Priority())
+	assert.Nil(t, s.Child("c").Priority())
+
+	var a string
+	require.NoError(t, s.Child("a").Val(&a))
+	assert.Equal(t, "x", a)
+
+	var v map[string]interface{}
+	require.NoError(t, s.Val(&v))
+	assert.Equal(t, map[string]interface{}{
+		"a": "x",
+		"b": map[string]interface{}{"name": "Bob"},
+		"c": 3.0,
+	}, v)
+
+	var keys []string
+	s.ForEach(func(child *Snapshot) error {
+		keys = append(keys, child.Key())
+		return nil
+	})
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+}
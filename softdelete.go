@@ -0,0 +1,41 @@
+package firego
+
+// deletedField is the child that marks a location as soft deleted.
+const deletedField = "_deleted"
+
+// ServerTimestamp is a placeholder value that Firebase replaces with the
+// time, in milliseconds since the Unix epoch, at which a write happened.
+//
+// Reference https://www.firebase.com/docs/rest/api/#section-server-values
+var ServerTimestamp = map[string]string{".sv": "timestamp"}
+
+// SoftDelete marks the Firebase reference as deleted by setting a
+// "_deleted" child to the server timestamp instead of removing its data.
+func (fb *Firebase) SoftDelete() error {
+	return fb.Update(map[string]interface{}{deletedField: ServerTimestamp})
+}
+
+// Restore removes the "_deleted" marker set by SoftDelete.
+func (fb *Firebase) Restore() error {
+	return fb.Child(deletedField).Remove()
+}
+
+// ListDeleted gets the children of the Firebase reference that have been
+// soft deleted. Firebase rules should declare an index on "_deleted".
+//
+// Reference https://www.firebase.com/docs/security/guide/indexing-data.html
+func (fb *Firebase) ListDeleted(v interface{}) error {
+	c := fb.OrderBy(deletedField)
+	c.params.Set(startAtParam, "0")
+	return c.Value(v)
+}
+
+// ListActive gets the children of the Firebase reference that have not
+// been soft deleted. Firebase rules should declare an index on "_deleted".
+//
+// Reference https://www.firebase.com/docs/security/guide/indexing-data.html
+func (fb *Firebase) ListActive(v interface{}) error {
+	c := fb.OrderBy(deletedField)
+	c.params.Set(equalToParam, "null")
+	return c.Value(v)
+}
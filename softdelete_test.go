@@ -0,0 +1,51 @@
+package firego
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/one", map[string]interface{}{"name": "bob"})
+	fb := New(server.URL).Child("users/one")
+
+	require.NoError(t, fb.SoftDelete())
+	v := server.Get("users/one")
+	require.IsType(t, map[string]interface{}{}, v)
+	assert.Equal(t, "bob", v.(map[string]interface{})["name"])
+	assert.Contains(t, v, deletedField)
+
+	require.NoError(t, fb.Restore())
+	assert.Equal(t, map[string]interface{}{"name": "bob"}, server.Get("users/one"))
+}
+
+func TestListDeleted(t *testing.T) {
+	t.Parallel()
+	var (
+		server = newTestServer("")
+		fb     = New(server.URL)
+	)
+	defer server.Close()
+
+	fb.ListDeleted(nil)
+	fb.ListActive(nil)
+	require.Len(t, server.receivedReqs, 2)
+
+	assert.Equal(t, url.Values{
+		orderByParam: {`"_deleted"`},
+		startAtParam: {"0"},
+	}, server.receivedReqs[0].URL.Query())
+	assert.Equal(t, url.Values{
+		orderByParam: {`"_deleted"`},
+		equalToParam: {"null"},
+	}, server.receivedReqs[1].URL.Query())
+}
@@ -0,0 +1,147 @@
+/*
+Package sse reads streams of server-sent events, such as the ones Firebase
+sends to the watches of a location, for consumers that handle the raw
+stream of a reference themselves:
+
+	stream, err := fb.Stream()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stream.Close()
+
+	events := sse.NewReader(stream)
+	for {
+		event, err := events.Next()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %s\n", event.Type, event.Data)
+	}
+
+Reference https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+*/
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrTooLarge is returned by Next for an event larger than the MaxSize of
+// the Reader.
+var ErrTooLarge = errors.New("sse: event exceeds the maximum size")
+
+// defaultBufferSize is the size of the buffer of the Readers created by
+// NewReader.
+const defaultBufferSize = 4096
+
+// Event is an event of a stream.
+type Event struct {
+	// Type is the event field of the event, "message" if it has none.
+	// Firebase sends put, patch, keep-alive, cancel, auth_revoked and
+	// rules_debug events.
+	Type string
+	// Data is the data of the event, the value of its data fields
+	// joined by newlines. Firebase sends JSON.
+	Data []byte
+	// ID is the id field of the event, if any.
+	ID string
+}
+
+// Reader reads the events of a stream.
+type Reader struct {
+	// MaxSize limits the size of an event, the lines of its fields
+	// included, to that many bytes. Zero means no limit.
+	MaxSize int
+
+	r *bufio.Reader
+}
+
+// NewReader creates a Reader of the events of r.
+func NewReader(r io.Reader) *Reader {
+	return NewReaderSize(r, defaultBufferSize)
+}
+
+// NewReaderSize is like NewReader but r is read with a buffer of size
+// bytes. Lines longer than the buffer are read in parts.
+func NewReaderSize(r io.Reader, size int) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, size)}
+}
+
+// Next returns the next event of the stream. It returns io.EOF once the
+// stream ends, the event it ends in the middle of is dropped, and
+// ErrTooLarge for an event larger than MaxSize, after which the stream
+// cannot be read further. Comments and unknown fields are ignored.
+func (r *Reader) Next() (Event, error) {
+	var (
+		event  Event
+		data   [][]byte
+		size   int
+		fields bool
+	)
+	for {
+		limit := -1
+		if r.MaxSize > 0 {
+			limit = r.MaxSize - size
+		}
+		line, err := r.readLine(limit)
+		if err != nil {
+			return Event{}, err
+		}
+		size += len(line)
+
+		if len(line) == 0 {
+			if !fields {
+				// blank lines between events
+				continue
+			}
+			if event.Type == "" {
+				event.Type = "message"
+			}
+			event.Data = bytes.Join(data, []byte("\n"))
+			return event, nil
+		}
+		if line[0] == ':' {
+			// a comment
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "event":
+			event.Type = string(value)
+		case "data":
+			data = append(data, value)
+		case "id":
+			event.ID = string(value)
+		default:
+			continue
+		}
+		fields = true
+	}
+}
+
+// readLine reads a line of any length, without its end of line. It returns
+// ErrTooLarge if the line is longer than limit bytes, unless limit is
+// negative.
+func (r *Reader) readLine(limit int) ([]byte, error) {
+	var line []byte
+	for {
+		part, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if limit >= 0 && len(line)+len(part) > limit {
+			return nil, ErrTooLarge
+		}
+		line = append(line, part...)
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+	stream := "event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n" +
+		"\n" +
+		": a comment\r\n" +
+		"event: patch\r\nid: 7\r\nretry: 1000\r\ndata: {\"path\":\"/a\",\r\ndata:\"data\":2}\r\n\r\n" +
+		"data\n\n" +
+		"event: keep-alive\ndata: null"
+	r := NewReaderSize(strings.NewReader(stream), 16)
+
+	event, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, Event{Type: "put", Data: []byte(`{"path":"/","data":1}`)}, event)
+
+	event, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, Event{Type: "patch", Data: []byte("{\"path\":\"/a\",\n\"data\":2}"), ID: "7"}, event)
+
+	event, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "message", event.Type)
+	assert.Empty(t, event.Data)
+
+	// the stream ends in the middle of the event
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNextMaxSize(t *testing.T) {
+	t.Parallel()
+	stream := "event: put\ndata: 12345\n\nevent: put\ndata: 123456\n\n"
+	r := NewReader(strings.NewReader(stream))
+	r.MaxSize = len("event: putdata: 12345")
+
+	event, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "12345", string(event.Data))
+
+	_, err = r.Next()
+	assert.Equal(t, ErrTooLarge, err)
+}
@@ -0,0 +1,118 @@
+package firego
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+)
+
+// ValueTo writes the JSON encoded value of the Firebase reference to w as
+// it is received, instead of reading the whole response into memory first.
+func (fb *Firebase) ValueTo(w io.Writer, opts ...RequestOption) error {
+	return fb.ValueToContext(context.Background(), w, opts...)
+}
+
+// ValueToContext is like ValueTo but the request is canceled when ctx is
+// done.
+func (fb *Firebase) ValueToContext(ctx context.Context, w io.Writer, opts ...RequestOption) error {
+	return fb.stream(ctx, opts, func(r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// DownloadTo is like ValueTo but the value is requested as an attachment,
+// see WithDownload, e.g. to pipe a large location straight to a file. The
+// attachment is named after the key of the reference unless WithDownload
+// is given.
+func (fb *Firebase) DownloadTo(w io.Writer, opts ...RequestOption) error {
+	return fb.DownloadToContext(context.Background(), w, opts...)
+}
+
+// DownloadToContext is like DownloadTo but the request is canceled when
+// ctx is done.
+func (fb *Firebase) DownloadToContext(ctx context.Context, w io.Writer, opts ...RequestOption) error {
+	name := fb.Key()
+	if name == "" {
+		name = "root"
+	}
+	opts = append([]RequestOption{WithDownload(name + ".json")}, opts...)
+	return fb.ValueToContext(ctx, w, opts...)
+}
+
+// Decode is like Value but v is decoded from the response as it is
+// received, instead of reading the whole response into memory first.
+func (fb *Firebase) Decode(v interface{}, opts ...RequestOption) error {
+	return fb.DecodeContext(context.Background(), v, opts...)
+}
+
+// DecodeContext is like Decode but the request is canceled when ctx is
+// done.
+func (fb *Firebase) DecodeContext(ctx context.Context, v interface{}, opts ...RequestOption) error {
+	return fb.stream(ctx, opts, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(v)
+	})
+}
+
+// Stream returns the raw stream of server-sent events Firebase sends to
+// watches of the reference, for consumers that parse it themselves, e.g.
+// with the sse package. Unlike the watches, the events are read as is: the
+// codecs and transforms of the reference do not apply. Closing the stream
+// closes the connection.
+func (fb *Firebase) Stream(opts ...RequestOption) (io.ReadCloser, error) {
+	return fb.StreamContext(context.Background(), opts...)
+}
+
+// StreamContext is like Stream but the connection is closed when ctx is
+// done.
+func (fb *Firebase) StreamContext(ctx context.Context, opts ...RequestOption) (io.ReadCloser, error) {
+	c, r := fb.prepare(opts)
+	r.header.Set("Accept", "text/event-stream")
+	resp, err := c.send(ctx, "GET", nil, r.header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/200 != 1 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, c.newError("GET", resp.StatusCode, b)
+	}
+	return resp.Body, nil
+}
+
+// stream calls fn with the body of a successful GET request. The value is
+// read through Value, and buffered, if it has to be rewritten by the codecs,
+// transforms or key cursors of the reference or may be served from its
+// cache.
+func (fb *Firebase) stream(ctx context.Context, opts []RequestOption, fn func(io.Reader) error) error {
+	if len(fb.codecs) > 0 || len(fb.transforms) > 0 || fb.startKey != nil || fb.endKey != nil || (fb.cache != nil && len(opts) == 0) {
+		var raw json.RawMessage
+		if err := fb.ValueContext(ctx, &raw, opts...); err != nil {
+			return err
+		}
+		return fn(bytes.NewReader(raw))
+	}
+
+	c, r := fb.prepare(opts)
+	resp, err := c.send(ctx, "GET", nil, r.header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/200 != 1 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return c.newError("GET", resp.StatusCode, b)
+	}
+	if err := fn(resp.Body); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return c.closedErr(ctx, err)
+	}
+	if r.etag != nil {
+		*r.etag = resp.Header.Get("ETag")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package firego
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego/sse"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestValueTo(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", map[string]interface{}{"name": "Ann"})
+
+	var buf bytes.Buffer
+	require.NoError(t, New(server.URL).Child("users").ValueTo(&buf))
+	assert.JSONEq(t, `{"a":{"name":"Ann"}}`, buf.String())
+
+	// codecs are still applied
+	buf.Reset()
+	fb := New(server.URL).WithCodec(suffixCodec("nn"))
+	require.NoError(t, fb.Child("users/a/name").ValueTo(&buf))
+	assert.Equal(t, `"A"`, buf.String())
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", map[string]interface{}{"name": "Ann"})
+
+	var v map[string]map[string]string
+	require.NoError(t, New(server.URL).Child("users").Decode(&v))
+	assert.Equal(t, map[string]map[string]string{"a": {"name": "Ann"}}, v)
+}
+
+func TestDownloadTo(t *testing.T) {
+	t.Parallel()
+	var downloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		downloads = append(downloads, req.URL.Query().Get(downloadParam))
+		w.Header().Set("Content-Disposition", "attachment")
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	var buf bytes.Buffer
+	require.NoError(t, fb.Child("users").DownloadTo(&buf))
+	assert.Equal(t, `{"a":1}`, buf.String())
+
+	require.NoError(t, fb.DownloadTo(&buf))
+	require.NoError(t, fb.DownloadTo(&buf, WithDownload("backup.json")))
+	assert.Equal(t, []string{"users.json", "root.json", "backup.json"}, downloads)
+}
+
+func TestStreamError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"Permission denied"}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	var buf bytes.Buffer
+	err := fb.ValueTo(&buf)
+	require.IsType(t, (*Error)(nil), err)
+	assert.Equal(t, http.StatusUnauthorized, err.(*Error).StatusCode)
+	assert.Equal(t, "Permission denied", err.(*Error).Message)
+	assert.Empty(t, buf.String())
+
+	var v interface{}
+	assert.Error(t, fb.Decode(&v))
+
+	_, err = fb.Stream()
+	require.IsType(t, (*Error)(nil), err)
+	assert.Equal(t, http.StatusUnauthorized, err.(*Error).StatusCode)
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a", 1)
+	fb := New(server.URL + "/users")
+	stream, err := fb.Stream()
+	require.NoError(t, err)
+	defer stream.Close()
+
+	events := sse.NewReader(stream)
+	event, err := events.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "put", event.Type)
+	assert.JSONEq(t, `{"path":"/","data":{"a":1}}`, string(event.Data))
+
+	server.Set("users/b", 2)
+	event, err = events.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "put", event.Type)
+	assert.JSONEq(t, `{"path":"/b","data":2}`, string(event.Data))
+}
@@ -0,0 +1,117 @@
+package firego
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrNotSynced is returned by the reads of a SyncedRef that did not receive
+// the value of its location yet.
+var ErrNotSynced = errors.New("synced reference has not received its value yet")
+
+// SyncedRef keeps an in-memory copy of the value of a reference, kept
+// current by a watch, for services that read the same data over and over.
+// Reads never wait for the network nor for the writes of the watch, they
+// see the value as of the last event applied. The SyncedRef owns the watch
+// on the reference.
+type SyncedRef struct {
+	// Reconnect configures how dropped watches are re-established, see
+	// WatchReconnect. Its OnError is also called with the errors that
+	// ended the watch.
+	Reconnect ReconnectOptions
+	// OnChange, if set, is called with the path, relative to the
+	// reference, of every write once reads see it, "" for a write of the
+	// whole value. It is called from the goroutine applying the writes,
+	// which it delays.
+	OnChange func(path string)
+
+	ref   *Firebase
+	value atomic.Value
+	ready chan struct{}
+	done  chan struct{}
+}
+
+// syncedValue wraps the value of a SyncedRef since an atomic.Value cannot
+// hold nil.
+type syncedValue struct {
+	v interface{}
+}
+
+// NewSyncedRef creates a SyncedRef of ref, the query of ref selects the
+// children that are kept.
+func NewSyncedRef(ref *Firebase) *SyncedRef {
+	return &SyncedRef{ref: ref}
+}
+
+// Start starts watching the reference in the background. It fails like
+// WatchReconnect does.
+func (s *SyncedRef) Start() error {
+	s.ready = make(chan struct{})
+	s.done = make(chan struct{})
+	notifications := make(chan Event)
+	if err := s.ref.WatchReconnect(notifications, s.Reconnect); err != nil {
+		return err
+	}
+	go s.run(notifications)
+	return nil
+}
+
+// Stop tears down the watch. The value is still readable afterwards, as of
+// the last event applied.
+func (s *SyncedRef) Stop() {
+	s.ref.StopWatching()
+	<-s.done
+}
+
+// Ready returns a channel that is closed once the value of the location was
+// received.
+func (s *SyncedRef) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Get decodes the value at path, relative to the reference, into dst. The
+// value of a location that does not exist is null. Get returns
+// ErrNotSynced until the value of the location was received.
+func (s *SyncedRef) Get(path string, dst interface{}) error {
+	current, ok := s.value.Load().(syncedValue)
+	if !ok {
+		return ErrNotSynced
+	}
+	b, err := json.Marshal(valueAt(current.v, splitPath(path)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+func (s *SyncedRef) run(notifications chan Event) {
+	defer close(s.done)
+	for event := range notifications {
+		current, _ := s.value.Load().(syncedValue)
+		switch event.Type {
+		case EventTypeResynced:
+			s.store(syncedValue{event.Data}, "")
+		case EventTypeError:
+			report(s.Reconnect, event.Data.(error))
+		default:
+			if v, ok := withEvent(current.v, event); ok {
+				s.store(syncedValue{v}, strings.Join(splitPath(event.Path), "/"))
+			}
+		}
+	}
+}
+
+// store makes v the value reads see, path is where it was written.
+func (s *SyncedRef) store(v syncedValue, path string) {
+	s.value.Store(v)
+	select {
+	case <-s.ready:
+	default:
+		close(s.ready)
+	}
+	if s.OnChange != nil {
+		s.OnChange(path)
+	}
+}
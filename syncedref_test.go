@@ -0,0 +1,55 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestSyncedRef(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users/a/name", "alice")
+	s := NewSyncedRef(New(server.URL + "/users"))
+	changes := make(chan string, 10)
+	s.OnChange = func(path string) { changes <- path }
+
+	var name string
+	assert.Equal(t, ErrNotSynced, s.Get("a/name", &name))
+
+	require.NoError(t, s.Start())
+	select {
+	case <-s.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("not synced")
+	}
+	assert.Equal(t, "", <-changes)
+	require.NoError(t, s.Get("a/name", &name))
+	assert.Equal(t, "alice", name)
+
+	server.Set("users/b", map[string]string{"name": "bob"})
+	select {
+	case path := <-changes:
+		assert.Equal(t, "b", path)
+	case <-time.After(time.Second):
+		t.Fatal("no change")
+	}
+	var users map[string]struct{ Name string }
+	require.NoError(t, s.Get("", &users))
+	assert.Equal(t, "bob", users["b"].Name)
+
+	// locations that do not exist are null
+	var missing *string
+	require.NoError(t, s.Get("c/name", &missing))
+	assert.Nil(t, missing)
+
+	s.Stop()
+	require.NoError(t, s.Get("b/name", &name))
+	assert.Equal(t, "bob", name)
+}
@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Conflict describes a child that changed remotely while a local change
+// to it was waiting to be pushed. Values are JSON encoded, a nil value
+// means the child does not exist.
+type Conflict struct {
+	// Key of the child.
+	Key string
+	// Local is the local change.
+	Local json.RawMessage
+	// Remote is the value written by another client.
+	Remote json.RawMessage
+	// Base is the value the local change was made on top of.
+	Base json.RawMessage
+	// LocalTime is when the local change was made.
+	LocalTime time.Time
+}
+
+// Resolver decides the outcome of a conflict. The returned value replaces
+// both versions and is pushed to Firebase if it differs from the remote
+// one; returning nil deletes the child. Resolvers are called from the
+// Engine's background goroutine and must not call the Engine.
+type Resolver interface {
+	Resolve(c Conflict) (json.RawMessage, error)
+}
+
+// ResolverFunc is an adapter to allow the use of ordinary functions as
+// resolvers.
+type ResolverFunc func(c Conflict) (json.RawMessage, error)
+
+// Resolve calls f(c).
+func (f ResolverFunc) Resolve(c Conflict) (json.RawMessage, error) {
+	return f(c)
+}
+
+var (
+	// Theirs keeps the remote version, discarding the local change.
+	Theirs Resolver = ResolverFunc(func(c Conflict) (json.RawMessage, error) {
+		return c.Remote, nil
+	})
+	// Ours keeps the local version, overwriting the remote change.
+	Ours Resolver = ResolverFunc(func(c Conflict) (json.RawMessage, error) {
+		return c.Local, nil
+	})
+	// MergeByField merges objects field by field: the fields changed by
+	// the local change take the local value and every other field takes
+	// the remote value. Values that are not objects are treated as a
+	// single field.
+	MergeByField Resolver = ResolverFunc(mergeByField)
+)
+
+func mergeByField(c Conflict) (json.RawMessage, error) {
+	var local, remote, base map[string]json.RawMessage
+	if !object(c.Local, &local) || !object(c.Remote, &remote) || !object(c.Base, &base) {
+		return c.Local, nil
+	}
+
+	for k := range union(local, base) {
+		l, b := local[k], base[k]
+		if string(l) == string(b) {
+			continue
+		}
+		if l == nil {
+			delete(remote, k)
+		} else {
+			remote[k] = l
+		}
+	}
+	if len(remote) == 0 {
+		return nil, nil
+	}
+
+	// json.RawMessage only marshals through a pointer before Go 1.8
+	merged := make(map[string]*json.RawMessage, len(remote))
+	for k := range remote {
+		v := remote[k]
+		merged[k] = &v
+	}
+	return canonical(merged)
+}
+
+// object decodes b into m, reporting whether b is an object or does not
+// exist. Field values are re-encoded canonically so they can be compared.
+func object(b json.RawMessage, m *map[string]json.RawMessage) bool {
+	*m = map[string]json.RawMessage{}
+	if b == nil {
+		return true
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil || fields == nil {
+		return false
+	}
+	for k, v := range fields {
+		f, err := canonical(v)
+		if err != nil {
+			return false
+		}
+		if f != nil {
+			(*m)[k] = f
+		}
+	}
+	return true
+}
+
+func union(a, b map[string]json.RawMessage) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
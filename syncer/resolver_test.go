@@ -0,0 +1,82 @@
+package syncer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func raw(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+func TestMergeByField(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		base, local, remote string
+		expected            string
+	}{
+		{
+			base:     `{"a":1,"b":1,"c":1}`,
+			local:    `{"a":2,"b":1}`,
+			remote:   `{"a":1,"b":3,"c":1,"d":4}`,
+			expected: `{"a":2,"b":3,"d":4}`,
+		},
+		{
+			base:     ``,
+			local:    `{"a":1}`,
+			remote:   `{"b":2}`,
+			expected: `{"a":1,"b":2}`,
+		},
+		{
+			base:     `{"a":1}`,
+			local:    ``,
+			remote:   `{"a":1,"b":2}`,
+			expected: `{"b":2}`,
+		},
+		{
+			base:     `{"a":{"x":1}}`,
+			local:    `{"a":{"x":2}}`,
+			remote:   `{"a":{"y":1}}`,
+			expected: `{"a":{"x":2}}`,
+		},
+		{
+			base:     `{"a":1}`,
+			local:    `{"a":2}`,
+			remote:   `"scalar"`,
+			expected: `{"a":2}`,
+		},
+		{
+			base:     `{"a":1}`,
+			local:    ``,
+			remote:   `{"a":2}`,
+			expected: ``,
+		},
+	} {
+		v, err := MergeByField.Resolve(Conflict{
+			Base:   raw(tt.base),
+			Local:  raw(tt.local),
+			Remote: raw(tt.remote),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, string(v), "base %s local %s remote %s", tt.base, tt.local, tt.remote)
+	}
+}
+
+func TestOursTheirs(t *testing.T) {
+	t.Parallel()
+	c := Conflict{Local: raw(`1`), Remote: raw(`2`)}
+
+	v, err := Ours.Resolve(c)
+	require.NoError(t, err)
+	assert.Equal(t, `1`, string(v))
+
+	v, err = Theirs.Resolve(c)
+	require.NoError(t, err)
+	assert.Equal(t, `2`, string(v))
+}
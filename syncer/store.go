@@ -0,0 +1,62 @@
+package syncer
+
+import (
+	"sort"
+	"sync"
+)
+
+// Store is the local key-value store that an Engine keeps converged with
+// a Firebase location. Values are JSON encoded.
+type Store interface {
+	Get(key string) (value []byte, ok bool, err error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+	Keys() ([]string, error)
+}
+
+// MemoryStore is a Store that keeps its values in memory.
+type MemoryStore struct {
+	mtx    sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: map[string][]byte{}}
+}
+
+// Get returns the value stored under key.
+func (m *MemoryStore) Get(key string) ([]byte, bool, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	v, ok := m.values[key]
+	return v, ok, nil
+}
+
+// Put stores value under key.
+func (m *MemoryStore) Put(key string, value []byte) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+// Delete removes key.
+func (m *MemoryStore) Delete(key string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// Keys returns the stored keys in sorted order.
+func (m *MemoryStore) Keys() ([]string, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
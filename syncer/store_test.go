@@ -0,0 +1,29 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	s := NewMemoryStore()
+	require.NoError(t, s.Put("b", []byte(`2`)))
+	require.NoError(t, s.Put("a", []byte(`1`)))
+
+	v, ok, err := s.Get("a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `1`, string(v))
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	require.NoError(t, s.Delete("a"))
+	_, ok, err = s.Get("a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,425 @@
+/*
+Package syncer keeps a local key-value Store and the children of a Firebase
+location converged, acting as a small persistence layer for Go programs.
+
+Local changes made through an Engine are written to the Store immediately
+and pushed to Firebase; pushes that fail, e.g. while offline, are retried
+until they succeed. Remote changes arrive through a Watch on the location
+and are applied to the Store. When a child changed remotely while a local
+change to it was still waiting to be pushed, the Engine's Resolver decides
+the outcome.
+*/
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firego/internal/tree"
+)
+
+// DefaultRetryInterval is the default time between attempts to push local
+// changes and to re-establish the watch.
+const DefaultRetryInterval = 5 * time.Second
+
+// change is a local change waiting to be pushed, a nil value is a
+// deletion.
+type change struct {
+	value []byte
+	base  []byte
+	time  time.Time
+}
+
+// Engine keeps a Store converged with the children of a Firebase
+// reference. The Engine's watch on the reference is its own, stopping the
+// Engine leaves the other watches of the reference alone.
+type Engine struct {
+	// Resolver resolves conflicts, it defaults to Theirs.
+	Resolver Resolver
+	// RetryInterval between attempts to push local changes and to
+	// re-establish the watch, it defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+	// OnError, if set, is called with the errors encountered in the
+	// background.
+	OnError func(error)
+	// Delta pushes a local change to an object as a multi-path update of
+	// its differences to the value it was made on top of, instead of the
+	// whole object. This saves bandwidth for large objects with small
+	// changes, e.g. when flushing the changes made while offline.
+	Delta bool
+
+	ref   *firego.Firebase
+	store Store
+
+	mtx     sync.Mutex
+	pending map[string]change
+	// synced holds the hash of the last value seen for every child
+	synced map[string][sha256.Size]byte
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates an Engine that syncs store with the children of ref.
+func New(ref *firego.Firebase, store Store) *Engine {
+	return &Engine{
+		ref:     ref,
+		store:   store,
+		pending: map[string]change{},
+		synced:  map[string][sha256.Size]byte{},
+	}
+}
+
+// Start pushes the pending local changes and starts applying remote
+// changes in the background.
+func (e *Engine) Start() {
+	var ctx context.Context
+	ctx, e.cancel = context.WithCancel(context.Background())
+	e.done = make(chan struct{})
+	go e.run(ctx)
+}
+
+// Stop tears down the watch and stops retrying pushes. It does nothing if
+// the Engine was not started.
+func (e *Engine) Stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+	e.cancel = nil
+}
+
+// Get decodes the local value of key into v, ok is false if the key does
+// not exist.
+func (e *Engine) Get(key string, v interface{}) (ok bool, err error) {
+	b, ok, err := e.store.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal(b, v)
+}
+
+// Set stores v under key locally and pushes it to Firebase. Failing to
+// push is not an error, the change is retried in the background.
+func (e *Engine) Set(key string, v interface{}) error {
+	b, err := canonical(v)
+	if err != nil {
+		return err
+	}
+	return e.local(key, b)
+}
+
+// Delete removes key locally and from Firebase. Failing to push is not an
+// error, the change is retried in the background.
+func (e *Engine) Delete(key string) error {
+	return e.local(key, nil)
+}
+
+// Pending returns the number of local changes that have not been pushed.
+func (e *Engine) Pending() int {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return len(e.pending)
+}
+
+// Flush attempts to push every pending local change.
+func (e *Engine) Flush() {
+	e.mtx.Lock()
+	keys := make([]string, 0, len(e.pending))
+	for k := range e.pending {
+		keys = append(keys, k)
+	}
+	e.mtx.Unlock()
+
+	for _, k := range keys {
+		e.push(k)
+	}
+}
+
+func (e *Engine) local(key string, value []byte) error {
+	e.mtx.Lock()
+	c, ok := e.pending[key]
+	if !ok {
+		// without a pending change the store holds the remote value
+		c.base, _, _ = e.store.Get(key)
+	}
+	err := e.write(key, value)
+	if err == nil {
+		c.value, c.time = value, time.Now()
+		e.pending[key] = c
+	}
+	e.mtx.Unlock()
+
+	if err != nil {
+		return err
+	}
+	e.push(key)
+	return nil
+}
+
+// push writes the pending change of key to Firebase.
+func (e *Engine) push(key string) {
+	e.mtx.Lock()
+	c, ok := e.pending[key]
+	e.mtx.Unlock()
+	if !ok {
+		return
+	}
+
+	var err error
+	if p, ok := e.delta(c); ok {
+		if len(p) > 0 {
+			err = e.ref.Child(key).Update(p)
+		}
+	} else if c.value == nil {
+		err = e.ref.Child(key).Remove()
+	} else {
+		// json.RawMessage only marshals through a pointer before Go 1.8
+		raw := json.RawMessage(c.value)
+		err = e.ref.Child(key).Set(&raw)
+	}
+	if err != nil {
+		e.report(err)
+		return
+	}
+
+	e.mtx.Lock()
+	// only forget the change if it was not replaced in the meantime
+	if p, ok := e.pending[key]; ok && string(p.value) == string(c.value) {
+		delete(e.pending, key)
+		e.synced[key] = sha256.Sum256(c.value)
+	}
+	e.mtx.Unlock()
+}
+
+// delta returns the differences of a change to its base, ok is false if
+// they cannot be expressed as a multi-path update.
+func (e *Engine) delta(c change) (p firego.Patch, ok bool) {
+	if !e.Delta || c.base == nil || c.value == nil {
+		return nil, false
+	}
+	var base, value interface{}
+	if json.Unmarshal(c.base, &base) != nil || json.Unmarshal(c.value, &value) != nil {
+		return nil, false
+	}
+	p, err := firego.Diff(base, value)
+	return p, err == nil
+}
+
+func (e *Engine) run(ctx context.Context) {
+	defer close(e.done)
+
+	interval := e.RetryInterval
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.Flush()
+
+		notifications := make(chan firego.Event)
+		if err := e.ref.WatchContext(ctx, notifications); err != nil {
+			e.report(err)
+		} else {
+			e.consume(notifications, ticker.C)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// consume applies events until the watch ends, retrying pending pushes on
+// every tick.
+func (e *Engine) consume(notifications chan firego.Event, tick <-chan time.Time) {
+	for {
+		select {
+		case event, ok := <-notifications:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case firego.EventTypePut, firego.EventTypePatch:
+				e.apply(event)
+			case firego.EventTypeError:
+				e.report(event.Data.(error))
+			}
+		case <-tick:
+			go e.Flush()
+		}
+	}
+}
+
+// apply turns a put or patch event into remote changes of children.
+func (e *Engine) apply(event firego.Event) {
+	path := tree.Split(event.Path)
+	if event.Type == firego.EventTypePatch {
+		data, _ := event.Data.(map[string]interface{})
+		for k, v := range data {
+			e.remoteWrite(append(path[:len(path):len(path)], tree.Split(k)...), v)
+		}
+		return
+	}
+	e.remoteWrite(path, event.Data)
+}
+
+func (e *Engine) remoteWrite(path []string, v interface{}) {
+	if len(path) == 0 {
+		// a snapshot of the whole location
+		children, _ := v.(map[string]interface{})
+		keys, err := e.store.Keys()
+		if err != nil {
+			e.report(err)
+		}
+		for _, k := range keys {
+			if _, ok := children[k]; !ok {
+				e.remoteChange(k, nil)
+			}
+		}
+		for k, child := range children {
+			e.remoteChange(k, child)
+		}
+		return
+	}
+
+	key := path[0]
+	if len(path) > 1 {
+		base, err := e.remoteBase(key)
+		if err != nil {
+			e.report(err)
+			return
+		}
+		v = tree.Set(base, path[1:], v)
+	}
+	e.remoteChange(key, v)
+}
+
+// remoteBase returns the remote value of key that a change to one of its
+// descendants applies to.
+func (e *Engine) remoteBase(key string) (interface{}, error) {
+	e.mtx.Lock()
+	_, pending := e.pending[key]
+	e.mtx.Unlock()
+
+	var v interface{}
+	if pending {
+		// the local value has diverged, ask Firebase
+		return v, e.ref.Child(key).Value(&v)
+	}
+
+	b, ok, err := e.store.Get(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v, json.Unmarshal(b, &v)
+}
+
+// remoteChange applies the remote value of key, nil meaning deleted.
+func (e *Engine) remoteChange(key string, v interface{}) {
+	var b []byte
+	if v != nil {
+		var err error
+		if b, err = canonical(v); err != nil {
+			e.report(err)
+			return
+		}
+	}
+	sum := sha256.Sum256(b)
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	base, known := e.synced[key]
+	e.synced[key] = sum
+	if c, ok := e.pending[key]; ok {
+		switch {
+		case string(c.value) == string(b):
+			// the remote already holds the local change
+			delete(e.pending, key)
+			return
+		case known && base == sum, !known && b == nil:
+			// the remote did not change since it was last seen
+			return
+		}
+
+		resolved, err := e.resolve(Conflict{
+			Key:       key,
+			Local:     c.value,
+			Remote:    b,
+			Base:      c.base,
+			LocalTime: c.time,
+		})
+		if err != nil {
+			e.report(err)
+			return
+		}
+		if string(resolved) != string(b) {
+			c.value, c.base = resolved, b
+			e.pending[key] = c
+			go e.push(key)
+			b = resolved
+		} else {
+			delete(e.pending, key)
+		}
+	}
+
+	if err := e.write(key, b); err != nil {
+		e.report(err)
+	}
+}
+
+func (e *Engine) resolve(c Conflict) ([]byte, error) {
+	r := e.Resolver
+	if r == nil {
+		r = Theirs
+	}
+	v, err := r.Resolve(c)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(v, &generic); err != nil {
+		return nil, err
+	}
+	return canonical(generic)
+}
+
+func (e *Engine) write(key string, value []byte) error {
+	if value == nil {
+		return e.store.Delete(key)
+	}
+	return e.store.Put(key, value)
+}
+
+func (e *Engine) report(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
+
+// canonical encodes v as JSON with sorted object keys, so that equal
+// values always have equal encodings.
+func canonical(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	if generic == nil {
+		return nil, nil
+	}
+	return json.Marshal(generic)
+}
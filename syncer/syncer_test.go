@@ -0,0 +1,259 @@
+package syncer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firego"
+	"github.com/zabawaba99/firetest"
+)
+
+func eventually(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "condition was not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// offline returns an Engine whose pushes always fail.
+func offline(t *testing.T) (*Engine, *MemoryStore) {
+	server := httptest.NewServer(nil)
+	server.Close()
+
+	store := NewMemoryStore()
+	return New(firego.New(server.URL), store), store
+}
+
+func value(t *testing.T, s Store, key string) string {
+	b, ok, err := s.Get(key)
+	require.NoError(t, err)
+	if !ok {
+		return ""
+	}
+	return string(b)
+}
+
+func TestEngineSync(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("remote", map[string]interface{}{"n": 1})
+
+	store := NewMemoryStore()
+	e := New(firego.New(server.URL+"/items"), store)
+	e.RetryInterval = 50 * time.Millisecond
+	e.Start()
+	defer e.Stop()
+
+	require.NoError(t, e.Set("local", map[string]interface{}{"n": 2}))
+	assert.EqualValues(t, map[string]interface{}{"n": 2.0}, server.Get("items/local"))
+	assert.Equal(t, 0, e.Pending())
+
+	server.Set("items/remote", map[string]interface{}{"n": 3})
+	eventually(t, func() bool { return value(t, store, "remote") == `{"n":3}` })
+
+	server.Delete("items/remote")
+	eventually(t, func() bool { return value(t, store, "remote") == "" })
+
+	var v struct{ N int }
+	ok, err := e.Get("local", &v)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, v.N)
+
+	require.NoError(t, e.Delete("local"))
+	assert.Nil(t, server.Get("items/local"))
+}
+
+func TestEngineOffline(t *testing.T) {
+	t.Parallel()
+	e, store := offline(t)
+	var errs []error
+	e.OnError = func(err error) { errs = append(errs, err) }
+
+	require.NoError(t, e.Set("k", 1))
+	assert.Equal(t, `1`, value(t, store, "k"))
+	assert.Equal(t, 1, e.Pending())
+	assert.Len(t, errs, 1)
+
+	e.Flush()
+	assert.Equal(t, 1, e.Pending())
+	assert.Len(t, errs, 2)
+}
+
+func TestEngineConflict(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		resolver Resolver
+		value    string
+		pending  int
+	}{
+		{nil, `2`, 0},
+		{Theirs, `2`, 0},
+		{Ours, `1`, 1},
+		{ResolverFunc(func(c Conflict) (json.RawMessage, error) {
+			return json.RawMessage(`[` + string(c.Local) + `, ` + string(c.Remote) + `]`), nil
+		}), `[1,2]`, 1},
+		{ResolverFunc(func(c Conflict) (json.RawMessage, error) {
+			return nil, nil
+		}), ``, 1},
+	} {
+		e, store := offline(t)
+		e.Resolver = tt.resolver
+
+		require.NoError(t, e.Set("k", 1))
+		e.apply(firego.Event{Type: "put", Path: "/k", Data: 2.0})
+
+		assert.Equal(t, tt.value, value(t, store, "k"))
+		assert.Equal(t, tt.pending, e.Pending())
+	}
+}
+
+func TestEngineConflictMetadata(t *testing.T) {
+	t.Parallel()
+	e, store := offline(t)
+
+	var got Conflict
+	e.Resolver = ResolverFunc(func(c Conflict) (json.RawMessage, error) {
+		got = c
+		return nil, assert.AnError
+	})
+	var errs []error
+	e.OnError = func(err error) { errs = append(errs, err) }
+
+	e.apply(firego.Event{Type: "put", Path: "/k", Data: "base"})
+	before := time.Now()
+	require.NoError(t, e.Set("k", "local"))
+	e.apply(firego.Event{Type: "put", Path: "/k", Data: "remote"})
+
+	assert.Equal(t, "k", got.Key)
+	assert.Equal(t, `"local"`, string(got.Local))
+	assert.Equal(t, `"remote"`, string(got.Remote))
+	assert.Equal(t, `"base"`, string(got.Base))
+	assert.False(t, got.LocalTime.Before(before))
+
+	// a failing resolver leaves the local change in place
+	assert.Contains(t, errs, assert.AnError)
+	assert.Equal(t, `"local"`, value(t, store, "k"))
+	assert.Equal(t, 1, e.Pending())
+}
+
+func TestEngineUnchangedRemote(t *testing.T) {
+	t.Parallel()
+	e, store := offline(t)
+
+	e.apply(firego.Event{Type: "put", Path: "/", Data: map[string]interface{}{"k": 1.0}})
+	require.NoError(t, e.Set("k", 2))
+	require.NoError(t, e.Set("new", 3))
+
+	// a reconnect delivers the same snapshot, which is not a conflict
+	e.apply(firego.Event{Type: "put", Path: "/", Data: map[string]interface{}{"k": 1.0}})
+	assert.Equal(t, `2`, value(t, store, "k"))
+	assert.Equal(t, `3`, value(t, store, "new"))
+	assert.Equal(t, 2, e.Pending())
+}
+
+func TestEngineApplyPatch(t *testing.T) {
+	t.Parallel()
+	e, store := offline(t)
+
+	e.apply(firego.Event{Type: "put", Path: "/", Data: map[string]interface{}{
+		"a": map[string]interface{}{"x": 1.0, "y": 2.0},
+		"b": 3.0,
+	}})
+	e.apply(firego.Event{Type: "patch", Path: "/a", Data: map[string]interface{}{
+		"x":   nil,
+		"z/w": 4.0,
+	}})
+	e.apply(firego.Event{Type: "patch", Path: "/", Data: map[string]interface{}{
+		"b":   nil,
+		"c/d": 5.0,
+	}})
+
+	assert.Equal(t, `{"y":2,"z":{"w":4}}`, value(t, store, "a"))
+	assert.Equal(t, "", value(t, store, "b"))
+	assert.Equal(t, `{"d":5}`, value(t, store, "c"))
+}
+
+func TestEngineDelta(t *testing.T) {
+	t.Parallel()
+	var patches []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "PATCH", req.Method)
+		assert.Equal(t, "/k/.json", req.URL.Path)
+		var m map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&m))
+		patches = append(patches, m)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	store := NewMemoryStore()
+	e := New(firego.New(server.URL), store)
+	e.Delta = true
+
+	e.apply(firego.Event{Type: "put", Path: "/k", Data: map[string]interface{}{
+		"title": "T",
+		"body":  "a long body that does not change",
+		"tags":  map[string]interface{}{"a": true},
+	}})
+	require.NoError(t, e.Set("k", map[string]interface{}{
+		"title": "New",
+		"body":  "a long body that does not change",
+		"tags":  map[string]interface{}{"b": true},
+	}))
+
+	assert.Equal(t, 0, e.Pending())
+	assert.Equal(t, []map[string]interface{}{{
+		"title":  "New",
+		"tags/a": nil,
+		"tags/b": true,
+	}}, patches)
+}
+
+func TestEngineStop(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	New(firego.New(server.URL), NewMemoryStore()).Stop()
+
+	ref := firego.New(server.URL + "/items")
+	notifications := make(chan firego.Event)
+	require.NoError(t, ref.Watch(notifications))
+	defer ref.StopWatching()
+
+	store := NewMemoryStore()
+	e := New(ref, store)
+	e.Start()
+	server.Set("items/a", 1)
+	eventually(t, func() bool { return value(t, store, "a") == "1" })
+	e.Stop()
+	e.Stop()
+
+	// the watches of the caller outlive the Engine
+	server.Set("items/b", 2)
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-notifications:
+			require.True(t, ok, "the watch of the caller was torn down")
+			if event.Path == "/b" {
+				return
+			}
+		case <-timeout:
+			require.FailNow(t, "the watch of the caller did not see the change")
+		}
+	}
+}
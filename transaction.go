@@ -0,0 +1,107 @@
+package firego
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// maxTransactionRetries is the number of times a transaction is retried
+// after losing a race with another writer.
+const maxTransactionRetries = 25
+
+const (
+	etagHeader    = "X-Firebase-ETag"
+	ifMatchHeader = "if-match"
+)
+
+// ErrTransactionConflict is returned by Transaction when the value kept
+// changing and the transaction could not be committed.
+var ErrTransactionConflict = errors.New("transaction was retried too many times")
+
+// TransactionFunc computes the new value of a location from its current
+// value, given in its generic JSON representation. It may be called more
+// than once and must not have side effects.
+type TransactionFunc func(current interface{}) (interface{}, error)
+
+// Transaction atomically replaces the value of the Firebase reference
+// with the result of fn. The write is conditional on the ETag of the value
+// that fn was given, if another writer changed the value in the meantime
+// fn is called again with the new value.
+//
+// fn is given the value as it is stored, decoded by the codecs of the
+// reference but not rewritten by its transforms: the value fn returns
+// replaces the whole location, so the fields a transform hides or
+// rewrites are kept as they are unless fn changes them.
+//
+// Reference https://firebase.google.com/docs/reference/rest/database/#section-conditional-requests
+func (fb *Firebase) Transaction(fn TransactionFunc) error {
+	return fb.TransactionContext(context.Background(), fn)
+}
+
+// TransactionContext is like Transaction but the requests are canceled
+// when ctx is done.
+func (fb *Firebase) TransactionContext(ctx context.Context, fn TransactionFunc) error {
+	resp, err := fb.do(ctx, "GET", nil, http.Header{etagHeader: {"true"}})
+	if err != nil {
+		return err
+	}
+	if resp.status/200 != 1 {
+		return fb.newError("GET", resp.status, resp.body)
+	}
+
+	for i := 0; i < maxTransactionRetries; i++ {
+		current, err := fb.current(resp.body)
+		if err != nil {
+			return err
+		}
+
+		next, err := fn(current)
+		if err != nil {
+			return err
+		}
+		b, err := fb.encode(next)
+		if err != nil {
+			return err
+		}
+
+		etag := resp.header.Get("ETag")
+		resp, err = fb.do(ctx, "PUT", bytes.NewReader(b), http.Header{etagHeader: {"true"}, ifMatchHeader: {etag}})
+		if err != nil {
+			return err
+		}
+		switch {
+		case resp.status == http.StatusPreconditionFailed:
+			// the response holds the current value and its ETag
+			continue
+		case resp.status/200 != 1:
+			return fb.newError("PUT", resp.status, resp.body)
+		}
+		return nil
+	}
+	return ErrTransactionConflict
+}
+
+// current decodes the value body of the reference for a TransactionFunc,
+// with its codecs applied.
+func (fb *Firebase) current(body []byte) (interface{}, error) {
+	var current interface{}
+	if len(fb.codecs) > 0 {
+		v, err := decodeNumbers(body)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = fb.decodeCodecs(nil, v); err != nil {
+			return nil, err
+		}
+		if body, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(body, &current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
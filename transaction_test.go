@@ -0,0 +1,127 @@
+package firego
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etagServer is a single value store that implements Firebase's
+// conditional request semantics.
+type etagServer struct {
+	*httptest.Server
+
+	mtx     sync.Mutex
+	value   string
+	version int
+	puts    int
+	// beforePut, if set, is called before a conditional PUT is evaluated
+	beforePut func(s *etagServer)
+}
+
+func newETagServer(value string) *etagServer {
+	s := &etagServer{value: value}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *etagServer) set(value string) {
+	s.value = value
+	s.version++
+}
+
+func (s *etagServer) serve(w http.ResponseWriter, req *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch req.Method {
+	case "GET":
+		if req.Header.Get(etagHeader) == "true" {
+			w.Header().Set("ETag", strconv.Itoa(s.version))
+		}
+		fmt.Fprint(w, s.value)
+	case "PUT":
+		s.puts++
+		if s.beforePut != nil {
+			s.beforePut(s)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("ETag", strconv.Itoa(s.version))
+		if m := req.Header.Get(ifMatchHeader); m != "" && m != strconv.Itoa(s.version) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, s.value)
+			return
+		}
+		s.set(string(body))
+		fmt.Fprint(w, s.value)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`1`)
+	defer server.Close()
+
+	// another writer sneaks in before the first conditional write
+	server.beforePut = func(s *etagServer) {
+		if s.puts == 1 {
+			s.set(`10`)
+		}
+	}
+
+	var seen []interface{}
+	err := New(server.URL).Transaction(func(current interface{}) (interface{}, error) {
+		seen = append(seen, current)
+		return current.(float64) + 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1.0, 10.0}, seen)
+	assert.Equal(t, "11", server.value)
+}
+
+func TestTransactionConflict(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`0`)
+	defer server.Close()
+	server.beforePut = func(s *etagServer) { s.set(strconv.Itoa(s.puts)) }
+
+	err := New(server.URL).Transaction(func(current interface{}) (interface{}, error) {
+		return "mine", nil
+	})
+	assert.Equal(t, ErrTransactionConflict, err)
+	assert.Equal(t, maxTransactionRetries, server.puts)
+}
+
+func TestTransactionFuncError(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`0`)
+	defer server.Close()
+
+	err := New(server.URL).Transaction(func(interface{}) (interface{}, error) {
+		return nil, assert.AnError
+	})
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, 0, server.puts)
+}
+
+func TestTransactionKeepsTransformedFields(t *testing.T) {
+	t.Parallel()
+	server := newETagServer(`{"name":"Ann","email":"ann@example.com","visits":1}`)
+	defer server.Close()
+
+	fb := New(server.URL).Transform("email", func(interface{}) interface{} { return nil })
+	err := fb.Transaction(func(current interface{}) (interface{}, error) {
+		user := current.(map[string]interface{})
+		user["visits"] = user["visits"].(float64) + 1
+		return user, nil
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Ann","email":"ann@example.com","visits":2}`, server.value)
+}
@@ -0,0 +1,109 @@
+package firego
+
+import (
+	_url "net/url"
+	"strconv"
+	"strings"
+)
+
+// TransformFunc rewrites a value read from Firebase before it is
+// delivered to the caller. Returning nil removes the value.
+type TransformFunc func(v interface{}) interface{}
+
+type transform struct {
+	// pattern is the absolute path, split into segments, at which the
+	// transform applies. A "*" segment matches any key.
+	pattern []string
+	fn      TransformFunc
+}
+
+// Transform creates a new Firebase reference that applies fn to every
+// value found at path, relative to the reference, after it has been
+// fetched and before it is delivered by Value or Watch. Path segments of
+// "*" match any key, e.g. "users/*/email".
+//
+// Transforms are inherited by children of the returned reference, which
+// makes it possible to hand a projected or anonymized view of the data to
+// unprivileged code.
+func (fb *Firebase) Transform(path string, fn TransformFunc) *Firebase {
+	c := fb.copy()
+	c.transforms = append(append([]transform(nil), fb.transforms...), transform{
+		pattern: append(fb.segments(), splitPath(path)...),
+		fn:      fn,
+	})
+	return c
+}
+
+// segments returns the path of the reference split into segments.
+func (fb *Firebase) segments() []string {
+	u, err := _url.Parse(fb.url)
+	if err != nil {
+		return nil
+	}
+	return splitPath(u.Path)
+}
+
+func splitPath(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// transformValue applies the transforms of the reference to v, the value
+// found at path relative to the reference.
+func (fb *Firebase) transformValue(path []string, v interface{}) interface{} {
+	if len(fb.transforms) == 0 {
+		return v
+	}
+
+	at := append(fb.segments(), path...)
+	for _, t := range fb.transforms {
+		if len(at) > len(t.pattern) || !matchSegments(t.pattern[:len(at)], at) {
+			continue
+		}
+		v = applyTransform(v, t.pattern[len(at):], t.fn)
+	}
+	return v
+}
+
+func matchSegments(pattern, path []string) bool {
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyTransform calls fn on every value inside v matching pattern.
+func applyTransform(v interface{}, pattern []string, fn TransformFunc) interface{} {
+	if len(pattern) == 0 {
+		return fn(v)
+	}
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			if pattern[0] != "*" && pattern[0] != k {
+				continue
+			}
+			if child = applyTransform(child, pattern[1:], fn); child == nil {
+				delete(node, k)
+			} else {
+				node[k] = child
+			}
+		}
+	case []interface{}:
+		for i, child := range node {
+			if pattern[0] != "*" && pattern[0] != strconv.Itoa(i) {
+				continue
+			}
+			node[i] = applyTransform(child, pattern[1:], fn)
+		}
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package firego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func stripField(field string) TransformFunc {
+	return func(v interface{}) interface{} {
+		if m, ok := v.(map[string]interface{}); ok {
+			delete(m, field)
+		}
+		return v
+	}
+}
+
+func TestTransform(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("users", map[string]interface{}{
+		"one": map[string]interface{}{"name": "bob", "email": "bob@example.com"},
+		"two": map[string]interface{}{"name": "ann", "email": "ann@example.com"},
+	})
+
+	privileged := New(server.URL)
+	public := privileged.Transform("users/*", stripField("email"))
+
+	var v map[string]map[string]string
+	require.NoError(t, public.Child("users").Value(&v))
+	assert.Equal(t, map[string]map[string]string{
+		"one": {"name": "bob"},
+		"two": {"name": "ann"},
+	}, v)
+
+	var user map[string]string
+	require.NoError(t, public.Child("users/one").Value(&user))
+	assert.Equal(t, map[string]string{"name": "bob"}, user)
+
+	require.NoError(t, privileged.Child("users/one").Value(&user))
+	assert.Equal(t, "bob@example.com", user["email"])
+}
+
+func TestTransformRemovesValue(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("", map[string]interface{}{"public": 1, "secret": 2})
+	fb := New(server.URL).Transform("secret", func(interface{}) interface{} { return nil })
+
+	var v map[string]int
+	require.NoError(t, fb.Value(&v))
+	assert.Equal(t, map[string]int{"public": 1}, v)
+}
+
+func TestTransformWatch(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).Transform("users/*", stripField("email"))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+	defer fb.StopWatching()
+	<-notifications // initial event
+
+	server.Set("users/one", map[string]interface{}{"name": "bob", "email": "bob@example.com"})
+	select {
+	case event := <-notifications:
+		assert.Equal(t, "/users/one", event.Path)
+		assert.Equal(t, map[string]interface{}{"name": "bob"}, event.Data)
+	case <-time.After(250 * time.Millisecond):
+		require.FailNow(t, "did not receive a notification")
+	}
+}
+
+func TestApplyTransformArray(t *testing.T) {
+	t.Parallel()
+	v := []interface{}{"a", "b", "c"}
+	upper := func(interface{}) interface{} { return "X" }
+	assert.Equal(t, []interface{}{"a", "X", "c"}, applyTransform(v, []string{"1"}, upper))
+	assert.Equal(t, []interface{}{"X", "X", "X"}, applyTransform(v, []string{"*"}, upper))
+}
@@ -1,13 +1,22 @@
 package firego
 
-import "encoding/json"
+import (
+	"bytes"
+	"context"
+)
 
 // Update the specific child with the given value.
-func (fb *Firebase) Update(v interface{}) error {
-	bytes, err := json.Marshal(v)
+func (fb *Firebase) Update(v interface{}, opts ...RequestOption) error {
+	return fb.UpdateContext(context.Background(), v, opts...)
+}
+
+// UpdateContext is like Update but the request is canceled when ctx is
+// done.
+func (fb *Firebase) UpdateContext(ctx context.Context, v interface{}, opts ...RequestOption) error {
+	b, err := fb.encode(v)
 	if err != nil {
 		return err
 	}
-	_, err = fb.doRequest("PATCH", bytes)
+	_, err = fb.doRequest(ctx, "PATCH", bytes.NewReader(b), opts)
 	return err
 }
@@ -16,7 +16,7 @@ func TestUpdate(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 	err := fb.Update(payload)
 	assert.NoError(t, err)
 
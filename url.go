@@ -0,0 +1,166 @@
+package firego
+
+import (
+	"fmt"
+	"net"
+	_url "net/url"
+	"os"
+	"strings"
+)
+
+// EmulatorHostEnv is the environment variable that routes every reference
+// created by New to the Realtime Database emulator when it is set to the
+// emulator's host:port, e.g. "localhost:9000".
+const EmulatorHostEnv = "FIREBASE_DATABASE_EMULATOR_HOST"
+
+// nsParam selects the database namespace on the emulator.
+const nsParam = "ns"
+
+const (
+	legacyDomain   = ".firebaseio.com"
+	regionalDomain = ".firebasedatabase.app"
+	defaultRegion  = "us-central1"
+)
+
+// DatabaseURL returns the URL of the database with the given namespace in
+// region. Databases in us-central1, or with no region, are served from
+// https://<namespace>.firebaseio.com and all others from
+// https://<namespace>.<region>.firebasedatabase.app.
+func DatabaseURL(namespace, region string) string {
+	if region == "" || region == defaultRegion {
+		return "https://" + namespace + legacyDomain
+	}
+	return "https://" + namespace + "." + region + regionalDomain
+}
+
+// Namespace returns the database namespace of url, which is either a
+// https://<namespace>.firebaseio.com URL, a
+// https://<namespace>.<region>.firebasedatabase.app URL or an emulator URL
+// with an "ns" query parameter.
+func Namespace(url string) (string, error) {
+	base, query := splitURL(url)
+	if ns := query.Get(nsParam); ns != "" {
+		return ns, nil
+	}
+
+	u, err := _url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	host := strings.ToLower(hostname(u.Host))
+
+	switch {
+	case strings.HasSuffix(host, legacyDomain):
+		labels := strings.Split(strings.TrimSuffix(host, legacyDomain), ".")
+		if len(labels) == 2 {
+			return "", fmt.Errorf("invalid database URL %q, databases in %s are served from %s", url, labels[1], DatabaseURL(labels[0], labels[1]))
+		}
+		if len(labels) != 1 || labels[0] == "" {
+			return "", fmt.Errorf("invalid database URL %q, expected https://<namespace>%s", url, legacyDomain)
+		}
+		return labels[0], nil
+	case strings.HasSuffix(host, regionalDomain):
+		labels := strings.Split(strings.TrimSuffix(host, regionalDomain), ".")
+		if len(labels) != 2 || labels[0] == "" || labels[1] == "" {
+			return "", fmt.Errorf("invalid database URL %q, expected https://<namespace>.<region>%s", url, regionalDomain)
+		}
+		return labels[0], nil
+	case isLocalHost(host):
+		return "", fmt.Errorf("invalid database URL %q, emulator URLs need an %q query parameter", url, nsParam)
+	}
+	return "", fmt.Errorf("invalid database URL %q, expected a %s or %s host", url, legacyDomain, regionalDomain)
+}
+
+// ValidateURL reports whether url is a valid database URL.
+func ValidateURL(url string) error {
+	_, err := Namespace(url)
+	return err
+}
+
+// NewURL is like New but returns an error if url is not a valid database
+// URL, see ValidateURL. Unlike New, it also requires an explicit https
+// scheme, or http for the emulator, and rejects URLs with user
+// information, a fragment, a query string other than the emulator's "ns"
+// parameter or a path that is not a valid location.
+func NewURL(url string, opts ...Option) (*Firebase, error) {
+	u, err := _url.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL %q: %v", url, err)
+	}
+	local := isLocalHost(strings.ToLower(hostname(u.Host)))
+	switch {
+	case u.Scheme != "https" && !(u.Scheme == "http" && local):
+		return nil, fmt.Errorf("invalid database URL %q, expected an https scheme", url)
+	case u.User != nil:
+		return nil, fmt.Errorf("invalid database URL %q, credentials go in the auth parameter", url)
+	case u.Fragment != "":
+		return nil, fmt.Errorf("invalid database URL %q, unexpected fragment", url)
+	}
+	for k := range u.Query() {
+		if k != nsParam {
+			return nil, fmt.Errorf("invalid database URL %q, unexpected query parameter %q", url, k)
+		}
+	}
+	if strings.HasSuffix(u.Path, ".json") {
+		return nil, fmt.Errorf("invalid database URL %q, the .json suffix is added to requests", url)
+	}
+	for _, seg := range splitPath(u.Path) {
+		if strings.ContainsAny(seg, ".#$[]") {
+			return nil, fmt.Errorf("invalid database URL %q, path segment %q contains one of . # $ [ ]", url, seg)
+		}
+	}
+	if err := ValidateURL(url); err != nil {
+		return nil, err
+	}
+	return New(url, opts...), nil
+}
+
+// splitURL separates the sanitized base URL from its query parameters.
+func splitURL(url string) (string, _url.Values) {
+	query := _url.Values{}
+	if i := strings.Index(url, "?"); i >= 0 {
+		query, _ = _url.ParseQuery(url[i+1:])
+		url = url[:i]
+	}
+	return sanitizeURL(url), query
+}
+
+// resolveURL returns the base URL and namespace parameter that requests
+// for url are sent with, routing them to the emulator when EmulatorHostEnv
+// is set.
+func resolveURL(url string) (base, ns string) {
+	base, query := splitURL(url)
+	ns = query.Get(nsParam)
+
+	host := os.Getenv(EmulatorHostEnv)
+	if host == "" || ns != "" {
+		return base, ns
+	}
+	namespace, err := Namespace(base)
+	if err != nil {
+		return base, ns
+	}
+	u, err := _url.Parse(base)
+	if err != nil {
+		return base, ns
+	}
+	return "http://" + host + strings.TrimSuffix(u.Path, "/"), namespace
+}
+
+func hostname(url string) string {
+	if i := strings.IndexAny(url, "/?"); i >= 0 {
+		url = url[:i]
+	}
+	if host, _, err := net.SplitHostPort(url); err == nil {
+		return host
+	}
+	return url
+}
+
+func isLocalHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
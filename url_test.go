@@ -0,0 +1,111 @@
+package firego
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	t.Parallel()
+	for url, expected := range map[string]string{
+		"https://somefirebaseapp.firebaseio.com":                          "somefirebaseapp",
+		"somefirebaseapp.firebaseIO.com/":                                 "somefirebaseapp",
+		"https://somefirebaseapp.europe-west1.firebasedatabase.app":       "somefirebaseapp",
+		"https://somefirebaseapp.europe-west1.firebasedatabase.app/users": "somefirebaseapp",
+		"http://localhost:9000?ns=somefirebaseapp":                        "somefirebaseapp",
+		"127.0.0.1:9000/?ns=somefirebaseapp":                              "somefirebaseapp",
+	} {
+		ns, err := Namespace(url)
+		require.NoError(t, err, url)
+		assert.Equal(t, expected, ns, url)
+	}
+}
+
+func TestNamespaceInvalid(t *testing.T) {
+	t.Parallel()
+	for url, msg := range map[string]string{
+		"https://app.europe-west1.firebaseio.com": "https://app.europe-west1.firebasedatabase.app",
+		"https://app.firebasedatabase.app":        "<namespace>.<region>",
+		"https://a.b.c.firebaseio.com":            "<namespace>.firebaseio.com",
+		"http://localhost:9000":                   `"ns" query parameter`,
+		"https://example.com":                     "expected a .firebaseio.com or .firebasedatabase.app host",
+	} {
+		err := ValidateURL(url)
+		if assert.Error(t, err, url) {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+}
+
+func TestNewURL(t *testing.T) {
+	t.Parallel()
+	for _, url := range []string{
+		"https://somefirebaseapp.firebaseio.com",
+		"https://somefirebaseapp.firebaseio.com/users/uid1",
+		"https://somefirebaseapp.europe-west1.firebasedatabase.app/",
+		"http://localhost:9000?ns=somefirebaseapp",
+	} {
+		fb, err := NewURL(url, WithAuth("token"))
+		require.NoError(t, err, url)
+		assert.Equal(t, "token", fb.params.Get(authParam), url)
+	}
+}
+
+func TestNewURLInvalid(t *testing.T) {
+	t.Parallel()
+	for url, msg := range map[string]string{
+		"somefirebaseapp.firebaseio.com":                    "https scheme",
+		"http://somefirebaseapp.firebaseio.com":             "https scheme",
+		"ftp://somefirebaseapp.firebaseio.com":              "https scheme",
+		"https://user:pw@somefirebaseapp.firebaseio.com":    "credentials",
+		"https://somefirebaseapp.firebaseio.com#users":      "fragment",
+		"https://somefirebaseapp.firebaseio.com?auth=token": `query parameter "auth"`,
+		"https://somefirebaseapp.firebaseio.com/users.json": ".json suffix",
+		"https://somefirebaseapp.firebaseio.com/a$b":        `segment "a$b"`,
+		"https://a.b.c.firebaseio.com":                      "<namespace>.firebaseio.com",
+		"http://localhost:9000":                             `"ns" query parameter`,
+		"https://somefirebaseapp.firebaseio.com/%zz":        "invalid URL escape",
+	} {
+		fb, err := NewURL(url)
+		assert.Nil(t, fb, url)
+		if assert.Error(t, err, url) {
+			assert.Contains(t, err.Error(), msg, url)
+		}
+	}
+}
+
+func TestDatabaseURL(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "https://app.firebaseio.com", DatabaseURL("app", ""))
+	assert.Equal(t, "https://app.firebaseio.com", DatabaseURL("app", "us-central1"))
+	assert.Equal(t, "https://app.asia-southeast1.firebasedatabase.app", DatabaseURL("app", "asia-southeast1"))
+}
+
+func TestNewRegionalURL(t *testing.T) {
+	t.Parallel()
+	fb := New("app.europe-west1.firebasedatabase.app/")
+	assert.Equal(t, "https://app.europe-west1.firebasedatabase.app", fb.url)
+	assert.Equal(t, "https://app.europe-west1.firebasedatabase.app/users/.json", fb.Child("users").String())
+}
+
+func TestNewEmulatorURL(t *testing.T) {
+	t.Parallel()
+	fb := New("localhost:9000/?ns=app")
+	assert.Equal(t, "http://localhost:9000", fb.url)
+	assert.Equal(t, "http://localhost:9000/users/.json?ns=app", fb.Child("users").String())
+}
+
+func TestNewEmulatorHostEnv(t *testing.T) {
+	defer os.Unsetenv(EmulatorHostEnv)
+	os.Setenv(EmulatorHostEnv, "localhost:9000")
+
+	fb := New("https://app.europe-west1.firebasedatabase.app/users")
+	assert.Equal(t, "http://localhost:9000/users/.json?ns=app", fb.String())
+
+	// URLs that already point at an emulator are left alone
+	fb = New("http://127.0.0.1:8080?ns=other")
+	assert.Equal(t, "http://127.0.0.1:8080/.json?ns=other", fb.String())
+}
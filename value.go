@@ -1,12 +1,128 @@
 package firego
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
 
 // Value gets the value of the Firebase reference.
-func (fb *Firebase) Value(v interface{}) error {
-	bytes, err := fb.doRequest("GET", nil)
+func (fb *Firebase) Value(v interface{}, opts ...RequestOption) error {
+	return fb.ValueContext(context.Background(), v, opts...)
+}
+
+// ValueContext is like Value but the request is canceled when ctx is done.
+func (fb *Firebase) ValueContext(ctx context.Context, v interface{}, opts ...RequestOption) error {
+	raw, err := fb.ValueRawContext(ctx, opts...)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(bytes, v)
+	return json.Unmarshal(raw, v)
+}
+
+// ValueRaw returns the JSON encoded value of the Firebase reference, e.g.
+// to pass it on or to decode it later.
+func (fb *Firebase) ValueRaw(opts ...RequestOption) (json.RawMessage, error) {
+	return fb.ValueRawContext(context.Background(), opts...)
+}
+
+// ValueRawContext is like ValueRaw but the request is canceled when ctx is
+// done.
+func (fb *Firebase) ValueRawContext(ctx context.Context, opts ...RequestOption) (json.RawMessage, error) {
+	bytes, err := fb.body(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return fb.decodeBody(bytes)
+}
+
+// body returns the value of the reference as sent by Firebase, or as
+// found in its cache.
+func (fb *Firebase) body(ctx context.Context, opts []RequestOption) ([]byte, error) {
+	ref := fb.cursorSlack()
+	if ref.cache != nil && len(opts) == 0 {
+		bytes, err := ref.cachedBody(ctx)
+		// the store may hand out the slice it keeps
+		return append([]byte(nil), bytes...), err
+	}
+	return ref.doRequest(ctx, "GET", nil, opts)
+}
+
+// decodeBody applies the key cursors, codecs and transforms of the
+// reference to a body returned by body.
+func (fb *Firebase) decodeBody(bytes []byte) ([]byte, error) {
+	bytes, err := fb.applyKeyCursors(bytes)
+	if err != nil {
+		return nil, err
+	}
+	return fb.decodeBytes(bytes)
+}
+
+// Exists reports whether the location of the reference holds a value. Only
+// the keys of its children are downloaded, so it is cheap to call on large
+// subtrees.
+func (fb *Firebase) Exists() (bool, error) {
+	return fb.ExistsContext(context.Background())
+}
+
+// ExistsContext is like Exists but the request is canceled when ctx is
+// done.
+func (fb *Firebase) ExistsContext(ctx context.Context) (bool, error) {
+	var v interface{}
+	if err := fb.location().ValueContext(ctx, &v, WithShallow()); err != nil {
+		return false, err
+	}
+	return v != nil, nil
+}
+
+// Keys returns the keys of the children of the reference in key order,
+// without downloading their values. It returns no keys if the location
+// does not hold an object.
+func (fb *Firebase) Keys() ([]string, error) {
+	return fb.KeysContext(context.Background())
+}
+
+// KeysContext is like Keys but the request is canceled when ctx is done.
+func (fb *Firebase) KeysContext(ctx context.Context) ([]string, error) {
+	var v interface{}
+	if err := fb.location().ValueContext(ctx, &v, WithShallow()); err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]interface{})
+	return sortedKeys(m), nil
+}
+
+// Count returns the number of children of the reference, 0 if the location
+// does not hold an object. Only the keys of the children are downloaded,
+// unless Firebase refuses to list that many keys in a single request, then
+// the children are counted page by page.
+func (fb *Firebase) Count() (int, error) {
+	return fb.CountContext(context.Background())
+}
+
+// CountContext is like Count but the requests are canceled when ctx is
+// done.
+func (fb *Firebase) CountContext(ctx context.Context) (int, error) {
+	keys, err := fb.KeysContext(ctx)
+	if err == nil {
+		return len(keys), nil
+	}
+	if !tooLarge(err) {
+		return 0, err
+	}
+
+	var n int
+	err = fb.location().forEachChild(ctx, aggregatePageSize, func(string, interface{}) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// tooLarge reports whether err is Firebase refusing a read because the
+// response would exceed the size of a single request.
+func tooLarge(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusBadRequest && strings.Contains(e.Message, "exceeds the maximum size")
 }
@@ -1,9 +1,16 @@
 package firego
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zabawaba99/firetest"
 )
 
@@ -16,7 +23,7 @@ func TestValue(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 
 	server.Set("", response)
 
@@ -25,3 +32,133 @@ func TestValue(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, response, v)
 }
+
+func TestValueContextDeadline(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var v interface{}
+	err := New(server.URL).ValueContext(ctx, &v)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		queries = append(queries, req.URL.RawQuery)
+		if strings.HasPrefix(req.URL.Path, "/users/a/") {
+			w.Write([]byte(`{"name":true}`))
+			return
+		}
+		w.Write([]byte("null"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	ok, err := fb.Child("users/a").OrderBy("name").Exists()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = fb.Child("users/b").Exists()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"shallow=true", "shallow=true"}, queries)
+}
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.RawQuery
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/users/"):
+			w.Write([]byte(`{"b":true,"10":true,"a":true,"2":true}`))
+		case strings.HasPrefix(req.URL.Path, "/name/"):
+			w.Write([]byte(`"bob"`))
+		default:
+			w.Write([]byte("null"))
+		}
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	keys, err := fb.Child("users").LimitToFirst(1).Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "10", "a", "b"}, keys)
+	assert.Equal(t, "shallow=true", query)
+
+	for _, path := range []string{"name", "missing"} {
+		keys, err = fb.Child(path).Keys()
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+	}
+}
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		queries = append(queries, q.Encode())
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/huge/") && q.Get(shallowParam) != "":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Data requested exceeds the maximum size that can be accessed with a single request."}`))
+		case strings.HasPrefix(req.URL.Path, "/denied/"):
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Permission denied"}`))
+		case strings.HasPrefix(req.URL.Path, "/missing/"):
+			w.Write([]byte("null"))
+		default:
+			w.Write([]byte(`{"a":1,"b":2,"c":3}`))
+		}
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	n, err := fb.Child("users").Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"shallow=true"}, queries)
+
+	n, err = fb.Child("missing").Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// too many keys for a single request are counted page by page
+	queries = nil
+	n, err = fb.Child("huge").Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	require.Len(t, queries, 2)
+	assert.Equal(t, `limitToFirst=1000&orderBy=%22%24key%22`, queries[1])
+
+	_, err = fb.Child("denied").Count()
+	assert.Error(t, err)
+}
+
+func TestValueRaw(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL).Child("raw")
+	assert.NoError(t, fb.SetRaw(json.RawMessage(`{"foo":["bar",1]}`)))
+	assert.Equal(t, map[string]interface{}{"foo": []interface{}{"bar", 1.0}}, server.Get("raw"))
+
+	raw, err := fb.ValueRaw()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"foo":["bar",1]}`, string(raw))
+
+	raw, err = fb.Child("missing").ValueRaw()
+	assert.NoError(t, err)
+	assert.JSONEq(t, "null", string(raw))
+}
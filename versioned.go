@@ -0,0 +1,134 @@
+package firego
+
+import (
+	"sort"
+	"time"
+)
+
+// Versioned wraps a Firebase reference so that every write also appends
+// the value it replaced to a history location, providing a lightweight
+// audit trail.
+type Versioned struct {
+	ref     *Firebase
+	history *Firebase
+
+	// Actor identifies who is performing the writes and is stored
+	// with every history entry.
+	Actor string
+	// MaxHistory is the number of history entries to retain, older
+	// entries are pruned after every write. Zero retains every entry.
+	MaxHistory int
+}
+
+// HistoryEntry is a value that a Versioned reference used to hold.
+type HistoryEntry struct {
+	// Key is the push ID of the entry inside the history location.
+	Key string `json:"-"`
+	// Value that was replaced.
+	Value interface{} `json:"value"`
+	// Timestamp of the write that replaced the value, in milliseconds
+	// since the Unix epoch.
+	Timestamp int64 `json:"timestamp"`
+	// Actor that replaced the value.
+	Actor string `json:"actor,omitempty"`
+}
+
+// NewVersioned creates a Versioned wrapper that writes to ref and
+// records previous values under history. The history location must not
+// be a child of ref, otherwise a Set would overwrite it.
+func NewVersioned(ref, history *Firebase) *Versioned {
+	return &Versioned{ref: ref, history: history}
+}
+
+// Set the value of the reference after recording the current one.
+func (v *Versioned) Set(value interface{}) error {
+	return v.write(value, v.ref.Set)
+}
+
+// Update the reference with the given value after recording the current one.
+func (v *Versioned) Update(value interface{}) error {
+	return v.write(value, v.ref.Update)
+}
+
+// Value gets the current value of the reference.
+func (v *Versioned) Value(value interface{}) error {
+	return v.ref.Value(value)
+}
+
+// History returns the recorded history entries, oldest first.
+func (v *Versioned) History() ([]HistoryEntry, error) {
+	var m map[string]HistoryEntry
+	if err := v.history.Value(&m); err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(m))
+	for k, e := range m {
+		e.Key = k
+		entries = append(entries, e)
+	}
+	sort.Sort(historyByKey(entries))
+	return entries, nil
+}
+
+func (v *Versioned) write(value interface{}, fn func(interface{}, ...RequestOption) error) error {
+	var prev interface{}
+	if err := v.ref.Value(&prev); err != nil {
+		return err
+	}
+
+	if prev != nil {
+		entry := map[string]interface{}{
+			"value":     prev,
+			"timestamp": time.Now().UnixNano() / int64(time.Millisecond),
+		}
+		if v.Actor != "" {
+			entry["actor"] = v.Actor
+		}
+		if _, err := v.history.Push(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(value); err != nil {
+		return err
+	}
+	return v.prune()
+}
+
+// prune removes the oldest history entries beyond MaxHistory.
+func (v *Versioned) prune() error {
+	if v.MaxHistory <= 0 {
+		return nil
+	}
+
+	keys := v.history.copy()
+	keys.Shallow(true)
+	var m map[string]interface{}
+	if err := keys.Value(&m); err != nil {
+		return err
+	}
+	if len(m) <= v.MaxHistory {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(m))
+	for k := range m {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	update := map[string]interface{}{}
+	for _, k := range sorted[:len(sorted)-v.MaxHistory] {
+		update[k] = nil
+	}
+	return v.history.Update(update)
+}
+
+// historyByKey sorts history entries chronologically, push IDs sort
+// lexicographically in the order they were created.
+type historyByKey []HistoryEntry
+
+func (h historyByKey) Len() int           { return len(h) }
+func (h historyByKey) Less(i, j int) bool { return h[i].Key < h[j].Key }
+func (h historyByKey) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
@@ -0,0 +1,54 @@
+package firego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestVersionedSet(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	v := NewVersioned(fb.Child("config"), fb.Child("history/config"))
+	v.Actor = "tester"
+
+	require.NoError(t, v.Set("one"))
+	require.NoError(t, v.Set("two"))
+	require.NoError(t, v.Set("three"))
+	assert.Equal(t, "three", server.Get("config"))
+
+	entries, err := v.History()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "one", entries[0].Value)
+	assert.Equal(t, "two", entries[1].Value)
+	assert.Equal(t, "tester", entries[1].Actor)
+	assert.True(t, entries[0].Key < entries[1].Key)
+}
+
+func TestVersionedPrune(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	fb := New(server.URL)
+	v := NewVersioned(fb.Child("config"), fb.Child("history/config"))
+	v.MaxHistory = 2
+
+	for _, val := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, v.Update(map[string]string{"val": val}))
+	}
+
+	entries, err := v.History()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"val": "c"}, entries[0].Value)
+	assert.Equal(t, map[string]interface{}{"val": "d"}, entries[1].Value)
+}
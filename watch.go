@@ -1,100 +1,280 @@
 package firego
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
-	"log"
-	"strings"
-	"sync"
+	"errors"
+	"io/ioutil"
+
+	"github.com/zabawaba99/firego/sse"
 )
 
 // EventTypeError is the type that is set on an Event struct if an
 // error occurs while watching a Firebase reference.
 const EventTypeError = "event_error"
 
+// ErrEventTooLarge is returned by WatchErr when the watch ended because
+// Firebase sent an event larger than the maximum event size of the
+// reference, see WithMaxEventSize.
+var ErrEventTooLarge = errors.New("watch event exceeds the maximum event size")
+
+// streamBufferSize is the size of the buffer the events of a watch are
+// read with, larger events are read in parts.
+const streamBufferSize = 64 * 1024
+
+// ErrWatchCanceled is returned by WatchErr when Firebase canceled the
+// watch without giving a reason, e.g. because the security rules no
+// longer allow reading the location.
+var ErrWatchCanceled = errors.New("watch canceled by Firebase")
+
+// The types of the events Firebase sends to a Watch.
+//
+// Reference https://firebase.google.com/docs/database/rest/retrieve-data#section-rest-streaming
+const (
+	// EventTypePut is sent when the value at Path was replaced by Data,
+	// and with the initial value of the location once the watch is
+	// established.
+	EventTypePut = "put"
+	// EventTypePatch is sent when the children of the value at Path
+	// were updated with the ones in Data.
+	EventTypePatch = "patch"
+	// EventTypeKeepAlive is sent periodically while nothing changes.
+	EventTypeKeepAlive = "keep-alive"
+	// EventTypeCancel is sent when Firebase ends the watch, e.g. because
+	// the security rules no longer allow reading the location. Data is
+	// an *Error if Firebase gave a reason.
+	EventTypeCancel = "cancel"
+	// EventTypeAuthRevoked is sent when the auth token of the watch is
+	// no longer valid, Data is the reason Firebase gave.
+	EventTypeAuthRevoked = "auth_revoked"
+)
+
 // Event represents a notification received when watching a
 // firebase reference.
 type Event struct {
-	// Type of event that was received
+	// Type of event that was received, one of the EventType constants
 	Type string
 	// Path to the data that changed
 	Path string
 	// Data that changed
 	Data interface{}
+	// Raw is the JSON encoded Data, after the codecs and transforms of
+	// the reference were applied. It is empty for EventTypeError.
+	Raw json.RawMessage
+}
+
+// Value decodes the JSON encoded data of the event into v.
+func (e Event) Value(v interface{}) error {
+	raw := e.Raw
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// newEvent creates the event of the given type, data is the payload of
+// the event as sent by Firebase.
+func (fb *Firebase) newEvent(typ string, data []byte) (Event, error) {
+	event := Event{Type: typ, Raw: json.RawMessage(data)}
+	switch typ {
+	case EventTypePut, EventTypePatch:
+		var payload struct {
+			Path string          `json:"path"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return Event{}, err
+		}
+		event.Path, event.Raw = payload.Path, payload.Data
+
+		if len(fb.codecs) > 0 || len(fb.transforms) > 0 {
+			v, err := decodeNumbers(payload.Data)
+			if err != nil {
+				return Event{}, err
+			}
+			if v, err = fb.decodeValue(splitPath(event.Path), v); err != nil {
+				return Event{}, err
+			}
+			if event.Raw, err = json.Marshal(v); err != nil {
+				return Event{}, err
+			}
+		}
+		// Data holds float64s like the values decoded without codecs
+		var v interface{}
+		if err := json.Unmarshal(event.Raw, &v); err != nil {
+			return Event{}, err
+		}
+		event.Data = v
+		return event, nil
+	case EventTypeCancel:
+		var reason string
+		json.Unmarshal(data, &reason)
+		if reason != "" {
+			event.Data = &Error{Message: reason, Method: "GET", URL: fb.redactedURL()}
+		}
+	case EventTypeAuthRevoked:
+		var reason string
+		json.Unmarshal(data, &reason)
+		event.Data = reason
+	}
+	return event, nil
+}
+
+// watch is the state of a single watch of a reference.
+type watch struct {
+	// ctx is canceled once the watch is torn down, by StopWatching or
+	// because the context the watch was started with is done
+	ctx    context.Context
+	cancel context.CancelFunc
+	parent context.Context
 }
 
-// StopWatching stops tears down all connections that are watching.
+// err returns why the watch was torn down: nil if StopWatching stopped
+// it, the error of the context it was started with otherwise.
+func (w *watch) err() error {
+	return w.parent.Err()
+}
+
+// StopWatching tears down all the watches of the reference, it can be
+// called any number of times and the reference can be watched again
+// afterwards.
 func (fb *Firebase) StopWatching() {
-	if fb.isWatching() {
-		// signal connection to terminal
-		fb.stopWatching <- struct{}{}
-		// flip the bit back to not watching
-		fb.setWatching(false)
+	fb.watchMtx.Lock()
+	for w := range fb.watches {
+		w.cancel()
 	}
+	fb.watches = nil
+	fb.watchMtx.Unlock()
 }
 
 func (fb *Firebase) isWatching() bool {
 	fb.watchMtx.Lock()
-	v := fb.watching
+	v := len(fb.watches) > 0
 	fb.watchMtx.Unlock()
 	return v
 }
 
-func (fb *Firebase) setWatching(v bool) {
+// startWatching records a new watch of the reference that is also torn
+// down when ctx is done or the client is closed, it returns ErrClosed if
+// the client already is.
+func (fb *Firebase) startWatching(ctx context.Context) (*watch, error) {
+	w := &watch{parent: ctx}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	if !fb.life.watch(w) {
+		w.cancel()
+		return nil, ErrClosed
+	}
 	fb.watchMtx.Lock()
-	fb.watching = v
+	defer fb.watchMtx.Unlock()
+	if fb.watches == nil {
+		fb.watches = map[*watch]struct{}{}
+	}
+	fb.watches[w] = struct{}{}
+	return w, nil
+}
+
+// stoppedWatching forgets w once it ended, err is the reason it ended.
+func (fb *Firebase) stoppedWatching(w *watch, err error) {
+	w.cancel()
+	fb.life.unwatch(w)
+	fb.watchMtx.Lock()
+	delete(fb.watches, w)
+	fb.watchErr = err
 	fb.watchMtx.Unlock()
 }
 
+// WatchErr returns the error that ended the watch of the reference that
+// ended last, once its notifications channel is closed. It is nil if the watch was
+// stopped with StopWatching, the error of the context if it was started
+// with WatchContext and the context is done, ErrWatchCanceled or an
+// *Error if Firebase sent a cancel event, and the error of the
+// connection or of decoding an event otherwise.
+func (fb *Firebase) WatchErr() error {
+	fb.watchMtx.Lock()
+	defer fb.watchMtx.Unlock()
+	return fb.watchErr
+}
+
 // Watch listens for changes on a firebase instance and
 // passes over to the given chan.
 //
-// Only one connection can be established at a time. The
-// second call to this function without a call to fb.StopWatching
-// will close the channel given and return nil immediately.
-func (fb *Firebase) Watch(notifications chan Event) error {
-	if fb.isWatching() {
-		close(notifications)
-		return nil
-	}
-	// set watching flag
-	fb.setWatching(true)
+// The query parameters of the reference, such as OrderBy, StartAt,
+// EndAt and the limits, are sent with the request so that Firebase
+// only streams the children the query selects. If Firebase refuses
+// the query, e.g. because the child it orders by is not indexed,
+// Watch returns an *Error. If it cancels the stream later on, the
+// cancel event carries an *Error with the reason when one is given,
+// e.g. one that wraps ErrIndexNotDefined.
+//
+// Every call establishes a connection of its own, so a reference can
+// be watched more than once at a time. StopWatching tears down all of
+// them, use WatchContext to stop a single one.
+//
+// The RequestOptions of the watch, such as WithBackpressure or the
+// query options, apply to this watch only.
+func (fb *Firebase) Watch(notifications chan Event, opts ...RequestOption) error {
+	return fb.WatchContext(context.Background(), notifications, opts...)
+}
 
-	// build SSE request
-	req, err := fb.makeRequest("GET", nil)
+// WatchContext is like Watch but the watch is also torn down when ctx is
+// done: the connection is closed and so is notifications, without an
+// EventTypeError event, even if nothing receives from it anymore. The same
+// goes for StopWatching, which also tears down the watches whose
+// connection is being established, WatchContext then returns nil.
+func (fb *Firebase) WatchContext(ctx context.Context, notifications chan Event, opts ...RequestOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w, err := fb.startWatching(ctx)
 	if err != nil {
-		fb.setWatching(false)
 		return err
 	}
-	req.Header.Add("Accept", "text/event-stream")
 
-	// do request
-	resp, err := fb.client.Do(req)
+	conn, r := fb.prepare(opts)
+	if r.pollInterval > 0 {
+		return fb.watchPolling(w, conn, r, notifications)
+	}
+
+	// do SSE request
+	r.header.Add("Accept", "text/event-stream")
+	resp, err := conn.send(w.ctx, "GET", nil, r.header)
 	if err != nil {
-		fb.setWatching(false)
+		if w.ctx.Err() != nil {
+			// torn down before the connection was established
+			err = w.err()
+		}
+		fb.stoppedWatching(w, err)
+		if err == nil {
+			close(notifications)
+		}
 		return err
 	}
+	if resp.StatusCode/200 != 1 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		err := fb.newError("GET", resp.StatusCode, b)
+		fb.stoppedWatching(w, err)
+		return err
+	}
+
+	r.monitor.established()
+	notifications, end := fb.pipe(w, notifications, r)
 
 	// start parsing response body
 	go func() {
 		// build scanner for response body
-		scanner := bufio.NewReader(resp.Body)
-		var (
-			scanErr        error
-			closedManually bool
-			mtx            sync.Mutex
-		)
-
-		// monitor the stopWatching channel
-		// if we're told to stop, close the response Body
-		go func() {
-			<-fb.stopWatching
+		scanner := sse.NewReaderSize(r.monitor.reader(resp.Body), streamBufferSize)
+		scanner.MaxSize = fb.maxEventSize
+		var scanErr, canceled error
 
-			mtx.Lock()
-			closedManually = true
-			mtx.Unlock()
-
-			resp.Body.Close()
+		// once the watch is torn down, close the response Body
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-w.ctx.Done():
+				resp.Body.Close()
+			case <-done:
+			}
 		}()
 	scanning:
 		for scanErr == nil {
@@ -102,102 +282,92 @@ func (fb *Firebase) Watch(notifications chan Event) error {
 			// 		event: put
 			// 		data: {"path":"/","data":{"foo":"bar"}}
 
-			var evt []byte
-			var dat []byte
-			isPrefix := true
-			var result []byte
-
-			// For possible results larger than 64 * 1024 bytes (MaxTokenSize)
-			// we need bufio#ReadLine()
-			// 1. step: scan for the 'event:' part. ReadLine() oes not return the \n
-			// so we have to add it to our result buffer.
-			evt, isPrefix, scanErr = scanner.ReadLine()
-			if scanErr != nil {
-				break scanning
-			}
-			result = append(result, evt...)
-			result = append(result, '\n')
-
-			// 2. step: scan for the 'data:' part. Firebase returns just one 'data:'
-			// part, but the value can be very large. If we exceed a certain length
-			// isPrefix will be true until all data is read.
-			for {
-				dat, isPrefix, scanErr = scanner.ReadLine()
-				if scanErr != nil {
-					break scanning
-				}
-				result = append(result, dat...)
-				if !isPrefix {
-					break
+			// Firebase sends a single 'data:' line, but the value can be
+			// very large, so lines are read in parts up to the maximum
+			// event size
+			var evt sse.Event
+			if evt, scanErr = scanner.Next(); scanErr != nil {
+				if scanErr == sse.ErrTooLarge {
+					scanErr = ErrEventTooLarge
 				}
-			}
-			// Again we add the \n
-			result = append(result, '\n')
-			_, _, scanErr = scanner.ReadLine()
-			if scanErr != nil {
 				break scanning
 			}
+			typ, data := evt.Type, evt.Data
 
-			txt := string(result)
-			parts := strings.Split(txt, "\n")
-
-			// create a base event
-			event := Event{
-				Type: strings.Replace(parts[0], "event: ", "", 1),
+			switch typ {
+			case "rules_debug":
+				fb.logger.Printf("Rules-Debug: %s\n", data)
+				continue
+			case EventTypePut, EventTypePatch, EventTypeKeepAlive, EventTypeCancel, EventTypeAuthRevoked:
+			default:
+				continue
 			}
+			r.monitor.received(typ)
 
-			// should be reacting differently based off the type of event
-			switch event.Type {
-			case "put", "patch": // we've got extra data we've got to parse
+			var event Event
+			if event, scanErr = fb.newEvent(typ, data); scanErr != nil {
+				break scanning
+			}
 
-				// the extra data is in json format
-				var data map[string]interface{}
-				if err := json.Unmarshal([]byte(strings.Replace(parts[1], "data: ", "", 1)), &data); err != nil {
-					scanErr = err
-					break scanning
+			// ship it
+			select {
+			case notifications <- event:
+			case <-w.ctx.Done():
+				break scanning
+			}
+			if typ == EventTypeCancel {
+				// Firebase no longer sends events for the location
+				canceled = ErrWatchCanceled
+				if err, ok := event.Data.(error); ok {
+					canceled = err
 				}
-
-				// set the extra fields
-				event.Path = data["path"].(string)
-				event.Data = data["data"]
-
-				// ship it
-				notifications <- event
-			case "keep-alive":
-				// received ping - nothing to do here
-			case "cancel":
-				// The data for this event is null
-				// This event will be sent if the Security and Firebase Rules
-				// cause a read at the requested location to no longer be allowed
-
-				// send the cancel event
-				notifications <- event
 				break scanning
-			case "auth_revoked":
-				// The data for this event is a string indicating that a the credential has expired
-				// This event will be sent when the supplied auth parameter is no longer valid
-
-				// TODO: handle
-			case "rules_debug":
-				log.Printf("Rules-Debug: %s\n", txt)
 			}
 		}
 
 		// check error type
-		mtx.Lock()
-		closed := closedManually
-		mtx.Unlock()
-		if !closed && scanErr != nil {
-			notifications <- Event{
-				Type: EventTypeError,
-				Data: scanErr,
+		var err error
+		switch {
+		case w.ctx.Err() != nil:
+			err = w.err()
+		case scanErr != nil:
+			err = scanErr
+			select {
+			case notifications <- Event{Type: EventTypeError, Data: scanErr}:
+			case <-w.ctx.Done():
 			}
+		default:
+			err = canceled
 		}
 
-		// call stop watching to reset state and cleanup routines
-		fb.StopWatching()
+		// reset state and cleanup routines
+		close(done)
+		resp.Body.Close()
+		end(err)
+	}()
+	return nil
+}
+
+// pipe returns the channel the events of w are to be sent to and the
+// function that ends w with its error once they were delivered to
+// notifications, which it then closes. The events go through a relay if the
+// watch does not block on backpressure.
+func (fb *Firebase) pipe(w *watch, notifications chan Event, r *request) (chan Event, func(error)) {
+	end := func(err error) {
+		fb.stoppedWatching(w, err)
 		close(notifications)
+	}
+	if r.backpressure == BackpressureBlock {
+		return notifications, end
+	}
 
+	in, ended := make(chan Event), make(chan error, 1)
+	go func() {
+		relay(w.ctx, in, notifications, r.backpressure, r.pending)
+		end(<-ended)
 	}()
-	return nil
+	return in, func(err error) {
+		ended <- err
+		close(in)
+	}
 }
@@ -1,9 +1,15 @@
 package firego
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,7 +27,7 @@ func TestWatch(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 
 	notifications := make(chan Event)
 	err := fb.Watch(notifications)
@@ -67,7 +73,7 @@ func TestWatchRedirectPreservesHeader(t *testing.T) {
 	}))
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 	notifications := make(chan Event)
 
 	err := fb.Watch(notifications)
@@ -89,7 +95,7 @@ func TestWatchError(t *testing.T) {
 
 	var (
 		notifications = make(chan Event)
-		fb            = New(server.URL, nil)
+		fb            = New(server.URL)
 	)
 	defer server.Close()
 
@@ -106,6 +112,309 @@ func TestWatchError(t *testing.T) {
 	assert.Implements(t, new(error), event.Data)
 }
 
+func TestWatchQuery(t *testing.T) {
+	t.Parallel()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.Query().Encode()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"a\":{\"age\":30}}}\n\n"))
+		w.Write([]byte("event: cancel\ndata: \"Index not defined, add \\\".indexOn\\\": \\\"age\\\", for path \\\"/users\\\", to the rules\"\n\n"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).OrderByChild("age").StartAtValue(18).LimitToFirst(10)
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+
+	event := <-notifications
+	assert.Equal(t, "put", event.Type)
+	assert.Equal(t, `limitToFirst=10&orderBy=%22age%22&startAt=18`, query)
+
+	event = <-notifications
+	assert.Equal(t, "cancel", event.Type)
+	err, ok := event.Data.(*Error)
+	require.True(t, ok, "cancel event carries an *Error")
+	assert.Equal(t, ErrIndexNotDefined, err.Unwrap())
+	assert.Equal(t, "firego: GET "+fb.String()+`: Index not defined, add ".indexOn": "age", for path "/users", to the rules`, err.Error())
+
+	_, ok = <-notifications
+	assert.False(t, ok, "notifications should be closed")
+}
+
+func TestWatchRejected(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Index not defined, add \".indexOn\": \"age\", for path \"/\", to the rules"}`))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).OrderByChild("age")
+	err := fb.Watch(make(chan Event))
+	require.IsType(t, &Error{}, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*Error).StatusCode)
+	assert.Equal(t, ErrIndexNotDefined, err.(*Error).Unwrap())
+
+	// the reference can watch again
+	assert.False(t, fb.isWatching())
+}
+
+func TestWatchEventTypes(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/users/a\",\"data\":{\"name\":\"Ann\"}}\n\n"))
+		w.Write([]byte("event: keep-alive\ndata: null\n\n"))
+		w.Write([]byte("event: rules_debug\ndata: \"evaluating\"\n\n"))
+		w.Write([]byte("event: patch\ndata: {\"path\":\"/users\",\"data\":{\"b/name\":\"Bob\"}}\n\n"))
+		w.Write([]byte("event: auth_revoked\ndata: \"credential is no longer valid\"\n\n"))
+		w.Write([]byte("event: cancel\ndata: null\n\n"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}), WithLogger(log.New(ioutil.Discard, "", 0)))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+
+	var events []Event
+	for event := range notifications {
+		events = append(events, event)
+	}
+	require.Len(t, events, 5)
+
+	assert.Equal(t, EventTypePut, events[0].Type)
+	assert.Equal(t, "/users/a", events[0].Path)
+	assert.Equal(t, map[string]interface{}{"name": "Ann"}, events[0].Data)
+	var user struct{ Name string }
+	require.NoError(t, events[0].Value(&user))
+	assert.Equal(t, "Ann", user.Name)
+
+	assert.Equal(t, EventTypeKeepAlive, events[1].Type)
+	assert.Nil(t, events[1].Data)
+
+	assert.Equal(t, EventTypePatch, events[2].Type)
+	assert.JSONEq(t, `{"b/name":"Bob"}`, string(events[2].Raw))
+
+	assert.Equal(t, EventTypeAuthRevoked, events[3].Type)
+	assert.Equal(t, "credential is no longer valid", events[3].Data)
+
+	assert.Equal(t, EventTypeCancel, events[4].Type)
+	assert.Nil(t, events[4].Data)
+	var v interface{}
+	require.NoError(t, events[4].Value(&v))
+	assert.Nil(t, v)
+}
+
+func TestWatchEventCodecs(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/name\",\"data\":\"Ann\"}\n\n"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{})).WithCodec(suffixCodec("nn"))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+
+	event := <-notifications
+	assert.Equal(t, "A", event.Data)
+	var name string
+	require.NoError(t, event.Value(&name))
+	assert.Equal(t, "A", name)
+	for range notifications {
+		// the stream ends once the handler returns
+	}
+}
+
+func TestWatchContext(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":2}\n\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchContext(ctx, notifications))
+
+	event := <-notifications
+	assert.Equal(t, 1.0, event.Data)
+
+	// nothing receives the second event, the watch ends anyway
+	cancel()
+	for event := range notifications {
+		assert.NotEqual(t, EventTypeError, event.Type)
+	}
+	assert.False(t, fb.isWatching())
+
+	// the reference can watch again
+	notifications = make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+	<-notifications
+	fb.StopWatching()
+	fb.StopWatching()
+	for range notifications {
+	}
+
+	assert.Equal(t, context.Canceled, fb.WatchContext(ctx, make(chan Event)))
+}
+
+func TestWatchErr(t *testing.T) {
+	t.Parallel()
+	var body string
+	var mtx sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		b := body
+		mtx.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(b))
+	}))
+	defer server.Close()
+
+	watch := func(stream string) error {
+		mtx.Lock()
+		body = stream
+		mtx.Unlock()
+		fb := New(server.URL, WithHTTPClient(&http.Client{}))
+		notifications := make(chan Event)
+		require.NoError(t, fb.Watch(notifications))
+		for range notifications {
+		}
+		return fb.WatchErr()
+	}
+
+	assert.Equal(t, ErrWatchCanceled, watch("event: cancel\ndata: null\n\n"))
+	err := watch("event: cancel\ndata: \"Permission denied\"\n\n")
+	require.IsType(t, &Error{}, err)
+	assert.Equal(t, ErrPermissionDenied, err.(*Error).Unwrap())
+	assert.Equal(t, io.EOF, watch("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+	assert.IsType(t, &json.SyntaxError{}, watch("event: put\ndata: {\n\n"))
+
+	// stopped watches end without an error
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer live.Close()
+	fb := New(live.URL, WithHTTPClient(&http.Client{}))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+	<-notifications
+	fb.StopWatching()
+	for range notifications {
+	}
+	assert.NoError(t, fb.WatchErr())
+}
+
+func TestWatchLargeEvents(t *testing.T) {
+	t.Parallel()
+	large := strings.Repeat("x", 3*streamBufferSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":\"" + large + "\"}\n\n"))
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":\"" + large + large + "\"}\n\n"))
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}), WithMaxEventSize(4*streamBufferSize))
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications))
+
+	event := <-notifications
+	assert.Equal(t, large, event.Data)
+	event = <-notifications
+	assert.Equal(t, EventTypeError, event.Type)
+	assert.Equal(t, ErrEventTooLarge, event.Data)
+	_, ok := <-notifications
+	assert.False(t, ok)
+	assert.Equal(t, ErrEventTooLarge, fb.WatchErr())
+}
+
+func TestWatchConcurrently(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("a", 1)
+	fb := New(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	first, second := make(chan Event), make(chan Event)
+	require.NoError(t, fb.WatchContext(ctx, first))
+	require.NoError(t, fb.Watch(second))
+	assert.Equal(t, 1.0, valueAt((<-first).Data, []string{"a"}))
+	assert.Equal(t, 1.0, valueAt((<-second).Data, []string{"a"}))
+
+	// the watches end independently
+	cancel()
+	for range first {
+	}
+	assert.True(t, fb.isWatching())
+	server.Set("a", 2)
+	assert.Equal(t, 2.0, (<-second).Data)
+
+	fb.StopWatching()
+	for range second {
+	}
+	assert.False(t, fb.isWatching())
+	assert.NoError(t, fb.WatchErr())
+}
+
+func TestWatchLifecycle(t *testing.T) {
+	t.Parallel()
+	established := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("slow") != "" {
+			// the headers are never sent
+			close(established)
+			<-req.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+	fb.StopWatching()
+	for i := 0; i < 2; i++ {
+		// watching again after StopWatching
+		notifications := make(chan Event)
+		require.NoError(t, fb.Watch(notifications))
+		<-notifications
+		fb.StopWatching()
+		fb.StopWatching()
+		_, ok := <-notifications
+		assert.False(t, ok)
+		assert.False(t, fb.isWatching())
+	}
+
+	// stopped while the connection is established
+	slow := fb.copy()
+	slow.setParam("slow", "true")
+	go func() {
+		<-established
+		slow.StopWatching()
+	}()
+	notifications := make(chan Event)
+	require.NoError(t, slow.Watch(notifications))
+	_, ok := <-notifications
+	assert.False(t, ok)
+	assert.NoError(t, slow.WatchErr())
+}
+
 func TestStopWatch(t *testing.T) {
 	t.Parallel()
 
@@ -113,7 +422,7 @@ func TestStopWatch(t *testing.T) {
 	server.Start()
 	defer server.Close()
 
-	fb := New(server.URL, nil)
+	fb := New(server.URL)
 
 	notifications := make(chan Event)
 	go func() {
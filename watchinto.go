@@ -0,0 +1,91 @@
+//go:build go1.18
+// +build go1.18
+
+package firego
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// TypedEvent is an event of WatchInto.
+type TypedEvent[T any] struct {
+	// Type of event that was received, one of the EventType constants
+	Type string
+	// Path to the data that changed
+	Path string
+	// Value is the value of the watched location once the event was
+	// applied, decoded as a T.
+	Value T
+	// Err is the error of an EventTypeError event, the reason of an
+	// EventTypeCancel event if Firebase gave one, or the error decoding
+	// the value as a T, in which case Value is the last value decoded.
+	Err error
+}
+
+// WatchInto is like Watch but the changes of put and patch events are
+// applied to a copy of the value of the location, which is sent decoded as
+// a T with every event:
+//
+//	events := make(chan firego.TypedEvent[map[string]User])
+//	if err := firego.WatchInto(fb.Child("users"), events); err != nil {
+//		return err
+//	}
+//	for event := range events {
+//		fmt.Println(len(event.Value), "users")
+//	}
+//
+// StopWatching ends the watch and closes events, even if nothing receives
+// from it anymore.
+func WatchInto[T any](fb *Firebase, events chan<- TypedEvent[T]) error {
+	w, err := fb.startWatching(context.Background())
+	if err != nil {
+		return err
+	}
+	ctx := w.ctx
+
+	conn := fb.copy()
+	notifications := make(chan Event)
+	if err := conn.WatchContext(ctx, notifications); err != nil {
+		fb.stoppedWatching(w, err)
+		return err
+	}
+
+	go func() {
+		defer func() {
+			err := conn.WatchErr()
+			if ctx.Err() != nil {
+				err = w.err()
+			}
+			fb.stoppedWatching(w, err)
+			close(events)
+		}()
+		var (
+			value   interface{}
+			current T
+		)
+		for event := range notifications {
+			e := TypedEvent[T]{Type: event.Type, Path: event.Path}
+			if v, ok := withEvent(value, event); ok {
+				value = v
+				var next T
+				raw, _ := json.Marshal(value)
+				if err := json.Unmarshal(raw, &next); err != nil {
+					e.Err = err
+				} else {
+					current = next
+				}
+			} else if err, ok := event.Data.(error); ok {
+				e.Err = err
+			}
+			e.Value = current
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				// stopped while nothing receives from events
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,85 @@
+//go:build go1.18
+// +build go1.18
+
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+func TestWatchInto(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"name\":\"Ann\",\"age\":30}}\n\n"))
+		w.Write([]byte("event: patch\ndata: {\"path\":\"/\",\"data\":{\"age\":31}}\n\n"))
+		w.Write([]byte("event: put\ndata: {\"path\":\"/name\",\"data\":null}\n\n"))
+		w.Write([]byte("event: keep-alive\ndata: null\n\n"))
+		w.Write([]byte("event: put\ndata: {\"path\":\"/age\",\"data\":\"old\"}\n\n"))
+	}))
+	defer server.Close()
+
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	events := make(chan TypedEvent[user])
+	require.NoError(t, WatchInto(New(server.URL, WithHTTPClient(&http.Client{})), events))
+
+	e := <-events
+	assert.Equal(t, TypedEvent[user]{Type: EventTypePut, Path: "/", Value: user{Name: "Ann", Age: 30}}, e)
+	e = <-events
+	assert.Equal(t, EventTypePatch, e.Type)
+	assert.Equal(t, user{Name: "Ann", Age: 31}, e.Value)
+	e = <-events
+	assert.Equal(t, "/name", e.Path)
+	assert.Equal(t, user{Age: 31}, e.Value)
+	e = <-events
+	assert.Equal(t, EventTypeKeepAlive, e.Type)
+	assert.Equal(t, user{Age: 31}, e.Value)
+
+	// values that are not a T keep the last one
+	e = <-events
+	assert.Error(t, e.Err)
+	assert.Equal(t, user{Age: 31}, e.Value)
+
+	e = <-events
+	assert.Equal(t, EventTypeError, e.Type)
+	assert.Error(t, e.Err)
+	for range events {
+	}
+}
+
+func TestWatchIntoStopWatching(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+	server.Set("count", 1)
+
+	fb := New(server.URL).Child("count")
+	events := make(chan TypedEvent[int])
+	require.NoError(t, WatchInto(fb, events))
+	assert.Equal(t, 1, (<-events).Value)
+
+	// nothing receives the event of the change
+	server.Set("count", 2)
+	time.Sleep(50 * time.Millisecond)
+	fb.StopWatching()
+
+	// the watch ends without the event being received
+	for i := 0; i < 100 && fb.isWatching(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.False(t, fb.isWatching(), "the watch was not stopped")
+	_, ok := <-events
+	assert.False(t, ok, "events should be closed")
+	assert.NoError(t, fb.WatchErr())
+}
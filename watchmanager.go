@@ -0,0 +1,277 @@
+package firego
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPathWatched is returned by AddPath for a path the WatchManager already
+// watches.
+var ErrPathWatched = errors.New("path is already watched")
+
+// WatchManager owns the watches of many locations below a reference, which
+// can be added and removed while it runs. Each location is watched with
+// WatchReconnect and restarted when its watch ends for good, e.g. because
+// Firebase canceled it. The events of all the locations are handled by a
+// shared pool of workers: the events of a location are handled in order,
+// by the same worker, and a slow handler delays the other locations of its
+// worker.
+type WatchManager struct {
+	// MaxStreams is the number of locations watched at a time, the
+	// locations added past it wait for another one to be removed. Zero
+	// means no limit.
+	MaxStreams int
+	// Workers is the number of goroutines the handler is called from, it
+	// defaults to 1.
+	Workers int
+	// RetryInterval between a watch ending for good and its restart, it
+	// defaults to 5 seconds.
+	RetryInterval time.Duration
+	// Reconnect configures how dropped watches are re-established, see
+	// WatchReconnect.
+	Reconnect ReconnectOptions
+	// OnError, if set, is called with the errors that ended the watch of
+	// a location before it is restarted.
+	OnError func(path string, err error)
+
+	root    *Firebase
+	handler func(path string, event Event)
+
+	mtx     sync.Mutex
+	watches map[string]*managedWatch
+	running bool
+	slots   chan struct{}
+	queues  []chan managedEvent
+	// watchers is the number of watches that may hand events to the
+	// queues, which are closed once it drops to 0
+	watchers sync.WaitGroup
+	workers  sync.WaitGroup
+}
+
+// managedWatch is the watch of a location of a WatchManager.
+type managedWatch struct {
+	path string
+	ref  *Firebase
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// managedEvent is an event of a location handed to a worker.
+type managedEvent struct {
+	path  string
+	event Event
+}
+
+// NewWatchManager creates a WatchManager of the locations below root that
+// calls handler with the path of a location, as given to AddPath, and each
+// of its events. Keep-alive events are not handled.
+func NewWatchManager(root *Firebase, handler func(path string, event Event)) *WatchManager {
+	return &WatchManager{
+		root:    root.location(),
+		handler: handler,
+		watches: map[string]*managedWatch{},
+	}
+}
+
+// Start starts the workers and the watches of the paths added so far.
+func (m *WatchManager) Start() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.running {
+		return
+	}
+	m.running = true
+
+	m.slots = nil
+	if m.MaxStreams > 0 {
+		m.slots = make(chan struct{}, m.MaxStreams)
+	}
+	workers := m.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	m.queues = make([]chan managedEvent, workers)
+	for i := range m.queues {
+		queue := make(chan managedEvent)
+		m.queues[i] = queue
+		m.workers.Add(1)
+		go func() {
+			defer m.workers.Done()
+			for e := range queue {
+				m.handler(e.path, e.event)
+			}
+		}()
+	}
+	for _, w := range m.watches {
+		m.start(w)
+	}
+}
+
+// Stop tears down the watches and waits for the handler calls in progress
+// to return. The paths are kept for the next Start.
+func (m *WatchManager) Stop() {
+	m.mtx.Lock()
+	if !m.running {
+		m.mtx.Unlock()
+		return
+	}
+	m.running = false
+	watches := make([]*managedWatch, 0, len(m.watches))
+	for _, w := range m.watches {
+		watches = append(watches, w)
+	}
+	queues := m.queues
+	m.mtx.Unlock()
+
+	for _, w := range watches {
+		w.teardown()
+	}
+	// and the ones RemovePath is tearing down
+	m.watchers.Wait()
+	for _, queue := range queues {
+		close(queue)
+	}
+	m.workers.Wait()
+}
+
+// AddPath starts watching the location at path, relative to the root of
+// the WatchManager. If the manager is not started, the location is watched
+// once it is.
+func (m *WatchManager) AddPath(path string) error {
+	path = strings.Trim(path, "/")
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.watches[path]; ok {
+		return ErrPathWatched
+	}
+	w := &managedWatch{path: path, ref: m.root.Child(path)}
+	m.watches[path] = w
+	if m.running {
+		m.start(w)
+	}
+	return nil
+}
+
+// RemovePath stops watching the location at path. It returns once the
+// events of the location are no longer handled, it reports whether the
+// location was watched.
+func (m *WatchManager) RemovePath(path string) bool {
+	path = strings.Trim(path, "/")
+	m.mtx.Lock()
+	w, ok := m.watches[path]
+	delete(m.watches, path)
+	running := m.running
+	m.mtx.Unlock()
+	if ok && running {
+		w.teardown()
+	}
+	return ok
+}
+
+// Paths returns the paths of the watched locations, in order.
+func (m *WatchManager) Paths() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	paths := make([]string, 0, len(m.watches))
+	for path := range m.watches {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// start runs the watch of w in the background, the caller holds mtx.
+func (m *WatchManager) start(w *managedWatch) {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	h := fnv.New32a()
+	h.Write([]byte(w.path))
+	queue := m.queues[h.Sum32()%uint32(len(m.queues))]
+	m.watchers.Add(1)
+	go m.run(w, m.slots, queue)
+}
+
+// teardown stops the watch of w and waits for it to end.
+func (w *managedWatch) teardown() {
+	close(w.stop)
+	w.ref.StopWatching()
+	<-w.done
+}
+
+func (m *WatchManager) run(w *managedWatch, slots chan struct{}, queue chan managedEvent) {
+	defer func() {
+		close(w.done)
+		m.watchers.Done()
+	}()
+
+	retry := m.RetryInterval
+	if retry <= 0 {
+		retry = 5 * time.Second
+	}
+	for {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-w.stop:
+				return
+			}
+		}
+
+		notifications := make(chan Event)
+		err := w.ref.WatchReconnect(notifications, m.Reconnect)
+		if err == nil {
+			select {
+			case <-w.stop:
+				// teardown may have run before the watch was established
+				w.ref.StopWatching()
+			default:
+			}
+			m.forward(w, notifications, queue)
+			err = w.ref.WatchErr()
+		}
+		if slots != nil {
+			<-slots
+		}
+
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		if err != nil && m.OnError != nil {
+			m.OnError(w.path, err)
+		}
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// forward hands the events of the watch of w to queue until the watch
+// ends.
+func (m *WatchManager) forward(w *managedWatch, notifications chan Event, queue chan managedEvent) {
+	for event := range notifications {
+		switch event.Type {
+		case EventTypeKeepAlive, EventTypeError:
+			// the error is the one of the watch
+			continue
+		}
+		select {
+		case queue <- managedEvent{path: w.path, event: event}:
+		case <-w.stop:
+			w.ref.StopWatching()
+			for range notifications {
+				// wait for the connection to be torn down
+			}
+			return
+		}
+	}
+}
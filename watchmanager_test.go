@@ -0,0 +1,139 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zabawaba99/firetest"
+)
+
+// managedEvents records the events handled by a WatchManager.
+type managedEvents struct {
+	mtx    sync.Mutex
+	events map[string][]Event
+}
+
+func (m *managedEvents) handle(path string, event Event) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.events == nil {
+		m.events = map[string][]Event{}
+	}
+	m.events[path] = append(m.events[path], event)
+}
+
+func (m *managedEvents) received(path string, n int) bool {
+	for i := 0; i < 100; i++ {
+		m.mtx.Lock()
+		got := len(m.events[path])
+		m.mtx.Unlock()
+		if got >= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func (m *managedEvents) get(path string) []Event {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]Event(nil), m.events[path]...)
+}
+
+func TestWatchManager(t *testing.T) {
+	t.Parallel()
+	server := firetest.New()
+	server.Start()
+	defer server.Close()
+
+	server.Set("rooms/a", 1)
+	server.Set("rooms/b", 2)
+	var events managedEvents
+	m := NewWatchManager(New(server.URL), events.handle)
+	m.MaxStreams = 1
+	m.Workers = 2
+
+	require.NoError(t, m.AddPath("rooms/a"))
+	m.Start()
+	defer m.Stop()
+	require.True(t, events.received("rooms/a", 1))
+	assert.Equal(t, 1.0, events.get("rooms/a")[0].Data)
+
+	require.NoError(t, m.AddPath("/rooms/b/"))
+	assert.Equal(t, ErrPathWatched, m.AddPath("rooms/a"))
+	assert.Equal(t, []string{"rooms/a", "rooms/b"}, m.Paths())
+
+	// rooms/b waits for a stream
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, events.get("rooms/b"))
+
+	assert.True(t, m.RemovePath("rooms/a"))
+	assert.False(t, m.RemovePath("rooms/a"))
+	require.True(t, events.received("rooms/b", 1))
+	assert.Equal(t, 2.0, events.get("rooms/b")[0].Data)
+
+	server.Set("rooms/a", 3)
+	server.Set("rooms/b", 4)
+	require.True(t, events.received("rooms/b", 2))
+	assert.Len(t, events.get("rooms/a"), 1)
+
+	// the paths are watched again after a restart
+	m.Stop()
+	assert.Equal(t, []string{"rooms/b"}, m.Paths())
+	m.Start()
+	require.True(t, events.received("rooms/b", 3))
+}
+
+func TestWatchManagerRestarts(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx         sync.Mutex
+		connections int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		connections++
+		n := connections
+		mtx.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		if n == 1 {
+			// rules no longer allow reading the location
+			w.Write([]byte("event: cancel\ndata: null\n\n"))
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	var (
+		events managedEvents
+		errs   []error
+	)
+	m := NewWatchManager(New(server.URL, WithHTTPClient(&http.Client{})), events.handle)
+	m.RetryInterval = time.Millisecond
+	m.OnError = func(path string, err error) {
+		assert.Equal(t, "users", path)
+		errs = append(errs, err)
+	}
+	require.NoError(t, m.AddPath("users"))
+	m.Start()
+
+	require.True(t, events.received("users", 3))
+	m.Stop()
+	assert.Equal(t, []error{ErrWatchCanceled}, errs)
+
+	var types []string
+	for _, event := range events.get("users") {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{EventTypePut, EventTypeCancel, EventTypePut}, types)
+}
@@ -0,0 +1,143 @@
+package firego
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// WatchStats describes the traffic of the watches a WatchMonitor is
+// attached to.
+type WatchStats struct {
+	// Events is the number of events received, by type.
+	Events map[string]int64
+	// Bytes is the number of bytes read from the connections.
+	Bytes int64
+	// Reconnects is the number of times WatchReconnect re-established
+	// the watch.
+	Reconnects int64
+	// Established is when the last connection was established.
+	Established time.Time
+	// LastEvent is when the last event was received, LastKeepAlive when
+	// the last keep-alive event was.
+	LastEvent     time.Time
+	LastKeepAlive time.Time
+}
+
+// SinceKeepAlive returns the time elapsed since the last keep-alive event
+// or, if the last connection did not receive one yet, since it was
+// established. Firebase sends a keep-alive every 30 seconds while nothing
+// changes, a stream that is silent for much longer is likely stuck.
+func (s WatchStats) SinceKeepAlive() time.Duration {
+	last := s.LastKeepAlive
+	if last.Before(s.Established) {
+		last = s.Established
+	}
+	if last.IsZero() {
+		return 0
+	}
+	return time.Since(last)
+}
+
+// WatchMonitor collects the WatchStats of the watches it is attached to
+// with WithWatchMonitor or ReconnectOptions, e.g. to alert on stuck
+// streams. It is safe for concurrent use.
+type WatchMonitor struct {
+	// OnEvent, if set, is called with the stats after every event, from
+	// the goroutine of the watch that received it.
+	OnEvent func(WatchStats)
+
+	mtx   sync.Mutex
+	stats WatchStats
+}
+
+// WithWatchMonitor makes m collect the stats of the watch.
+func WithWatchMonitor(m *WatchMonitor) RequestOption {
+	return func(r *request) {
+		r.monitor = m
+	}
+}
+
+// Stats returns the stats collected so far.
+func (m *WatchMonitor) Stats() WatchStats {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.copyStats()
+}
+
+// copyStats returns a copy of the stats, the caller holds mtx.
+func (m *WatchMonitor) copyStats() WatchStats {
+	stats := m.stats
+	stats.Events = make(map[string]int64, len(m.stats.Events))
+	for typ, n := range m.stats.Events {
+		stats.Events[typ] = n
+	}
+	return stats
+}
+
+// established records that a connection was established.
+func (m *WatchMonitor) established() {
+	if m == nil {
+		return
+	}
+	m.mtx.Lock()
+	m.stats.Established = time.Now()
+	m.mtx.Unlock()
+}
+
+// reconnected records that WatchReconnect re-established the watch.
+func (m *WatchMonitor) reconnected() {
+	if m == nil {
+		return
+	}
+	m.mtx.Lock()
+	m.stats.Reconnects++
+	m.mtx.Unlock()
+}
+
+// received records an event of type typ.
+func (m *WatchMonitor) received(typ string) {
+	if m == nil {
+		return
+	}
+	m.mtx.Lock()
+	if m.stats.Events == nil {
+		m.stats.Events = map[string]int64{}
+	}
+	m.stats.Events[typ]++
+	m.stats.LastEvent = time.Now()
+	if typ == EventTypeKeepAlive {
+		m.stats.LastKeepAlive = m.stats.LastEvent
+	}
+	var stats WatchStats
+	if m.OnEvent != nil {
+		stats = m.copyStats()
+	}
+	m.mtx.Unlock()
+
+	if m.OnEvent != nil {
+		m.OnEvent(stats)
+	}
+}
+
+// reader returns r, counting the bytes read from it if there is a monitor.
+func (m *WatchMonitor) reader(r io.Reader) io.Reader {
+	if m == nil {
+		return r
+	}
+	return monitoredReader{r: r, m: m}
+}
+
+// monitoredReader counts the bytes read from r in the stats of m.
+type monitoredReader struct {
+	r io.Reader
+	m *WatchMonitor
+}
+
+func (r monitoredReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.m.mtx.Lock()
+	r.m.stats.Bytes += int64(n)
+	r.m.mtx.Unlock()
+	return n, err
+}
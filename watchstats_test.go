@@ -0,0 +1,93 @@
+package firego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchMonitor(t *testing.T) {
+	t.Parallel()
+	body := "event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n" +
+		"event: keep-alive\ndata: null\n\n" +
+		"event: patch\ndata: {\"path\":\"/\",\"data\":{\"a\":2}}\n\n" +
+		"event: put\ndata: {\"path\":\"/b\",\"data\":3}\n\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	var calls int
+	m := &WatchMonitor{OnEvent: func(stats WatchStats) { calls++ }}
+	assert.Zero(t, m.Stats().SinceKeepAlive())
+
+	start := time.Now()
+	notifications := make(chan Event)
+	require.NoError(t, fb.Watch(notifications, WithWatchMonitor(m)))
+	for range notifications {
+	}
+
+	stats := m.Stats()
+	assert.Equal(t, map[string]int64{"put": 2, "patch": 1, "keep-alive": 1}, stats.Events)
+	assert.Equal(t, int64(len(body)), stats.Bytes)
+	assert.Zero(t, stats.Reconnects)
+	assert.False(t, stats.Established.Before(start))
+	assert.False(t, stats.LastKeepAlive.Before(stats.Established))
+	assert.False(t, stats.LastEvent.Before(stats.LastKeepAlive))
+	assert.True(t, stats.SinceKeepAlive() < time.Second)
+	assert.Equal(t, 4, calls)
+
+	// the stats are a copy
+	stats.Events["put"] = 10
+	assert.Equal(t, int64(2), m.Stats().Events["put"])
+}
+
+func TestWatchMonitorReconnects(t *testing.T) {
+	t.Parallel()
+	var (
+		mtx         sync.Mutex
+		connections int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mtx.Lock()
+		connections++
+		n := connections
+		mtx.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":1}\n\n"))
+		if n < 3 {
+			// dropped after the initial snapshot
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+	fb := New(server.URL, WithHTTPClient(&http.Client{}))
+
+	m := &WatchMonitor{}
+	notifications := make(chan Event)
+	require.NoError(t, fb.WatchReconnect(notifications, ReconnectOptions{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		Monitor:    m,
+	}))
+	for i := 0; i < 3; i++ {
+		<-notifications
+	}
+	fb.StopWatching()
+	for range notifications {
+	}
+
+	stats := m.Stats()
+	assert.Equal(t, int64(2), stats.Reconnects)
+	assert.Equal(t, map[string]int64{"put": 3}, stats.Events)
+}